@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
 	"github.com/spf13/cobra"
 )
 
@@ -20,3 +21,23 @@ func setCLIClient(_ *cobra.Command, _ []string) error {
 
 	return nil
 }
+
+// applyCommonConfig sets the options every command shares, from the flags registered by registerCommonFlags, on cfg.
+func applyCommonConfig(cfg *yamll.Config) {
+	cfg.LockFile = cliCfg.LockFile
+	cfg.NoLock = cliCfg.NoLock
+	cfg.CacheDir = cliCfg.CacheDir
+	cfg.NoCache = cliCfg.NoCache
+	cfg.Offline = cliCfg.Offline
+	cfg.Vendor = cliCfg.Vendor
+	cfg.VendorDir = cliCfg.VendorDir
+	cfg.Jobs = cliCfg.Jobs
+	cfg.ProjectRoot = cliCfg.ProjectRoot
+	cfg.Timeout = cliCfg.Timeout
+	cfg.RequestTimeout = cliCfg.RequestTimeout
+	cfg.Retries = cliCfg.Retries
+	cfg.CredentialsFile = cliCfg.CredentialsFile
+	cfg.StrictHostKeyChecking = cliCfg.StrictHostKeys
+	cfg.HTTPCredentials = cliCfg.HTTPCredentials
+	cfg.Profile = cliCfg.Profile
+}
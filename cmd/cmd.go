@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 )
@@ -24,10 +27,14 @@ func Main() {
 }
 
 // execute will actually execute the cli by taking the arguments passed to cli.
+// Interrupting it, e.g. with Ctrl-C or a CI job timeout, cancels any remote fetch in flight.
 func execute(args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	cmd.SetArgs(args)
 
-	if _, err := cmd.ExecuteC(); err != nil {
+	if _, err := cmd.ExecuteContextC(ctx); err != nil {
 		return err
 	}
 
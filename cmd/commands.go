@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -49,15 +50,13 @@ func getImportCommand() *cobra.Command {
 yamll import --file path/to/file.yaml --no-validation
 yamll import --file path/to/file.yaml --effective`,
 		PreRunE: setCLIClient,
-		RunE: func(_ *cobra.Command, _ []string) error {
+		RunE: func(cmd *cobra.Command, _ []string) error {
 			cfg := yamll.New(yamllCfg.Merge, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
 			cfg.SetLogger()
 			logger = cfg.GetLogger()
-			cfg.LockFile = cliCfg.LockFile
-			cfg.NoLock = cliCfg.NoLock
-			cfg.Profile = cliCfg.Profile
+			applyCommonConfig(cfg)
 
-			out, err := cfg.Yaml()
+			out, err := cfg.Yaml(cmd.Context())
 			if err != nil {
 				logger.Error("errored generating final yaml", slog.Any("err", err))
 			}
@@ -118,15 +117,13 @@ func getBuildCommand() *cobra.Command {
 		Long:    "Builds YAML by substituting all anchors and aliases defined in sub-YAML files defined as libraries",
 		Example: `yamll build --file path/to/file.yaml`,
 		PreRunE: setCLIClient,
-		RunE: func(_ *cobra.Command, _ []string) error {
+		RunE: func(cmd *cobra.Command, _ []string) error {
 			cfg := yamll.New(yamllCfg.Merge, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
 			cfg.SetLogger()
 			logger = cfg.GetLogger()
-			cfg.LockFile = cliCfg.LockFile
-			cfg.NoLock = cliCfg.NoLock
-			cfg.Profile = cliCfg.Profile
+			applyCommonConfig(cfg)
 
-			out, err := cfg.YamlBuild()
+			out, err := cfg.YamlBuild(cmd.Context())
 			if err != nil {
 				logger.Error("errored generating final yaml", slog.Any("err", err))
 			}
@@ -200,14 +197,13 @@ yamll tree --file path/to/file.yaml --output=json
 yamll tree --file path/to/file.yaml --output=dot
 yamll tree --file path/to/file.yaml --output=mermaid`,
 		PreRunE: setCLIClient,
-		RunE: func(_ *cobra.Command, _ []string) error {
+		RunE: func(cmd *cobra.Command, _ []string) error {
 			cfg := yamll.New(yamllCfg.Merge, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
 			cfg.SetLogger()
 			logger = cfg.GetLogger()
-			cfg.LockFile = cliCfg.LockFile
-			cfg.NoLock = cliCfg.NoLock
+			applyCommonConfig(cfg)
 
-			out, err := cfg.Tree(cliCfg.TreeOutput, cliCfg.NoColor, cliCfg.ShowPattern)
+			out, err := cfg.Tree(cmd.Context(), cliCfg.TreeOutput, cliCfg.NoColor, cliCfg.ShowPattern)
 			if err != nil {
 				logger.Error("errored generating final yaml", slog.Any("err", err))
 				os.Exit(1)
@@ -238,7 +234,7 @@ func getImpactCommand() *cobra.Command {
 yamll impact -f internal/fixtures/import.yaml internal/fixtures/base.yaml`,
 		Args:    cobra.ExactArgs(1),
 		PreRunE: setCLIClient,
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			target := args[0]
 			if target != "" {
 				cliCfg.ImpactTarget = target
@@ -247,10 +243,9 @@ yamll impact -f internal/fixtures/import.yaml internal/fixtures/base.yaml`,
 			cfg := yamll.New(false, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
 			cfg.SetLogger()
 			logger = cfg.GetLogger()
-			cfg.LockFile = cliCfg.LockFile
-			cfg.NoLock = cliCfg.NoLock
+			applyCommonConfig(cfg)
 
-			report, err := cfg.Impact(cliCfg.ImpactTarget)
+			report, err := cfg.Impact(cmd.Context(), cliCfg.ImpactTarget)
 			if err != nil {
 				logger.Error("errored generating impact report", slog.Any("err", err))
 				os.Exit(1)
@@ -279,7 +274,7 @@ func getTraceCommand() *cobra.Command {
 yamll trace --file internal/fixtures/import.yaml base.movies`,
 		Args:    cobra.ExactArgs(1),
 		PreRunE: setCLIClient,
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			rootFile, tracePath := parseTraceTarget(args[0])
 			if rootFile != "" {
 				cliCfg.Files = []string{rootFile}
@@ -288,10 +283,9 @@ yamll trace --file internal/fixtures/import.yaml base.movies`,
 			cfg := yamll.New(false, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
 			cfg.SetLogger()
 			logger = cfg.GetLogger()
-			cfg.LockFile = cliCfg.LockFile
-			cfg.NoLock = cliCfg.NoLock
+			applyCommonConfig(cfg)
 
-			trace, err := cfg.Trace(tracePath)
+			trace, err := cfg.Trace(cmd.Context(), tracePath)
 			if err != nil {
 				logger.Error("errored tracing yaml path", slog.Any("err", err))
 				os.Exit(1)
@@ -327,18 +321,20 @@ func getLockCommand() *cobra.Command {
 		Long:  "Resolves remote imports and writes a lock file containing resolved commits and checksums.",
 		Example: `yamll lock -f path/to/root.yaml
 yamll lock verify -f path/to/root.yaml
-yamll lock explain common/base.yaml -f path/to/root.yaml`,
+yamll lock explain common/base.yaml -f path/to/root.yaml
+yamll lock outdated -f path/to/root.yaml
+yamll lock update 'git+https://github.com/company/lib@^1.2?path=base.yaml' -f path/to/root.yaml
+yamll lock diff --git-ref main`,
 		PreRunE: setCLIClient,
-		RunE: func(_ *cobra.Command, _ []string) error {
+		RunE: func(cmd *cobra.Command, _ []string) error {
 			cfg := yamll.New(false, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
 			cfg.SetLogger()
 
 			logger = cfg.GetLogger()
 
-			cfg.LockFile = cliCfg.LockFile
-			cfg.NoLock = cliCfg.NoLock
+			applyCommonConfig(cfg)
 
-			out, err := cfg.Lock()
+			out, err := cfg.Lock(cmd.Context())
 			if err != nil {
 				logger.Error("errored generating lock file", slog.Any("err", err))
 				os.Exit(1)
@@ -361,7 +357,7 @@ yamll lock explain common/base.yaml -f path/to/root.yaml`,
 
 	lockCommand.SilenceErrors = true
 	registerCommonFlags(lockCommand)
-	lockCommand.AddCommand(getLockVerifyCommand(), getLockExplainCommand())
+	lockCommand.AddCommand(getLockVerifyCommand(), getLockExplainCommand(), getLockOutdatedCommand(), getLockUpdateCommand(), getLockDiffCommand())
 
 	return lockCommand
 }
@@ -373,14 +369,13 @@ func getLockVerifyCommand() *cobra.Command {
 		Long:    "Resolves the selected roots and verifies that every locked dependency still matches its recorded checksum.",
 		Example: "yamll lock verify -f path/to/root.yaml",
 		PreRunE: setCLIClient,
-		RunE: func(_ *cobra.Command, _ []string) error {
+		RunE: func(cmd *cobra.Command, _ []string) error {
 			cfg := yamll.New(false, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
 			cfg.SetLogger()
 			logger = cfg.GetLogger()
-			cfg.LockFile = cliCfg.LockFile
-			cfg.NoLock = cliCfg.NoLock
+			applyCommonConfig(cfg)
 
-			report, err := cfg.LockVerify()
+			report, err := cfg.LockVerify(cmd.Context())
 			if err != nil {
 				logger.Error("lock verification failed", slog.Any("err", err))
 				os.Exit(1)
@@ -403,14 +398,13 @@ func getLockExplainCommand() *cobra.Command {
 		Example: "yamll lock explain common/base.yaml -f app.yaml -f jobs.yaml",
 		Args:    cobra.ExactArgs(1),
 		PreRunE: setCLIClient,
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := yamll.New(false, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
 			cfg.SetLogger()
 			logger = cfg.GetLogger()
-			cfg.LockFile = cliCfg.LockFile
-			cfg.NoLock = cliCfg.NoLock
+			applyCommonConfig(cfg)
 
-			report, err := cfg.LockExplain(args[0])
+			report, err := cfg.LockExplain(cmd.Context(), args[0])
 			if err != nil {
 				logger.Error("lock explain failed", slog.Any("err", err))
 				os.Exit(1)
@@ -425,6 +419,156 @@ func getLockExplainCommand() *cobra.Command {
 	}
 }
 
+func getLockOutdatedCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "outdated [flags]",
+		Short:   "Lists locked git and OCI imports that have a newer version",
+		Long:    "Resolves every git and OCI import afresh and lists those whose ref or constraint now allows a newer tag, commit or digest than the locked one.",
+		Example: "yamll lock outdated -f path/to/root.yaml",
+		PreRunE: setCLIClient,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg := yamll.New(false, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
+			cfg.SetLogger()
+			logger = cfg.GetLogger()
+			applyCommonConfig(cfg)
+
+			// Newer versions are looked up at the origin of every import, never in the vendor directory.
+			cfg.Vendor = false
+
+			report, err := cfg.LockOutdated(cmd.Context())
+			if err != nil {
+				logger.Error("listing outdated imports failed", slog.Any("err", err))
+				os.Exit(1)
+			}
+
+			if _, err = writer.Write([]byte(report.String())); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+}
+
+func getLockUpdateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "update [source...] [flags]",
+		Short: "Updates the named imports of the lock file to their latest version",
+		Long: `Re-resolves only the named git and OCI imports, every one of them when none is named, and rewrites the lock file.
+Every other entry is left untouched, so that library bumps can be made and reviewed one at a time.`,
+		Example: `yamll lock update 'git+https://github.com/company/lib@^1.2?path=base.yaml' -f path/to/root.yaml
+yamll lock update oci://ghcr.io/company/bundle:v1 -f path/to/root.yaml`,
+		PreRunE: setCLIClient,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := yamll.New(false, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
+			cfg.SetLogger()
+			logger = cfg.GetLogger()
+			applyCommonConfig(cfg)
+
+			// Updated imports are resolved at their origin, never from the vendor directory.
+			cfg.Vendor = false
+
+			out, report, err := cfg.LockUpdate(cmd.Context(), args...)
+			if err != nil {
+				logger.Error("updating lock file failed", slog.Any("err", err))
+				os.Exit(1)
+			}
+
+			const readPermission = 0o600
+
+			if err = os.WriteFile(cliCfg.LockFile, out, readPermission); err != nil {
+				return err
+			}
+
+			if _, err = writer.Write([]byte(report.String())); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+}
+
+func getLockDiffCommand() *cobra.Command {
+	lockDiffCommand := &cobra.Command{
+		Use:   "diff [old.lock new.lock] [flags]",
+		Short: "Shows the dependencies added, removed and changed between two lock files",
+		Long: `Pairs the entries of two lock files by source and pattern file, and reports the dependencies added, removed and changed,
+with the commit range of git imports and the digest changes of OCI and HTTP ones.
+With --git-ref the lock file, --lock-file or the one given, is compared against its version committed at that ref.`,
+		Example: `yamll lock diff old.lock yamll.lock
+yamll lock diff --git-ref main --markdown`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cliCfg.DiffGitRef != "" {
+				return cobra.MaximumNArgs(1)(cmd, args)
+			}
+
+			return cobra.ExactArgs(2)(cmd, args) //nolint:mnd
+		},
+		PreRunE: setCLIClient,
+		RunE: func(_ *cobra.Command, args []string) error {
+			cfg := yamll.New(false, yamllCfg.LogLevel, yamllCfg.Limiter)
+			cfg.SetLogger()
+			logger = cfg.GetLogger()
+
+			oldData, newData, err := lockFilesToDiff(args)
+			if err != nil {
+				logger.Error("reading lock files failed", slog.Any("err", err))
+				os.Exit(1)
+			}
+
+			report, err := yamll.DiffLockFiles(oldData, newData)
+			if err != nil {
+				logger.Error("diffing lock files failed", slog.Any("err", err))
+				os.Exit(1)
+			}
+
+			out := report.String()
+			if cliCfg.DiffMarkdown {
+				out = report.Markdown()
+			}
+
+			if _, err = writer.Write([]byte(out)); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	registerLockDiffFlags(lockDiffCommand)
+
+	return lockDiffCommand
+}
+
+// lockFilesToDiff reads the two lock files given, or the lock file as committed at --git-ref and as it is now.
+func lockFilesToDiff(args []string) ([]byte, []byte, error) {
+	if cliCfg.DiffGitRef == "" {
+		oldData, err := os.ReadFile(args[0])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		newData, err := os.ReadFile(args[1])
+
+		return oldData, newData, err
+	}
+
+	lockPath := cliCfg.LockFile
+	if len(args) == 1 {
+		lockPath = args[0]
+	}
+
+	oldData, err := yamll.ReadLockFileAtGitRef(lockPath, cliCfg.DiffGitRef)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newData, err := os.ReadFile(lockPath)
+
+	return oldData, newData, err
+}
+
 func getLintCommand() *cobra.Command {
 	lintCommand := &cobra.Command{
 		Use:     "lint [flags]",
@@ -432,14 +576,13 @@ func getLintCommand() *cobra.Command {
 		Long:    "Runs static checks on the YAML import graph, anchors, and merge usage.",
 		Example: "yamll lint -f path/to/root.yaml",
 		PreRunE: setCLIClient,
-		RunE: func(_ *cobra.Command, _ []string) error {
+		RunE: func(cmd *cobra.Command, _ []string) error {
 			cfg := yamll.New(false, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
 			cfg.SetLogger()
 			logger = cfg.GetLogger()
-			cfg.LockFile = cliCfg.LockFile
-			cfg.NoLock = cliCfg.NoLock
+			applyCommonConfig(cfg)
 
-			report, err := cfg.Lint()
+			report, err := cfg.Lint(cmd.Context())
 			if err != nil {
 				logger.Error("lint errored", slog.Any("err", err))
 				os.Exit(1)
@@ -470,6 +613,224 @@ func getLintCommand() *cobra.Command {
 	return lintCommand
 }
 
+func getCacheCommand() *cobra.Command {
+	cacheCommand := &cobra.Command{
+		Use:   "cache [command]",
+		Short: "Manages the local cache of remote imports",
+		Long:  "Lists, prunes or clears the content-addressed cache used for Git, HTTP and OCI imports.",
+		Example: `yamll cache ls
+yamll cache prune --older-than 168h
+yamll cache clear`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Usage()
+		},
+	}
+
+	registerCommonFlags(cacheCommand)
+	cacheCommand.AddCommand(getCacheListCommand(), getCachePruneCommand(), getCacheClearCommand())
+
+	return cacheCommand
+}
+
+func getCacheListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "ls [flags]",
+		Short:   "Lists the cached remote imports",
+		Long:    "Lists every resolved identity held in the cache along with its content digest, size and last use.",
+		Example: "yamll cache ls --cache-dir /tmp/yamll-cache",
+		Args:    cobra.NoArgs,
+		PreRunE: setCLIClient,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			cache, err := yamll.NewCache(cliCfg.CacheDir)
+			if err != nil {
+				return err
+			}
+
+			entries, err := cache.List()
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range entries {
+				if _, err = fmt.Fprintf(writer, "%s\t%s\t%d\t%s\n",
+					entry.Key, entry.SHA256, entry.Size, entry.AccessedAt.Format(time.RFC3339)); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+func getCachePruneCommand() *cobra.Command {
+	cachePruneCommand := &cobra.Command{
+		Use:     "prune [flags]",
+		Short:   "Removes stale entries from the cache",
+		Long:    "Removes cache entries that were not used recently along with any content no entry refers to.",
+		Example: "yamll cache prune --older-than 168h",
+		Args:    cobra.NoArgs,
+		PreRunE: setCLIClient,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			cache, err := yamll.NewCache(cliCfg.CacheDir)
+			if err != nil {
+				return err
+			}
+
+			report, err := cache.Prune(cliCfg.PruneAge)
+			if err != nil {
+				return err
+			}
+
+			_, err = fmt.Fprint(writer, report.String())
+
+			return err
+		},
+	}
+
+	registerCachePruneFlags(cachePruneCommand)
+
+	return cachePruneCommand
+}
+
+func getCacheClearCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "clear [flags]",
+		Short:   "Removes everything from the cache",
+		Long:    "Deletes the cache directory, forcing every remote import to be fetched again on the next run.",
+		Example: "yamll cache clear",
+		Args:    cobra.NoArgs,
+		PreRunE: setCLIClient,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			cache, err := yamll.NewCache(cliCfg.CacheDir)
+			if err != nil {
+				return err
+			}
+
+			return cache.Clear()
+		},
+	}
+}
+
+func getVendorCommand() *cobra.Command {
+	vendorCommand := &cobra.Command{
+		Use:   "vendor [flags]",
+		Short: "Copies remote imports into the repository",
+		Long: `Resolves the whole import graph and writes every Git, HTTP and OCI import into the vendor directory,
+along with a modules.txt manifest mapping each import to its vendored path and sha256.`,
+		Example: `yamll vendor -f path/to/root.yaml
+yamll build -f path/to/root.yaml --vendor`,
+		PreRunE: setCLIClient,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg := yamll.New(false, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
+			cfg.SetLogger()
+			logger = cfg.GetLogger()
+			applyCommonConfig(cfg)
+
+			// Vendoring reads every import from its origin, to write it into the vendor directory.
+			cfg.Vendor = false
+
+			report, err := cfg.VendorDependencies(cmd.Context())
+			if err != nil {
+				logger.Error("vendoring remote imports failed", slog.Any("err", err))
+				os.Exit(1)
+			}
+
+			if _, err = writer.Write([]byte(report.String())); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	vendorCommand.SilenceErrors = true
+	registerCommonFlags(vendorCommand)
+
+	return vendorCommand
+}
+
+func getPublishCommand() *cobra.Command {
+	publishCommand := &cobra.Command{
+		Use:   "publish <oci://registry/repository:tag> [files...] [flags]",
+		Short: "Pushes YAML libraries or built outputs to an OCI registry",
+		Long: `Pushes the files to an OCI registry as an artifact, one layer per file titled with its path relative to --base-dir,
+so that oci://registry/repository:tag?path=<title> imports it. With --build, the output of yamll build for the root files is pushed instead.
+Registries are authenticated against with the credentials file and the docker config, like OCI imports.`,
+		Example: `yamll publish oci://ghcr.io/company/lib:v1.2.0 lib/base.yaml lib/envs/dev.yaml --base-dir lib
+yamll publish oci://ghcr.io/company/app:v1.2.0 --build -f path/to/root.yaml --source https://github.com/company/app`,
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: setCLIClient,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := yamll.New(yamllCfg.Merge, yamllCfg.LogLevel, yamllCfg.Limiter, cliCfg.Files...)
+			cfg.SetLogger()
+			logger = cfg.GetLogger()
+			applyCommonConfig(cfg)
+
+			files, err := publishFiles(cmd, cfg, args[1:])
+			if err != nil {
+				logger.Error("reading files to publish failed", slog.Any("err", err))
+				os.Exit(1)
+			}
+
+			annotations, err := yamll.ParsePublishAnnotations(cliCfg.Annotations)
+			if err != nil {
+				return err
+			}
+
+			report, err := cfg.Publish(cmd.Context(), args[0], files, yamll.PublishOptions{
+				Source:      cliCfg.PublishSource,
+				Version:     cliCfg.PublishVersion,
+				Annotations: annotations,
+			})
+			if err != nil {
+				logger.Error("publishing failed", slog.Any("err", err))
+				os.Exit(1)
+			}
+
+			if _, err = writer.Write([]byte(report.String())); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	publishCommand.SilenceErrors = true
+	registerCommonFlags(publishCommand)
+	registerPublishFlags(publishCommand)
+
+	return publishCommand
+}
+
+// publishFiles reads the library files to publish, or builds the root files when publishing with --build.
+func publishFiles(cmd *cobra.Command, cfg *yamll.Config, paths []string) ([]yamll.PublishFile, error) {
+	if !cliCfg.PublishBuild {
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("publish requires the files to push, or --build with root files")
+		}
+
+		return yamll.ReadPublishFiles(cliCfg.PublishBaseDir, paths...)
+	}
+
+	if len(paths) != 0 || len(cliCfg.Files) == 0 {
+		return nil, fmt.Errorf("publish --build pushes the output of the root files set with --file, not files given as arguments")
+	}
+
+	out, err := cfg.YamlBuild(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	title := cliCfg.PublishTitle
+	if title == "" {
+		title = filepath.Base(cliCfg.Files[0])
+	}
+
+	return []yamll.PublishFile{{Title: title, Data: string(out)}}, nil
+}
+
 func versionConfig(_ *cobra.Command, _ []string) error {
 	buildInfo, err := json.Marshal(version.GetBuildInfo())
 	if err != nil {
@@ -1,22 +1,47 @@
 package cmd
 
 import (
+	"time"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
 	"github.com/spf13/cobra"
 )
 
 // Config holds the information of the cli config.
 type Config struct {
-	NoValidation bool
-	Explode      bool
-	NoColor      bool
-	ShowPattern  bool
-	TreeOutput   string
-	ImpactTarget string
-	Profile      bool
-	LockFile     string
-	NoLock       bool
-	ToFile       string
-	Files        []string
+	NoValidation    bool
+	Explode         bool
+	NoColor         bool
+	ShowPattern     bool
+	TreeOutput      string
+	ImpactTarget    string
+	Profile         bool
+	LockFile        string
+	NoLock          bool
+	CacheDir        string
+	NoCache         bool
+	Offline         bool
+	Vendor          bool
+	VendorDir       string
+	Jobs            int
+	ProjectRoot     string
+	Timeout         time.Duration
+	RequestTimeout  time.Duration
+	Retries         int
+	CredentialsFile string
+	StrictHostKeys  bool
+	HTTPCredentials bool
+	PruneAge        time.Duration
+	ToFile          string
+	Files           []string
+	PublishBuild    bool
+	PublishBaseDir  string
+	PublishTitle    string
+	PublishSource   string
+	PublishVersion  string
+	Annotations     []string
+	DiffGitRef      string
+	DiffMarkdown    bool
 }
 
 // Registers all global flags to utility.
@@ -35,6 +60,59 @@ func registerCommonFlags(cmd *cobra.Command) {
 		"path to the lock file used for reproducible remote imports")
 	cmd.PersistentFlags().BoolVarP(&cliCfg.NoLock, "no-lock", "", false,
 		"when enabled, ignores any lock file during import/build/tree")
+	cmd.PersistentFlags().StringVarP(&cliCfg.CacheDir, "cache-dir", "", "",
+		"directory used to cache remote imports (defaults to $XDG_CACHE_HOME/yamll)")
+	cmd.PersistentFlags().BoolVarP(&cliCfg.NoCache, "no-cache", "", false,
+		"when enabled, remote imports are neither read from nor written to the cache")
+	cmd.PersistentFlags().BoolVarP(&cliCfg.Offline, "offline", "", false,
+		"when enabled, remote imports are resolved only from the local cache and never fetched over the network")
+	cmd.PersistentFlags().BoolVarP(&cliCfg.Vendor, "vendor", "", false,
+		"when enabled, remote imports are read from the vendor directory instead of Git, HTTP or OCI")
+	cmd.PersistentFlags().StringVarP(&cliCfg.VendorDir, "vendor-dir", "", yamll.DefaultVendorDir,
+		"directory holding vendored remote imports")
+	cmd.PersistentFlags().IntVarP(&cliCfg.Jobs, "jobs", "j", yamll.DefaultJobs,
+		"number of imports fetched concurrently")
+	cmd.PersistentFlags().StringVarP(&cliCfg.ProjectRoot, "project-root", "", "",
+		"directory //-anchored imports resolve against (defaults to the nearest ancestor of the first root file containing .git)")
+	cmd.PersistentFlags().DurationVarP(&cliCfg.Timeout, "timeout", "", 0,
+		"time allowed to resolve every import, no limit when zero")
+	cmd.PersistentFlags().DurationVarP(&cliCfg.RequestTimeout, "request-timeout", "", yamll.DefaultRequestTimeout,
+		"time allowed for a single request to a remote import, no limit when zero")
+	cmd.PersistentFlags().IntVarP(&cliCfg.Retries, "retries", "", yamll.DefaultRetries,
+		"number of times a remote request failing with 429, 5xx or a dropped connection is retried, with exponential backoff")
+	cmd.PersistentFlags().StringVarP(&cliCfg.CredentialsFile, "credentials-file", "", "",
+		"file mapping hosts or URL prefixes to the auth of imports without inline auth (defaults to $XDG_CONFIG_HOME/yamll/credentials.yaml)")
+	cmd.PersistentFlags().BoolVarP(&cliCfg.StrictHostKeys, "strict-host-key-checking", "", false,
+		"when enabled, git over ssh fails for servers missing from known_hosts instead of accepting them with a warning")
+	cmd.PersistentFlags().BoolVarP(&cliCfg.HTTPCredentials, "http-credentials", "", false,
+		"when enabled, credentials from ~/.netrc and git credential helpers are sent to imports over plain http too")
+}
+
+func registerCachePruneFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().DurationVarP(&cliCfg.PruneAge, "older-than", "", yamll.DefaultCachePruneAge,
+		"remove cache entries that were not used within this duration")
+}
+
+func registerLockDiffFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&cliCfg.DiffGitRef, "git-ref", "", "",
+		"compares the lock file against its version committed at this git ref, e.g. main, instead of another lock file")
+	cmd.PersistentFlags().BoolVarP(&cliCfg.DiffMarkdown, "markdown", "", false,
+		"when enabled, renders the changes as a markdown table, e.g. for pull request comments")
+}
+
+func registerPublishFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVarP(&cliCfg.PublishBuild, "build", "", false,
+		"when enabled, publishes the output of yamll build for the root files instead of library files")
+	cmd.PersistentFlags().StringVarP(&cliCfg.PublishBaseDir, "base-dir", "", ".",
+		"directory the published files are titled relative to, the path imports select them by with ?path=")
+	cmd.PersistentFlags().StringVarP(&cliCfg.PublishTitle, "title", "", "",
+		"title of the built output published with --build (defaults to the name of the first root file)")
+	cmd.PersistentFlags().StringVarP(&cliCfg.PublishSource, "source", "", "",
+		"org.opencontainers.image.source annotation of the artifact, e.g. the repository the files come from")
+	cmd.PersistentFlags().StringVarP(&cliCfg.PublishVersion, "version", "", "",
+		"org.opencontainers.image.version annotation of the artifact (defaults to the tag)")
+	cmd.PersistentFlags().StringArrayVarP(&cliCfg.Annotations, "annotation", "", nil,
+		"additional annotation of the artifact set as key=value, can be repeated")
 }
 
 func registerImportFlags(cmd *cobra.Command) {
@@ -34,6 +34,9 @@ func getYamllCommands() *cobra.Command {
 	command.commands = append(command.commands, getTraceCommand())
 	command.commands = append(command.commands, getLockCommand())
 	command.commands = append(command.commands, getLintCommand())
+	command.commands = append(command.commands, getCacheCommand())
+	command.commands = append(command.commands, getVendorCommand())
+	command.commands = append(command.commands, getPublishCommand())
 	command.commands = append(command.commands, getVersionCommand())
 
 	return command.prepareCommands()
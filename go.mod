@@ -13,8 +13,8 @@ require (
 	github.com/go-git/go-git/v5 v5.19.1
 	github.com/go-resty/resty/v2 v2.13.1
 	github.com/goccy/go-yaml v1.11.3
-	github.com/google/uuid v1.6.0
 	github.com/nikhilsbhat/common v0.0.6-0.20240705174411-75b5dafa56bb
+	github.com/skeema/knownhosts v1.3.1
 	github.com/spf13/cobra v1.8.1
 	github.com/stretchr/testify v1.11.1
 	github.com/thoas/go-funk v0.9.3
@@ -49,7 +49,6 @@ require (
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/sergi/go-diff v1.3.2-0.20230802210424-5b0b94c5c0d3 // indirect
 	github.com/sirupsen/logrus v1.9.3 // indirect
-	github.com/skeema/knownhosts v1.3.1 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/xanzy/ssh-agent v0.3.3 // indirect
 	golang.org/x/net v0.53.0 // indirect
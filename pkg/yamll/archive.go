@@ -0,0 +1,303 @@
+package yamll
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+// TypeArchive is the type of imports reading a file out of a local or HTTP hosted archive,
+// bundles/lib.zip?path=base.yaml or https://artifacts/platform-config-1.4.tar.gz?path=*.yaml.
+const TypeArchive = "archive"
+
+var archiveExtensions = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// isArchiveImport reports whether the import points at a tar.gz, tgz, tar or zip archive, on disk or over HTTP.
+func isArchiveImport(input string) bool {
+	location, _, _ := strings.Cut(input, "?")
+
+	if strings.Contains(location, "://") && !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return false
+	}
+
+	location = strings.ToLower(location)
+
+	for _, extension := range archiveExtensions {
+		if strings.HasSuffix(location, extension) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitArchiveImport splits an archive import into the archive, keeping any other query parameter of its URL,
+// and the ?path= selecting its member.
+func splitArchiveImport(importPath string) (string, string, error) {
+	location, query, _ := strings.Cut(importPath, "?")
+
+	var (
+		member string
+		params []string
+	)
+
+	for pair := range strings.SplitSeq(query, "&") {
+		if key, value, _ := strings.Cut(pair, "="); key == "path" {
+			member = value
+		} else if pair != "" {
+			params = append(params, pair)
+		}
+	}
+
+	if member == "" {
+		return "", "", &errors.YamllError{Message: fmt.Sprintf("archive import '%s' has to select a file with ?path=<file>", importPath)}
+	}
+
+	if len(params) != 0 {
+		location += "?" + strings.Join(params, "&")
+	}
+
+	return location, member, nil
+}
+
+// archiveImport spells the import of one member of the archive.
+func archiveImport(archive, member string) string {
+	separator := "?"
+	if strings.Contains(archive, "?") {
+		separator = "&"
+	}
+
+	return archive + separator + "path=" + member
+}
+
+func isRemoteArchive(archive string) bool {
+	return strings.HasPrefix(archive, "http://") || strings.HasPrefix(archive, "https://")
+}
+
+// isLocalArchiveImport reports whether the import reads an archive on disk, which needs no network even offline.
+func isLocalArchiveImport(dependency *Dependency) bool {
+	return dependency.Type == TypeArchive && !isRemoteArchive(dependency.Path)
+}
+
+// Archive reads the ?path= file out of the archive, or every file matching it when it is a pattern,
+// each kept as a Source of the import the way local pattern imports are.
+func (dependency *Dependency) Archive(ctx context.Context, log *slog.Logger) (File, error) {
+	archive, member, err := splitArchiveImport(dependency.Path)
+	if err != nil {
+		return File{}, err
+	}
+
+	content, err := dependency.readArchive(ctx, archive, log)
+	if err != nil {
+		return File{}, err
+	}
+
+	archiveSHA256 := checksumForContent(string(content))
+	pattern := isPattern(member)
+	member = cleanOriginPath(member)
+
+	if pattern {
+		if err = validateGlob(member); err != nil {
+			return File{}, &errors.YamllError{Message: fmt.Sprintf("error matching pattern: '%v'", err)}
+		}
+	}
+
+	members, names, err := archiveMembers(archive, content, func(name string) bool {
+		if !pattern {
+			return name == member
+		}
+
+		matched, _ := matchGlob(member, name)
+
+		return matched
+	})
+	if err != nil {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf("reading archive '%s' errored with: '%v'", archive, err)}
+	}
+
+	log.Debug("read archive", slog.String("archive", archive), slog.String("sha256", archiveSHA256), slog.Int("files", len(names)))
+
+	if !pattern {
+		data, ok := members[member]
+		if !ok {
+			return File{}, &errors.YamllError{Message: fmt.Sprintf(
+				"archive '%s' has no file '%s', available: %s", archive, member, strings.Join(names, ", "),
+			)}
+		}
+
+		return File{Name: dependency.Path, Data: data, Meta: FileMeta{SHA256: checksumForContent(data), ArchiveSHA256: archiveSHA256}}, nil
+	}
+
+	if len(members) == 0 {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf("pattern matched no files: '%s'", dependency.Path)}
+	}
+
+	sources := make([]File, 0, len(members))
+
+	for name, data := range members {
+		sources = append(sources, File{
+			Name: archiveImport(archive, name),
+			Data: data,
+			Meta: FileMeta{SHA256: checksumForContent(data), ArchiveSHA256: archiveSHA256},
+		})
+	}
+
+	file := patternFile(dependency.Path, sources)
+	file.Meta.ArchiveSHA256 = archiveSHA256
+
+	return file, nil
+}
+
+// readArchive reads the archive from disk, or over HTTP the way URL imports are, with their auth, TLS, retries and cache.
+func (dependency *Dependency) readArchive(ctx context.Context, archive string, log *slog.Logger) ([]byte, error) {
+	if !isRemoteArchive(archive) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(filepath.FromSlash(archive))
+		if err != nil {
+			return nil, &errors.YamllError{Message: fmt.Sprintf("reading archive errored with: '%v'", err)}
+		}
+
+		return content, nil
+	}
+
+	download := &Dependency{Path: archive, Type: TypeArchive, Auth: dependency.Auth, cache: dependency.cache, retry: dependency.retry}
+
+	return download.download(ctx, archiveCacheKey(archive), log)
+}
+
+// archiveMembers reads the regular files of the archive wanted by the filter, keyed by their cleaned path,
+// and lists the names of every regular file it holds.
+func archiveMembers(archive string, content []byte, wanted func(name string) bool) (map[string]string, []string, error) {
+	members := make(map[string]string)
+
+	var names []string
+
+	add := func(name string, reader io.Reader) error {
+		name = cleanOriginPath(name)
+		names = append(names, name)
+
+		if !wanted(name) {
+			return nil
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+
+		members[name] = string(data)
+
+		return nil
+	}
+
+	location, _, _ := strings.Cut(strings.ToLower(archive), "?")
+
+	if strings.HasSuffix(location, ".zip") {
+		zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, zipFile := range zipReader.File {
+			if !zipFile.Mode().IsRegular() {
+				continue
+			}
+
+			reader, err := zipFile.Open()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			err = add(zipFile.Name, reader)
+			_ = reader.Close()
+
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	} else {
+		var reader io.Reader = bytes.NewReader(content)
+
+		if !strings.HasSuffix(location, ".tar") {
+			gzipReader, err := gzip.NewReader(reader)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			defer gzipReader.Close()
+
+			reader = gzipReader
+		}
+
+		tarReader := tar.NewReader(reader)
+
+		for {
+			header, err := tarReader.Next()
+			if stdErrors.Is(err, io.EOF) {
+				break
+			}
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if header.Typeflag != tar.TypeReg {
+				continue
+			}
+
+			if err = add(header.Name, tarReader); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	return members, names, nil
+}
+
+// archiveSiblingImport resolves an import found inside a file of an archive to another file of the same archive.
+func archiveSiblingImport(importerPath, importPath string) (string, error) {
+	archive, member, err := splitArchiveImport(importerPath)
+	if err != nil {
+		return "", err
+	}
+
+	filePath, err := siblingPath(member, importPath)
+	if err != nil {
+		return "", err
+	}
+
+	return archiveImport(archive, filePath), nil
+}
+
+// archiveID is the archive, absolute when on disk, with the cleaned path of the member.
+func archiveID(source string) string {
+	archive, member, err := splitArchiveImport(source)
+	if err != nil {
+		return source
+	}
+
+	if isRemoteArchive(archive) {
+		archive = urlID(archive)
+	} else if absArchive, err := filepath.Abs(filepath.FromSlash(archive)); err == nil {
+		archive = filepath.ToSlash(absArchive)
+	}
+
+	return archiveImport(archive, cleanOriginPath(member))
+}
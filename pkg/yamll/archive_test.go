@@ -0,0 +1,196 @@
+package yamll_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+var archiveFiles = map[string]string{
+	"base.yaml":      "##++common.yaml\nbase: &base\n  <<: *common\n",
+	"common.yaml":    "common: &common\n  origin: archive\n",
+	"envs/dev.yaml":  "dev: &dev\n  replicas: 1\n",
+	"envs/prod.yaml": "prod: &prod\n  replicas: 3\n",
+}
+
+func newTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0o600, Size: int64(len(files[name])), Typeflag: tar.TypeReg}))
+
+		_, err := tarWriter.Write([]byte(files[name]))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+
+	return buffer.Bytes()
+}
+
+func newZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+
+	zipWriter := zip.NewWriter(&buffer)
+
+	for name, content := range files {
+		writer, err := zipWriter.Create(name)
+		require.NoError(t, err)
+
+		_, err = writer.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, zipWriter.Close())
+
+	return buffer.Bytes()
+}
+
+func TestDependencyArchive(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "bundles"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bundles", "lib.zip"), newZip(t, archiveFiles), 0o600))
+
+	tarball := newTarGz(t, archiveFiles)
+
+	// The comment closes the zip, whose trailing whitespace has to reach the reader as sent.
+	var commented bytes.Buffer
+
+	zipWriter := zip.NewWriter(&commented)
+	writer, err := zipWriter.Create("base.yaml")
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("base: &base\n  origin: commented\n"))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.SetComment("platform config\n"))
+	require.NoError(t, zipWriter.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/platform-config-1.4.tar.gz":
+			_, _ = w.Write(tarball)
+		case "/commented.zip":
+			_, _ = w.Write(commented.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	build := func(t *testing.T, imports string) (*yamll.Config, string, error) {
+		t.Helper()
+
+		rootFile := filepath.Join(dir, "root.yaml")
+		require.NoError(t, os.WriteFile(rootFile, []byte(imports+"app: true\n"), 0o600))
+
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.NoCache = true
+
+		out, err := cfg.Yaml(t.Context())
+
+		return cfg, string(out), err
+	}
+
+	t.Run("file of a local zip importing its sibling", func(t *testing.T) {
+		_, out, err := build(t, "##++bundles/lib.zip?path=base.yaml\n")
+		require.NoError(t, err)
+		require.Contains(t, out, "origin: archive")
+	})
+
+	t.Run("pattern in a tarball over HTTP", func(t *testing.T) {
+		_, out, err := build(t, "##++"+server.URL+"/platform-config-1.4.tar.gz?path=envs/*.yaml\n")
+		require.NoError(t, err)
+		require.Contains(t, out, "replicas: 1")
+		require.Contains(t, out, "replicas: 3")
+		require.NotContains(t, out, "origin: archive")
+	})
+
+	t.Run("archive over HTTP ending in whitespace", func(t *testing.T) {
+		_, out, err := build(t, "##++"+server.URL+"/commented.zip?path=base.yaml\n")
+		require.NoError(t, err)
+		require.Contains(t, out, "origin: commented")
+	})
+
+	t.Run("lock records the archive and every file", func(t *testing.T) {
+		cfg, _, err := build(t, "##++bundles/lib.zip?path=envs/*.yaml\n")
+		require.NoError(t, err)
+
+		cfg.LockFile = filepath.Join(dir, "yamll.lock")
+
+		lockData, err := cfg.Lock(t.Context())
+		require.NoError(t, err)
+
+		archive := filepath.ToSlash(filepath.Join(dir, "bundles", "lib.zip"))
+		require.Contains(t, string(lockData), "type: archive")
+		require.Contains(t, string(lockData), "pattern_file: "+archive+"?path=envs/dev.yaml")
+		require.Contains(t, string(lockData), "pattern_file: "+archive+"?path=envs/prod.yaml")
+		require.Contains(t, string(lockData), "archive_sha256: ")
+		require.Equal(t, 2, strings.Count(string(lockData), "archive_sha256: "))
+		require.NoError(t, os.WriteFile(cfg.LockFile, lockData, 0o600))
+
+		verifyCfg := yamll.New(false, "DEBUG", "---", filepath.Join(dir, "root.yaml"))
+		verifyCfg.SetLogger()
+		verifyCfg.NoCache = true
+		verifyCfg.LockFile = cfg.LockFile
+
+		report, err := verifyCfg.LockVerify(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, report.String(), "Lock file is valid")
+	})
+
+	t.Run("local archive offline", func(t *testing.T) {
+		rootFile := filepath.Join(dir, "root.yaml")
+		require.NoError(t, os.WriteFile(rootFile, []byte("##++bundles/lib.zip?path=envs/dev.yaml\napp: *dev\n"), 0o600))
+
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.Offline = true
+		cfg.CacheDir = t.TempDir()
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "replicas: 1")
+	})
+
+	t.Run("invalid archive imports", func(t *testing.T) {
+		for imports, message := range map[string]string{
+			"##++bundles/lib.zip?path=missing.yaml\n":          "has no file 'missing.yaml', available: base.yaml, common.yaml, envs/dev.yaml, envs/prod.yaml",
+			"##++bundles/lib.zip\n":                            "has to select a file with ?path=<file>",
+			"##++bundles/lib.zip?path=*.json\n":                "pattern matched no files",
+			"##++bundles/missing.tar.gz?path=base.yaml\n":      "reading archive errored with",
+			"##++" + server.URL + "/gone.tgz?path=base.yaml\n": "404",
+		} {
+			_, _, err := build(t, imports)
+			require.ErrorContains(t, err, message, imports)
+		}
+	})
+}
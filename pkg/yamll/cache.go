@@ -0,0 +1,407 @@
+package yamll
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+const (
+	cacheBlobDir         = "blobs"
+	cacheRefDir          = "refs"
+	cacheFilePermissions = 0o600
+	// DefaultCachePruneAge is the age after which unused cache entries are removed by Prune.
+	DefaultCachePruneAge = 30 * 24 * time.Hour
+)
+
+// Cache is a persistent, content-addressed store for remote imports.
+// Content is stored once per sha256 digest under blobs/, and every resolved identity
+// (git commit and path, OCI manifest digest, HTTP URL) points to a blob through a small JSON record under refs/.
+type Cache struct {
+	Dir string
+}
+
+// CacheEntry describes a single resolved identity stored in the cache.
+type CacheEntry struct {
+	Key            string    `json:"key"`
+	Type           string    `json:"type"`
+	SHA256         string    `json:"sha256"`
+	Name           string    `json:"name,omitempty"`
+	GitCommit      string    `json:"git_commit,omitempty"`
+	ManifestDigest string    `json:"manifest_digest,omitempty"`
+	ETag           string    `json:"etag,omitempty"`
+	Size           int64     `json:"size"`
+	FetchedAt      time.Time `json:"fetched_at"`
+	AccessedAt     time.Time `json:"accessed_at"`
+	// Sources lists the imports of the files a pattern import matched, each cached under its own key.
+	Sources []string `json:"sources,omitempty"`
+}
+
+// CachePruneReport summarises what Prune removed from the cache.
+type CachePruneReport struct {
+	EntriesRemoved int
+	BlobsRemoved   int
+	BytesFreed     int64
+}
+
+// DefaultCacheDir returns the cache location, honouring $XDG_CACHE_HOME.
+func DefaultCacheDir() (string, error) {
+	if xdgCache := os.Getenv("XDG_CACHE_HOME"); xdgCache != "" {
+		return filepath.Join(xdgCache, "yamll"), nil
+	}
+
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		return "", &errors.YamllError{Message: fmt.Sprintf("locating user cache directory errored with: '%v'", err)}
+	}
+
+	return filepath.Join(userCache, "yamll"), nil
+}
+
+// NewCache returns a cache rooted at dir, falling back to DefaultCacheDir when dir is empty.
+func NewCache(dir string) (*Cache, error) {
+	if dir == "" {
+		defaultDir, err := DefaultCacheDir()
+		if err != nil {
+			return nil, err
+		}
+
+		dir = defaultDir
+	}
+
+	return &Cache{Dir: dir}, nil
+}
+
+// Get returns the entry and content stored for the given identity key.
+func (c *Cache) Get(key string) (CacheEntry, string, bool) {
+	if c == nil {
+		return CacheEntry{}, "", false
+	}
+
+	data, err := os.ReadFile(c.refPath(key))
+	if err != nil {
+		return CacheEntry{}, "", false
+	}
+
+	var entry CacheEntry
+	if err = json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return CacheEntry{}, "", false
+	}
+
+	content, ok := c.Blob(entry.SHA256)
+	if !ok {
+		return CacheEntry{}, "", false
+	}
+
+	entry.AccessedAt = time.Now().UTC()
+	_ = c.writeRef(entry)
+
+	return entry, content, true
+}
+
+// Blob returns the content stored under the sha256 digest, verifying it on the way out.
+func (c *Cache) Blob(digest string) (string, bool) {
+	if c == nil || !isSHA256Hex(digest) {
+		return "", false
+	}
+
+	data, err := os.ReadFile(c.blobPath(digest))
+	if err != nil {
+		return "", false
+	}
+
+	if checksumForContent(string(data)) != digest {
+		return "", false
+	}
+
+	return string(data), true
+}
+
+// Put stores content and records the entry pointing at it.
+func (c *Cache) Put(entry CacheEntry, content string) error {
+	if c == nil {
+		return nil
+	}
+
+	entry.SHA256 = checksumForContent(content)
+	entry.Size = int64(len(content))
+
+	now := time.Now().UTC()
+	if entry.FetchedAt.IsZero() {
+		entry.FetchedAt = now
+	}
+
+	entry.AccessedAt = now
+
+	if _, err := os.Stat(c.blobPath(entry.SHA256)); err != nil {
+		if err = writeFileAtomic(c.blobPath(entry.SHA256), []byte(content)); err != nil {
+			return err
+		}
+	}
+
+	return c.writeRef(entry)
+}
+
+// List returns every entry in the cache ordered by key.
+func (c *Cache) List() ([]CacheEntry, error) {
+	refFiles, err := filepath.Glob(filepath.Join(c.Dir, cacheRefDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]CacheEntry, 0, len(refFiles))
+
+	for _, refFile := range refFiles {
+		data, err := os.ReadFile(refFile)
+		if err != nil {
+			return nil, err
+		}
+
+		var entry CacheEntry
+		if err = json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	return entries, nil
+}
+
+// Prune removes entries that were not used within olderThan and any blob no entry points to.
+func (c *Cache) Prune(olderThan time.Duration) (CachePruneReport, error) {
+	var report CachePruneReport
+
+	entries, err := c.List()
+	if err != nil {
+		return report, err
+	}
+
+	cutoff := time.Now().UTC().Add(-olderThan)
+	referenced := make(map[string]struct{}, len(entries))
+
+	for _, entry := range entries {
+		if entry.AccessedAt.Before(cutoff) {
+			if err = os.Remove(c.refPath(entry.Key)); err != nil && !stdErrors.Is(err, os.ErrNotExist) {
+				return report, err
+			}
+
+			report.EntriesRemoved++
+
+			continue
+		}
+
+		referenced[entry.SHA256] = struct{}{}
+	}
+
+	blobFiles, err := filepath.Glob(filepath.Join(c.Dir, cacheBlobDir, "sha256", "*"))
+	if err != nil {
+		return report, err
+	}
+
+	for _, blobFile := range blobFiles {
+		if _, ok := referenced[filepath.Base(blobFile)]; ok {
+			continue
+		}
+
+		info, err := os.Stat(blobFile)
+		if err != nil {
+			continue
+		}
+
+		if err = os.Remove(blobFile); err != nil {
+			return report, err
+		}
+
+		report.BlobsRemoved++
+		report.BytesFreed += info.Size()
+	}
+
+	return report, nil
+}
+
+// Clear removes the whole cache directory.
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
+
+func (c *Cache) writeRef(entry CacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(c.refPath(entry.Key), data)
+}
+
+func (c *Cache) refPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(c.Dir, cacheRefDir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *Cache) blobPath(digest string) string {
+	return filepath.Join(c.Dir, cacheBlobDir, "sha256", digest)
+}
+
+func (r CachePruneReport) String() string {
+	return fmt.Sprintf("Entries removed: %d\nBlobs removed: %d\nBytes freed: %d\n", r.EntriesRemoved, r.BlobsRemoved, r.BytesFreed)
+}
+
+// cache returns the cache used during resolution, or nil when caching is disabled.
+func (cfg *Config) cache() *Cache {
+	if cfg.NoCache {
+		return nil
+	}
+
+	if cfg.cacheStore != nil {
+		return cfg.cacheStore
+	}
+
+	cacheStore, err := NewCache(cfg.CacheDir)
+	if err != nil {
+		if cfg.log != nil {
+			cfg.log.Warn("remote import cache is disabled", slog.Any("err", err))
+		}
+
+		return nil
+	}
+
+	cfg.cacheStore = cacheStore
+
+	return cfg.cacheStore
+}
+
+// readLockedFromCache serves a remote dependency straight from the cache when the lock file
+// pins its content, so that warm CI builds never reach the network.
+func (cfg *Config) readLockedFromCache(dependency *Dependency, locked *LockEntry) (File, bool) {
+	if locked == nil || locked.SHA256 == "" || !isRemoteType(dependency.Type) {
+		return File{}, false
+	}
+
+	content, ok := cfg.cache().Blob(locked.SHA256)
+	if !ok {
+		return File{}, false
+	}
+
+	cfg.log.Debug("serving locked dependency from cache", slog.String("path", dependency.Path), slog.String("sha256", locked.SHA256))
+
+	name := locked.Resolved
+	if name == "" {
+		name = dependency.Path
+	}
+
+	return File{
+		Name: name,
+		Data: content,
+		Meta: FileMeta{SHA256: locked.SHA256, GitCommit: locked.GitCommit, ArchiveSHA256: locked.ArchiveSHA256, ManifestDigest: locked.ManifestDigest},
+	}, true
+}
+
+func gitCacheKey(repoURL, commit, path string) string {
+	return fmt.Sprintf("git:%s@%s:%s", repoURL, commit, path)
+}
+
+func ociCacheKey(ref *ociReference, manifestDigest string) string {
+	key := fmt.Sprintf("oci:%s/%s@%s", ref.Registry, ref.Repository, manifestDigest)
+	if ref.Path != "" {
+		key += ":" + ref.Path
+	}
+
+	if len(ref.MediaTypes) != 0 {
+		key += "|" + strings.Join(ref.MediaTypes, ",")
+	}
+
+	return key
+}
+
+func httpCacheKey(url string) string {
+	return "http:" + url
+}
+
+// archiveCacheKey keeps HTTP archives apart from URL imports of the same URL, which are cached with their whitespace trimmed.
+func archiveCacheKey(url string) string {
+	return "archive:" + url
+}
+
+// isRemoteType reports whether imports of the type come from outside the local filesystem,
+// which holds for every source other than files, patterns and directories, including registered ones.
+func isRemoteType(dependencyType string) bool {
+	return dependencyType != "" && dependencyType != TypeFile && dependencyType != TypeFilePattern && dependencyType != TypeDirectory
+}
+
+// isLocalGitImport reports whether the import reads a repository on disk, which needs no network even offline.
+func isLocalGitImport(dependency *Dependency) bool {
+	if dependency.Type != TypeGit {
+		return false
+	}
+
+	meta, err := dependency.getGitMetaData()
+
+	return err == nil && meta.scheme == "file"
+}
+
+func isSHA256Hex(digest string) bool {
+	const sha256HexLength = 64
+
+	if len(digest) != sha256HexLength {
+		return false
+	}
+
+	_, err := hex.DecodeString(digest)
+
+	return err == nil
+}
+
+func isGitCommitHash(ref string) bool {
+	const gitCommitHexLength = 40
+
+	if len(ref) != gitCommitHexLength {
+		return false
+	}
+
+	_, err := hex.DecodeString(strings.ToLower(ref))
+
+	return err == nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), defaultDirPermissions); err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tempFile.Name())
+
+	if _, err = tempFile.Write(data); err != nil {
+		tempFile.Close()
+
+		return err
+	}
+
+	if err = tempFile.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(tempFile.Name(), cacheFilePermissions); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), path)
+}
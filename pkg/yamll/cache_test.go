@@ -0,0 +1,182 @@
+package yamll_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	cache, err := yamll.NewCache(t.TempDir())
+	require.NoError(t, err)
+
+	t.Run("put and get", func(t *testing.T) {
+		require.NoError(t, cache.Put(yamll.CacheEntry{Key: "http:https://example.com/base.yaml", Type: yamll.TypeURL, ETag: `"v1"`}, "name: base\n"))
+
+		entry, content, ok := cache.Get("http:https://example.com/base.yaml")
+		require.True(t, ok)
+		require.Equal(t, "name: base\n", content)
+		require.Equal(t, `"v1"`, entry.ETag)
+
+		blob, ok := cache.Blob(entry.SHA256)
+		require.True(t, ok)
+		require.Equal(t, content, blob)
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		_, _, ok := cache.Get("http:https://example.com/missing.yaml")
+		require.False(t, ok)
+	})
+
+	t.Run("prune removes stale entries and orphaned blobs", func(t *testing.T) {
+		report, err := cache.Prune(0)
+		require.NoError(t, err)
+		require.Equal(t, 1, report.EntriesRemoved)
+		require.Equal(t, 1, report.BlobsRemoved)
+
+		entries, err := cache.List()
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		require.NoError(t, cache.Put(yamll.CacheEntry{Key: "http:https://example.com/other.yaml"}, "other: true\n"))
+		require.NoError(t, cache.Clear())
+
+		_, err := os.Stat(cache.Dir)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestConfigYamlServesLockedRemoteImportFromCache(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+
+		_, _ = w.Write([]byte("shared: &shared\n  remote: true\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	rootFile := filepath.Join(dir, "root.yaml")
+	lockFile := filepath.Join(dir, "yamll.lock")
+
+	require.NoError(t, os.WriteFile(rootFile, []byte("##++"+server.URL+"\napp: *shared\n"), 0o600))
+
+	lockCfg := yamll.New(false, "DEBUG", "---", rootFile)
+	lockCfg.SetLogger()
+	lockCfg.LockFile = lockFile
+	lockCfg.CacheDir = cacheDir
+
+	lockData, err := lockCfg.Lock(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(lockFile, lockData, 0o600))
+	require.Equal(t, int32(1), requests.Load())
+
+	server.Close()
+
+	cfg := yamll.New(false, "DEBUG", "---", rootFile)
+	cfg.SetLogger()
+	cfg.LockFile = lockFile
+	cfg.CacheDir = cacheDir
+
+	out, err := cfg.Yaml(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, string(out), "remote: true")
+	require.Equal(t, int32(1), requests.Load())
+}
+
+func TestDependencyURLRevalidatesCachedContentWithETag(t *testing.T) {
+	var fullResponses atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		fullResponses.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("name: cached\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	rootFile := filepath.Join(dir, "root.yaml")
+
+	require.NoError(t, os.WriteFile(rootFile, []byte("##++"+server.URL+"\napp: true\n"), 0o600))
+
+	for range 2 {
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.CacheDir = filepath.Join(dir, "cache")
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "name: cached")
+	}
+
+	require.Equal(t, int32(1), fullResponses.Load())
+
+	cache, err := yamll.NewCache(filepath.Join(dir, "cache"))
+	require.NoError(t, err)
+
+	entries, err := cache.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "http:"+server.URL, entries[0].Key)
+	require.Equal(t, `"v1"`, entries[0].ETag)
+	require.WithinDuration(t, time.Now(), entries[0].AccessedAt, time.Minute)
+}
+
+func TestConfigYamlOffline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("shared: &shared\n  remote: true\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	rootFile := filepath.Join(dir, "root.yaml")
+
+	require.NoError(t, os.WriteFile(rootFile, []byte("##++"+server.URL+"\napp: *shared\n"), 0o600))
+
+	newConfig := func(cacheDir string, offline bool) *yamll.Config {
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.CacheDir = cacheDir
+		cfg.Offline = offline
+
+		return cfg
+	}
+
+	t.Run("fails when the dependency was never cached", func(t *testing.T) {
+		_, err := newConfig(filepath.Join(dir, "empty-cache"), true).Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "dependency "+server.URL+" is not available offline")
+	})
+
+	t.Run("serves previously fetched dependency", func(t *testing.T) {
+		cacheDir := filepath.Join(dir, "cache")
+
+		_, err := newConfig(cacheDir, false).Yaml(t.Context())
+		require.NoError(t, err)
+
+		server.Close()
+
+		out, err := newConfig(cacheDir, true).Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "remote: true")
+	})
+}
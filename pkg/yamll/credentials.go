@@ -0,0 +1,580 @@
+package yamll
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/a8m/envsubst"
+	"github.com/goccy/go-yaml"
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+// CredentialsFile maps hosts or URL prefixes to the Auth of the imports not declaring their own.
+//
+//	credentials:
+//	  - match: ghcr.io/company
+//	    auth:
+//	      user_name: ${GHCR_USER}
+//	      password: ${GHCR_TOKEN}
+type CredentialsFile struct {
+	Credentials []Credential `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+}
+
+// Credential is the Auth used by imports matching Match, a host like config.internal:8443,
+// or a URL prefix like https://config.internal/team-a or ghcr.io/company.
+type Credential struct {
+	Match string `json:"match,omitempty" yaml:"match,omitempty"`
+	Auth  Auth   `json:"auth,omitempty" yaml:"auth,omitempty"`
+}
+
+// credentialKindSSH is the kind of the targets of git imports over ssh.
+const credentialKindSSH = "ssh"
+
+// credentialTarget is where an import is fetched from, the key credentials are looked up with.
+type credentialTarget struct {
+	kind   string
+	scheme string
+	host   string
+	path   string
+}
+
+// credentialStore finds the Auth of imports without inline auth, in order from
+// the credentials file, ~/.netrc, ~/.docker/config.json and git credential helpers.
+type credentialStore struct {
+	mutex    sync.Mutex
+	file     []Credential
+	netrc    []netrcMachine
+	docker   dockerConfig
+	resolved map[credentialTarget]*credentialLookup
+	// http allows ~/.netrc and git credential helpers to authenticate imports over plain http.
+	http bool
+	log  *slog.Logger
+}
+
+// credentialLookup is the Auth of a single target, looked up once, while imports of other targets carry on.
+// Helpers like git credential fill or docker-credential-* can be slow to answer.
+type credentialLookup struct {
+	once sync.Once
+	auth *Auth
+}
+
+// DefaultCredentialsFile returns the credentials file location, honouring $XDG_CONFIG_HOME.
+func DefaultCredentialsFile() (string, error) {
+	if xdgConfig := os.Getenv("XDG_CONFIG_HOME"); xdgConfig != "" {
+		return filepath.Join(xdgConfig, "yamll", "credentials.yaml"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", &errors.YamllError{Message: fmt.Sprintf("locating home directory errored with: '%v'", err)}
+	}
+
+	return filepath.Join(home, ".config", "yamll", "credentials.yaml"), nil
+}
+
+// loadCredentials reads every credential source once, before imports are fetched concurrently.
+func (cfg *Config) loadCredentials() error {
+	if cfg.credentials != nil {
+		return nil
+	}
+
+	store := &credentialStore{resolved: make(map[credentialTarget]*credentialLookup), http: cfg.HTTPCredentials, log: cfg.log}
+
+	credentialsFile, err := cfg.readCredentialsFile()
+	if err != nil {
+		return err
+	}
+
+	store.file = credentialsFile.Credentials
+
+	if store.netrc, err = readNetrc(); err != nil {
+		return err
+	}
+
+	if store.docker, err = readDockerConfig(); err != nil {
+		return err
+	}
+
+	cfg.credentials = store
+
+	return nil
+}
+
+func (cfg *Config) readCredentialsFile() (CredentialsFile, error) {
+	var credentialsFile CredentialsFile
+
+	path := cfg.CredentialsFile
+	if path == "" {
+		defaultPath, err := DefaultCredentialsFile()
+		if err != nil {
+			return credentialsFile, nil //nolint:nilerr
+		}
+
+		path = defaultPath
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if stdErrors.Is(err, fs.ErrNotExist) && cfg.CredentialsFile == "" {
+			return credentialsFile, nil
+		}
+
+		return credentialsFile, &errors.YamllError{Message: fmt.Sprintf("reading credentials file errored with: '%v'", err)}
+	}
+
+	substituted, err := envsubst.String(string(data))
+	if err != nil {
+		return credentialsFile, err
+	}
+
+	if err = yaml.Unmarshal([]byte(substituted), &credentialsFile); err != nil {
+		return credentialsFile, &errors.YamllError{Message: fmt.Sprintf("parsing credentials file '%s' errored with: '%v'", path, err)}
+	}
+
+	for _, credential := range credentialsFile.Credentials {
+		if strings.TrimSpace(credential.Match) == "" {
+			return credentialsFile, &errors.YamllError{Message: fmt.Sprintf("credentials file '%s' has an entry without match", path)}
+		}
+	}
+
+	return credentialsFile, nil
+}
+
+// authFor returns the Auth configured for the import, or nil when no source has one.
+func (store *credentialStore) authFor(ctx context.Context, dependency *Dependency) *Auth {
+	target, ok := credentialTargetOf(dependency)
+	if !ok {
+		return nil
+	}
+
+	store.mutex.Lock()
+
+	lookup, ok := store.resolved[target]
+	if !ok {
+		lookup = &credentialLookup{}
+		store.resolved[target] = lookup
+	}
+
+	store.mutex.Unlock()
+
+	lookup.once.Do(func() {
+		lookup.auth = store.lookup(ctx, target)
+	})
+
+	return lookup.auth
+}
+
+func (store *credentialStore) lookup(ctx context.Context, target credentialTarget) *Auth {
+	if auth, ok := matchCredential(store.file, target); ok {
+		store.log.Debug("using credentials file", slog.String("host", target.host))
+
+		return &auth
+	}
+
+	// What ~/.netrc and the helpers hold is usually meant for https, never sent in cleartext unless asked to.
+	if target.scheme == "http" && !store.http && target.kind != TypeOCI {
+		store.log.Debug("not looking up credentials for plain http, enable http credentials to send them", slog.String("host", target.host))
+
+		return nil
+	}
+
+	switch target.kind {
+	case TypeOCI:
+		if auth, ok := store.docker.authFor(ctx, target.host, store.log); ok {
+			store.log.Debug("using docker credentials", slog.String("registry", target.host))
+
+			return &auth
+		}
+	case credentialKindSSH:
+		// Only the credentials file carries ssh keys, the ssh-agent is used otherwise.
+		return nil
+	case TypeGit:
+		if auth, ok := netrcAuth(store.netrc, target.host); ok {
+			store.log.Debug("using netrc credentials", slog.String("host", target.host))
+
+			return &auth
+		}
+
+		if auth, ok := gitCredentialFill(ctx, target); ok {
+			store.log.Debug("using git credential helper", slog.String("host", target.host))
+
+			return &auth
+		}
+	default:
+		if auth, ok := netrcAuth(store.netrc, target.host); ok {
+			store.log.Debug("using netrc credentials", slog.String("host", target.host))
+
+			return &auth
+		}
+	}
+
+	return nil
+}
+
+// credentialTargetOf returns where the import is fetched from, local imports have none.
+func credentialTargetOf(dependency *Dependency) (credentialTarget, bool) {
+	switch dependency.Type {
+	case TypeOCI:
+		ref, err := parseOCIReference(dependency.Path)
+		if err != nil {
+			return credentialTarget{}, false
+		}
+
+		return credentialTarget{kind: TypeOCI, scheme: ociScheme(ref.Registry), host: strings.ToLower(ref.Registry), path: ref.Repository}, true
+	case TypeGit:
+		meta, err := dependency.getGitMetaData()
+		if err != nil || meta.scheme == "git" || meta.scheme == "file" {
+			return credentialTarget{}, false
+		}
+
+		kind := TypeGit
+		if meta.ssh {
+			kind = credentialKindSSH
+		}
+
+		return credentialTarget{kind: kind, scheme: meta.scheme, host: strings.ToLower(meta.hostPort()), path: meta.repoPath}, true
+	case TypeArchive:
+		archive, _, err := splitArchiveImport(dependency.Path)
+		if err != nil || !isRemoteArchive(archive) {
+			return credentialTarget{}, false
+		}
+
+		parsed, err := url.Parse(archive)
+		if err != nil {
+			return credentialTarget{}, false
+		}
+
+		return credentialTarget{kind: TypeURL, scheme: parsed.Scheme, host: strings.ToLower(parsed.Host), path: strings.Trim(parsed.Path, "/")}, true
+	case TypeFile, TypeFilePattern, TypeDirectory:
+		return credentialTarget{}, false
+	default:
+		parsed, err := url.Parse(dependency.Path)
+		if err != nil || parsed.Host == "" {
+			return credentialTarget{}, false
+		}
+
+		return credentialTarget{kind: dependency.Type, scheme: parsed.Scheme, host: strings.ToLower(parsed.Host), path: strings.Trim(parsed.Path, "/")}, true
+	}
+}
+
+// matchCredential picks the entry with the longest match covering the target, on path segment boundaries.
+// Entries spelling a scheme, https://config.internal, only match targets fetched over that scheme.
+func matchCredential(credentials []Credential, target credentialTarget) (Auth, bool) {
+	var (
+		best      Auth
+		bestMatch = -1
+	)
+
+	location := target.host + "/" + target.path
+
+	for _, credential := range credentials {
+		match := credential.Match
+		if scheme, rest, found := strings.Cut(match, "://"); found {
+			if target.scheme != "" && !strings.EqualFold(scheme, target.scheme) {
+				continue
+			}
+
+			match = rest
+		}
+
+		match = strings.TrimSuffix(strings.ToLower(match), "/")
+		if match == "" {
+			continue
+		}
+
+		host, matchPath, _ := strings.Cut(match, "/")
+		if host != target.host || (matchPath != "" && location != match && !strings.HasPrefix(location, match+"/")) {
+			continue
+		}
+
+		if len(match) > bestMatch {
+			best, bestMatch = credential.Auth, len(match)
+		}
+	}
+
+	return best, bestMatch >= 0
+}
+
+// netrcMachine is a machine, or the default, of a .netrc file.
+type netrcMachine struct {
+	name     string
+	login    string
+	password string
+}
+
+// readNetrc reads $NETRC, else ~/.netrc, a missing file has no machines.
+func readNetrc() ([]netrcMachine, error) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil //nolint:nilerr
+		}
+
+		path = filepath.Join(home, ".netrc")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if stdErrors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, &errors.YamllError{Message: fmt.Sprintf("reading netrc errored with: '%v'", err)}
+	}
+
+	return parseNetrc(string(data)), nil
+}
+
+func parseNetrc(data string) []netrcMachine {
+	var (
+		machines []netrcMachine
+		current  *netrcMachine
+	)
+
+	for _, line := range strings.Split(data, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		for index := 0; index < len(fields); index++ {
+			value := ""
+			if index+1 < len(fields) {
+				value = fields[index+1]
+			}
+
+			switch fields[index] {
+			case "machine":
+				machines = append(machines, netrcMachine{name: strings.ToLower(value)})
+				current = &machines[len(machines)-1]
+				index++
+			case "default":
+				machines = append(machines, netrcMachine{})
+				current = &machines[len(machines)-1]
+			case "login":
+				if current != nil {
+					current.login = value
+				}
+
+				index++
+			case "password":
+				if current != nil {
+					current.password = value
+				}
+
+				index++
+			case "account", "macdef":
+				index++
+			}
+		}
+	}
+
+	return machines
+}
+
+// netrcAuth returns the login of the machine named host, with or without its port, falling back to the default.
+func netrcAuth(machines []netrcMachine, host string) (Auth, bool) {
+	hostname := host
+	if name, _, found := strings.Cut(host, ":"); found {
+		hostname = name
+	}
+
+	var fallback *netrcMachine
+
+	for index := range machines {
+		machine := &machines[index]
+
+		switch machine.name {
+		case host, hostname:
+			return Auth{UserName: machine.login, Password: machine.password}, true
+		case "":
+			if fallback == nil {
+				fallback = machine
+			}
+		}
+	}
+
+	if fallback != nil {
+		return Auth{UserName: fallback.login, Password: fallback.password}, true
+	}
+
+	return Auth{}, false
+}
+
+// dockerConfig is the part of ~/.docker/config.json holding registry credentials.
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth,omitempty"`
+		Username      string `json:"username,omitempty"`
+		Password      string `json:"password,omitempty"`
+		RegistryToken string `json:"registrytoken,omitempty"`
+	} `json:"auths,omitempty"`
+	CredHelpers map[string]string `json:"credHelpers,omitempty"`
+	CredsStore  string            `json:"credsStore,omitempty"`
+}
+
+// dockerHubServer is the key Docker stores the credentials of Docker Hub under.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// readDockerConfig reads $DOCKER_CONFIG/config.json, else ~/.docker/config.json, a missing file has no credentials.
+func readDockerConfig() (dockerConfig, error) {
+	var config dockerConfig
+
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return config, nil //nolint:nilerr
+		}
+
+		dir = filepath.Join(home, ".docker")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		if stdErrors.Is(err, fs.ErrNotExist) {
+			return config, nil
+		}
+
+		return config, &errors.YamllError{Message: fmt.Sprintf("reading docker config errored with: '%v'", err)}
+	}
+
+	if err = json.Unmarshal(data, &config); err != nil {
+		return config, &errors.YamllError{Message: fmt.Sprintf("parsing docker config errored with: '%v'", err)}
+	}
+
+	return config, nil
+}
+
+// authFor returns the credentials of the registry, from its credential helper or the inline auths.
+func (config dockerConfig) authFor(ctx context.Context, registry string, log *slog.Logger) (Auth, bool) {
+	server := registry
+	if registry == "docker.io" || registry == "index.docker.io" || registry == "registry-1.docker.io" {
+		server = dockerHubServer
+	}
+
+	helper := config.CredHelpers[registry]
+	if helper == "" {
+		helper = config.CredsStore
+	}
+
+	if helper != "" {
+		auth, err := dockerCredentialHelper(ctx, helper, server)
+		if err == nil {
+			return auth, true
+		}
+
+		log.Debug("docker credential helper has no credentials", slog.String("helper", helper), slog.String("registry", registry), slog.Any("err", err))
+	}
+
+	for key, entry := range config.Auths {
+		if dockerServerHost(key) != dockerServerHost(server) {
+			continue
+		}
+
+		switch {
+		case entry.RegistryToken != "":
+			return Auth{BarerToken: entry.RegistryToken}, true
+		case entry.Username != "" || entry.Password != "":
+			return Auth{UserName: entry.Username, Password: entry.Password}, true
+		case entry.Auth != "":
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				continue
+			}
+
+			userName, password, _ := strings.Cut(string(decoded), ":")
+
+			return Auth{UserName: userName, Password: password}, true
+		}
+	}
+
+	return Auth{}, false
+}
+
+// dockerServerHost reduces the keys of auths, e.g. https://ghcr.io or https://index.docker.io/v1/, to their host.
+func dockerServerHost(server string) string {
+	if _, rest, found := strings.Cut(server, "://"); found {
+		server = rest
+	}
+
+	host, _, _ := strings.Cut(server, "/")
+
+	return strings.ToLower(host)
+}
+
+// dockerCredentialHelper asks docker-credential-<helper> for the credentials of server.
+func dockerCredentialHelper(ctx context.Context, helper, server string) (Auth, error) {
+	var stdout bytes.Buffer
+
+	command := exec.CommandContext(ctx, "docker-credential-"+helper, "get") //nolint:gosec
+	command.Stdin = strings.NewReader(server)
+	command.Stdout = &stdout
+
+	if err := command.Run(); err != nil {
+		return Auth{}, err
+	}
+
+	var response struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return Auth{}, err
+	}
+
+	if response.Username == "<token>" {
+		// Identity tokens are exchanged through the OAuth2 flow of the registry, which is not supported.
+		return Auth{}, &errors.YamllError{Message: "identity tokens are not supported"}
+	}
+
+	return Auth{UserName: response.Username, Password: response.Secret}, nil
+}
+
+// gitCredentialFill asks the git credential helpers configured for the host, without ever prompting.
+func gitCredentialFill(ctx context.Context, target credentialTarget) (Auth, bool) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return Auth{}, false
+	}
+
+	var stdout bytes.Buffer
+
+	command := exec.CommandContext(ctx, "git", "credential", "fill")
+	command.Stdin = strings.NewReader(fmt.Sprintf("protocol=%s\nhost=%s\npath=%s\n\n", target.scheme, target.host, target.path))
+	command.Stdout = &stdout
+	command.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never", "GIT_ASKPASS=", "SSH_ASKPASS=")
+
+	if err := command.Run(); err != nil {
+		return Auth{}, false
+	}
+
+	var auth Auth
+
+	for line := range strings.SplitSeq(stdout.String(), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+
+		switch key {
+		case "username":
+			auth.UserName = value
+		case "password":
+			auth.Password = value
+		}
+	}
+
+	return auth, auth.Password != ""
+}
@@ -0,0 +1,124 @@
+package yamll
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchCredential(t *testing.T) {
+	credentials := []Credential{
+		{Match: "ghcr.io", Auth: Auth{UserName: "host"}},
+		{Match: "https://ghcr.io/company/", Auth: Auth{UserName: "company"}},
+		{Match: "ghcr.io/company/team", Auth: Auth{UserName: "team"}},
+	}
+
+	for target, expected := range map[credentialTarget]string{
+		{host: "ghcr.io", path: "other/config"}:                    "host",
+		{host: "ghcr.io", path: "company/config"}:                  "company",
+		{host: "ghcr.io", path: "company/team/config"}:             "team",
+		{host: "ghcr.io", path: "company/teams/config"}:            "company",
+		{host: "ghcr.io:5000", path: "company/team/conf"}:          "",
+		{scheme: "https", host: "ghcr.io", path: "company/config"}: "company",
+		{scheme: "http", host: "ghcr.io", path: "company/config"}:  "host",
+	} {
+		auth, ok := matchCredential(credentials, target)
+		require.Equal(t, expected != "", ok, target)
+		require.Equal(t, expected, auth.UserName, target)
+	}
+}
+
+func TestNetrcAuth(t *testing.T) {
+	machines := parseNetrc("machine git.internal:8443 login port password p1\n" +
+		"machine git.internal login host password p2 account ignored\n" +
+		"macdef init\n" +
+		"default login anonymous password guest\n")
+
+	auth, ok := netrcAuth(machines, "git.internal:8443")
+	require.True(t, ok)
+	require.Equal(t, Auth{UserName: "port", Password: "p1"}, auth)
+
+	auth, ok = netrcAuth(machines, "git.internal:9443")
+	require.True(t, ok)
+	require.Equal(t, Auth{UserName: "host", Password: "p2"}, auth)
+
+	auth, ok = netrcAuth(machines, "example.com")
+	require.True(t, ok)
+	require.Equal(t, Auth{UserName: "anonymous", Password: "guest"}, auth)
+}
+
+func TestGitCredentialFill(t *testing.T) {
+	home := t.TempDir()
+	gitConfig := filepath.Join(home, ".gitconfig")
+
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", gitConfig)
+
+	target := credentialTarget{kind: TypeGit, scheme: "https", host: "git.internal", path: "org/repo"}
+
+	_, ok := gitCredentialFill(t.Context(), target)
+	require.False(t, ok)
+
+	require.NoError(t, os.WriteFile(gitConfig, []byte(
+		"[credential \"https://git.internal\"]\n\thelper = \"!f() { echo username=ci; echo password=from-helper; }; f\"\n",
+	), 0o600))
+
+	auth, ok := gitCredentialFill(t.Context(), target)
+	require.True(t, ok)
+	require.Equal(t, Auth{UserName: "ci", Password: "from-helper"}, auth)
+
+	// The helper stored the credentials for https, plain http never gets them.
+	target.scheme = "http"
+
+	_, ok = gitCredentialFill(t.Context(), target)
+	require.False(t, ok)
+
+	require.NoError(t, os.WriteFile(gitConfig, []byte(
+		"[credential]\n\thelper = \"!f() { echo username=ci; echo password=from-helper; }; f\"\n",
+	), 0o600))
+
+	store := &credentialStore{resolved: make(map[credentialTarget]*credentialLookup), log: slog.New(slog.DiscardHandler)}
+	require.Nil(t, store.lookup(t.Context(), target))
+
+	store.http = true
+	require.Equal(t, &Auth{UserName: "ci", Password: "from-helper"}, store.lookup(t.Context(), target))
+}
+
+func TestCredentialStoreLooksTargetsUpConcurrently(t *testing.T) {
+	// Each helper answers only once the helper of the other registry runs too, so lookups held behind one lock never finish.
+	seen := t.TempDir()
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker-credential-waiting"), //nolint:gosec
+		[]byte("#!/bin/sh\nread server\ntouch '"+seen+"'/$server\n"+
+			"for i in $(seq 100); do\n  [ -e '"+seen+"'/a.internal ] && [ -e '"+seen+"'/b.internal ] && "+
+			"echo '{\"Username\":\"helper\",\"Secret\":\"'$server'\"}' && exit 0\n  sleep 0.1\ndone\nexit 1\n"), 0o700))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	store := &credentialStore{
+		docker:   dockerConfig{CredHelpers: map[string]string{"a.internal": "waiting", "b.internal": "waiting"}},
+		resolved: make(map[credentialTarget]*credentialLookup),
+		log:      slog.New(slog.DiscardHandler),
+	}
+
+	var waitGroup sync.WaitGroup
+
+	auths := make([]*Auth, 4)
+
+	for index, registry := range []string{"a.internal", "b.internal", "a.internal", "b.internal"} {
+		waitGroup.Go(func() {
+			auths[index] = store.authFor(t.Context(), &Dependency{Path: "oci://" + registry + "/config:v1", Type: TypeOCI})
+		})
+	}
+
+	waitGroup.Wait()
+
+	for index, registry := range []string{"a.internal", "b.internal", "a.internal", "b.internal"} {
+		require.NotNil(t, auths[index], registry)
+		require.Equal(t, Auth{UserName: "helper", Password: registry}, *auths[index])
+	}
+}
@@ -0,0 +1,191 @@
+package yamll_test
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+// isolateCredentials points every credential source at an empty temporary home.
+func isolateCredentials(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("NETRC", filepath.Join(home, ".netrc"))
+	t.Setenv("DOCKER_CONFIG", filepath.Join(home, ".docker"))
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	return home
+}
+
+func writeHomeFile(t *testing.T, home, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(home, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestConfigCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userName, password, _ := r.BasicAuth()
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/team-a/") && userName == "team-a" && password == "secret-a":
+		case !strings.HasPrefix(r.URL.Path, "/team-a/") && userName == "reader" && password == "secret":
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("base: &base\n  user: " + userName + "\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	t.Run("no credentials", func(t *testing.T) {
+		isolateCredentials(t)
+
+		_, err := newImportingConfig(t, server.URL+"/base.yaml").Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "401")
+	})
+
+	t.Run("credentials file picks the longest match", func(t *testing.T) {
+		home := isolateCredentials(t)
+		t.Setenv("TEAM_A_PASSWORD", "secret-a")
+
+		writeHomeFile(t, home, ".config/yamll/credentials.yaml", strings.Join([]string{
+			"credentials:",
+			"  - match: " + host,
+			"    auth:",
+			"      user_name: reader",
+			"      password: secret",
+			"  - match: " + server.URL + "/team-a",
+			"    auth:",
+			"      user_name: team-a",
+			"      password: ${TEAM_A_PASSWORD}",
+		}, "\n"))
+
+		out, err := newImportingConfig(t, server.URL+"/base.yaml").Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "user: reader")
+
+		out, err = newImportingConfig(t, server.URL+"/team-a/base.yaml").Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "user: team-a")
+	})
+
+	t.Run("inline auth wins", func(t *testing.T) {
+		home := isolateCredentials(t)
+		writeHomeFile(t, home, ".config/yamll/credentials.yaml", "credentials:\n  - match: "+host+"\n    auth:\n      user_name: wrong\n")
+
+		out, err := newImportingConfig(t, server.URL+`/base.yaml;{"user_name":"reader","password":"secret"}`).Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "user: reader")
+	})
+
+	t.Run("explicit credentials file must exist", func(t *testing.T) {
+		isolateCredentials(t)
+
+		cfg := newImportingConfig(t, server.URL+"/base.yaml")
+		cfg.CredentialsFile = filepath.Join(t.TempDir(), "missing.yaml")
+
+		_, err := cfg.Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "reading credentials file")
+	})
+
+	t.Run("netrc", func(t *testing.T) {
+		home := isolateCredentials(t)
+		writeHomeFile(t, home, ".netrc", "# ci credentials\nmachine example.com login nobody password nothing\nmachine 127.0.0.1\n  login reader\n  password secret\n")
+
+		// The server speaks plain http, which netrc credentials are only sent to when asked to.
+		_, err := newImportingConfig(t, server.URL+"/base.yaml").Yaml(t.Context())
+		require.ErrorContains(t, err, "401")
+
+		cfg := newImportingConfig(t, server.URL+"/base.yaml")
+		cfg.HTTPCredentials = true
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "user: reader")
+	})
+
+	t.Run("credentials file entries for https", func(t *testing.T) {
+		home := isolateCredentials(t)
+		writeHomeFile(t, home, ".config/yamll/credentials.yaml",
+			"credentials:\n  - match: https://"+host+"\n    auth:\n      user_name: reader\n      password: secret\n")
+
+		_, err := newImportingConfig(t, server.URL+"/base.yaml").Yaml(t.Context())
+		require.ErrorContains(t, err, "401")
+	})
+}
+
+func TestConfigDockerCredentials(t *testing.T) {
+	var authorizations, requests []string
+
+	yamll.SetOCIHTTPClientForTest(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			authorizations = append(authorizations, req.Header.Get("Authorization"))
+			requests = append(requests, req.URL.Host+req.URL.Path)
+
+			body := "base: &base\n  registry: ok\n"
+			if strings.HasSuffix(req.URL.Path, "/manifests/v1") {
+				body = `{"schemaVersion":2,"layers":[` + ociLayer("application/yaml", "base: &base\n  registry: ok\n", "") + `]}`
+			}
+
+			return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body))}, nil
+		}),
+	})
+	t.Cleanup(func() {
+		yamll.SetOCIHTTPClientForTest(nil)
+	})
+
+	basic := func(userName, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(userName+":"+password))
+	}
+
+	t.Run("auths", func(t *testing.T) {
+		home := isolateCredentials(t)
+		writeHomeFile(t, home, ".docker/config.json",
+			`{"auths":{"https://ghcr.io":{"auth":"`+base64.StdEncoding.EncodeToString([]byte("robot:token"))+`"}}}`)
+
+		authorizations = nil
+
+		_, err := newImportingConfig(t, "oci://ghcr.io/company/config:v1").Yaml(t.Context())
+		require.NoError(t, err)
+		require.Equal(t, []string{basic("robot", "token"), basic("robot", "token")}, authorizations)
+		require.Equal(t, "ghcr.io/v2/company/config/manifests/v1", requests[0])
+	})
+
+	t.Run("credential helper", func(t *testing.T) {
+		home := isolateCredentials(t)
+		writeHomeFile(t, home, ".docker/config.json", `{"credHelpers":{"ghcr.io":"fake"}}`)
+
+		binDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker-credential-fake"), //nolint:gosec
+			[]byte("#!/bin/sh\nread server\necho '{\"ServerURL\":\"'$server'\",\"Username\":\"helper\",\"Secret\":\"from-'$server'\"}'\n"), 0o700))
+		t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+		authorizations = nil
+
+		_, err := newImportingConfig(t, "oci://ghcr.io/company/config:v1").Yaml(t.Context())
+		require.NoError(t, err)
+		require.Equal(t, basic("helper", "from-ghcr.io"), authorizations[0])
+	})
+}
@@ -2,12 +2,14 @@ package yamll
 
 import (
 	"bufio"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -31,6 +33,15 @@ type Dependency struct {
 	Type        string `json:"type,omitempty" yaml:"type,omitempty"`
 	Auth        *Auth  `json:"auth,omitempty" yaml:"auth,omitempty"`
 	excludePath string
+	statement   string
+	line        int
+	cache       *Cache
+	gitRepos    *gitRepositories
+	retry       retryPolicy
+	// strictHostKeys rejects ssh servers missing from known_hosts.
+	strictHostKeys bool
+	// excludePatterns are the ##++!<pattern> exclusions of the importing file, dropped from pattern imports.
+	excludePatterns []string
 }
 
 // Auth holds the authentication information to resolve the remote yaml files.
@@ -43,12 +54,39 @@ type Auth struct {
 	BarerToken string `json:"barer_token,omitempty" yaml:"barer_token,omitempty"`
 	// CaContent, is content of CA bundle if in case you needs to connect to remote server via CA auth.
 	CaContent string `json:"ca_content,omitempty" yaml:"ca_content,omitempty"`
-	// SSHKey, Path to SSH key to be used while pulling git repository.
+	// CaFile, path to a CA bundle, or the PEM itself, trusted in addition to the system roots.
+	CaFile string `json:"ca_file,omitempty" yaml:"ca_file,omitempty"`
+	// ClientCert, path to a client certificate, or the PEM itself, presented to servers requiring mutual TLS.
+	ClientCert string `json:"client_cert,omitempty" yaml:"client_cert,omitempty"`
+	// ClientKey, path to the key of ClientCert, or the PEM itself.
+	ClientKey string `json:"client_key,omitempty" yaml:"client_key,omitempty"`
+	// InsecureSkipVerify disables verification of the server certificate, use it only against servers you trust.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+	// SSHKey, Path to SSH key to be used while pulling git repository, or the key itself.
+	// The keys of the ssh-agent are used when it is not set.
 	SSHKey string `json:"ssh_key,omitempty" yaml:"ssh_key,omitempty"`
+	// SSHKeyPassphrase decrypts SSHKey, usually substituted from an environment variable. Defaults to $YAMLL_SSH_KEY_PASSPHRASE.
+	SSHKeyPassphrase string `json:"ssh_key_passphrase,omitempty" yaml:"ssh_key_passphrase,omitempty"`
+	// KnownHosts, paths to the known_hosts files the ssh server is verified against, separated like $PATH.
+	// Defaults to $SSH_KNOWN_HOSTS, else ~/.ssh/known_hosts and /etc/ssh/ssh_known_hosts.
+	KnownHosts string `json:"known_hosts,omitempty" yaml:"known_hosts,omitempty"`
 }
 
 // ResolveDependencies addresses the dependencies of YAML imports specified in the YAML files.
-func (cfg *Config) ResolveDependencies(routes map[string]*YamlData, dependenciesPath ...*Dependency) (map[string]*YamlData, error) {
+// Remote imports are fetched under ctx, which bounds the whole resolution together with Config.Timeout.
+func (cfg *Config) ResolveDependencies(ctx context.Context, routes map[string]*YamlData, dependenciesPath ...*Dependency) (map[string]*YamlData, error) {
+	ctx, cancel := cfg.withTimeout(ctx)
+	defer cancel()
+
+	routes, err := cfg.resolveDependencies(ctx, routes, dependenciesPath...)
+	if err != nil {
+		return nil, cfg.contextError(ctx, err)
+	}
+
+	return routes, nil
+}
+
+func (cfg *Config) resolveDependencies(ctx context.Context, routes map[string]*YamlData, dependenciesPath ...*Dependency) (map[string]*YamlData, error) {
 	var rootFile bool
 	if !cfg.Root {
 		rootFile = true
@@ -59,36 +97,33 @@ func (cfg *Config) ResolveDependencies(routes map[string]*YamlData, dependencies
 		return nil, err
 	}
 
-	for fileHierarchy, dependencyPath := range dependenciesPath {
-		if dependencyPath == nil {
-			return nil, &errors.YamllError{Message: "dependency path is nil"}
-		}
-
-		originalSource := dependencyPath.Path
+	cfg.canonicalizeDependencies(routes, dependenciesPath)
 
-		if lockEntries != nil && dependencyPath.Type == TypeGit {
-			if entry, ok := lockEntries[lockEntryKey(dependencyPath.Path, "")]; ok && entry.GitCommit != "" {
-				dependencyPath.Path = pinGitImportToCommit(dependencyPath.Path, entry.GitCommit)
-				dependencyPath.IdentifyType()
-			}
-		}
+	fetched, err := cfg.fetchDependencies(ctx, routes, lockEntries, dependenciesPath)
+	if err != nil {
+		return nil, err
+	}
 
+	for fileHierarchy, dependencyPath := range dependenciesPath {
 		if _, ok := routes[dependencyPath.Path]; ok {
 			continue
 		}
 
-		yamlFile, err := cfg.readDataWithProfile(dependencyPath)
-		if err != nil {
+		originalSource, yamlFile := fetched[fileHierarchy].source, fetched[fileHierarchy].file
+		if err = fetched[fileHierarchy].err; err != nil {
 			return nil, &errors.YamllError{Message: fmt.Sprintf("reading YAML file errored with: '%v'", err)}
 		}
 
-		if err = validateLockedDependency(lockEntries, originalSource, yamlFile); err != nil {
-			return nil, err
+		// Lock update reads what it unlocked, and any import new to the lock these bring along, without an entry to validate.
+		if cfg.unlock == nil || hasLockEntry(lockEntries, originalSource) {
+			if err = validateLockedDependency(lockEntries, originalSource, yamlFile); err != nil {
+				return nil, err
+			}
 		}
 
 		cfg.log.Debug("the absolute path of the file which was read", slog.String("path", yamlFile.Name))
 
-		dependencies, yamlData, err := cfg.extractDependencies(yamlFile.Data, yamlFile.Name)
+		dependencies, yamlData, err := cfg.extractDependencies(yamlFile, dependencyPath)
 		if err != nil {
 			return nil, err
 		}
@@ -105,6 +140,7 @@ func (cfg *Config) ResolveDependencies(routes map[string]*YamlData, dependencies
 		routes[dependencyPath.Path] = &YamlData{
 			Root:       rootFile,
 			File:       dependencyPath.Path,
+			ID:         dependencyID(dependencyPath.Path),
 			DataRaw:    yamlData,
 			Dependency: dependencies,
 			Index:      fileHierarchy,
@@ -112,7 +148,7 @@ func (cfg *Config) ResolveDependencies(routes map[string]*YamlData, dependencies
 		}
 
 		if len(dependencies) != 0 {
-			dependencyRoutes, err := cfg.ResolveDependencies(routes, dependencies...)
+			dependencyRoutes, err := cfg.resolveDependencies(ctx, routes, dependencies...)
 			if err != nil {
 				return nil, err
 			}
@@ -126,11 +162,41 @@ func (cfg *Config) ResolveDependencies(routes map[string]*YamlData, dependencies
 	return routes, nil
 }
 
+// canonicalizeDependencies makes every spelling of an already known source, e.g. ./base.yaml and its absolute path,
+// use the route key it was first seen under, so that each source is fetched and listed once.
+// A resolution starting from empty routes starts afresh.
+func (cfg *Config) canonicalizeDependencies(routes map[string]*YamlData, dependencies []*Dependency) {
+	if len(routes) == 0 || cfg.identities == nil {
+		cfg.identities = make(map[string]string)
+	}
+
+	for _, dependency := range dependencies {
+		id := dependencyID(dependency.Path)
+
+		key, ok := cfg.identities[id]
+		if !ok {
+			cfg.identities[id] = dependency.Path
+
+			continue
+		}
+
+		if key != dependency.Path {
+			cfg.log.Debug("import refers to an already known source", slog.String("import", dependency.Path), slog.String("source", key))
+
+			dependency.Path = key
+		}
+	}
+}
+
 func validateLockedDependency(lockEntries map[string]LockEntry, source string, file File) error {
 	if len(lockEntries) == 0 {
 		return nil
 	}
 
+	if err := validateLockedManifest(lockEntries, source, file.Meta.ManifestDigest); err != nil {
+		return err
+	}
+
 	if len(file.Source) == 0 {
 		return validateSingleLockedFile(lockEntries, source, "", file)
 	}
@@ -144,6 +210,31 @@ func validateLockedDependency(lockEntries map[string]LockEntry, source string, f
 	return nil
 }
 
+func hasLockEntry(lockEntries map[string]LockEntry, source string) bool {
+	for _, entry := range lockEntries {
+		if entry.Source == source {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateLockedManifest reports an OCI import that was read at another manifest than the locked one.
+func validateLockedManifest(lockEntries map[string]LockEntry, source, manifestDigest string) error {
+	if manifestDigest == "" {
+		return nil
+	}
+
+	for _, entry := range lockEntries {
+		if entry.Source == source && entry.ManifestDigest != "" && entry.ManifestDigest != manifestDigest {
+			return ociTagMovedError(source, entry.ManifestDigest, manifestDigest)
+		}
+	}
+
+	return nil
+}
+
 func validateSingleLockedFile(lockEntries map[string]LockEntry, source, patternFile string, file File) error {
 	entry, ok := lockEntries[lockEntryKey(source, patternFile)]
 	if !ok {
@@ -178,15 +269,45 @@ func checksumForContent(content string) string {
 	return hex.EncodeToString(sum[:])
 }
 
-func (cfg *Config) readDataWithProfile(dependencyPath *Dependency) (File, error) {
+func (cfg *Config) readDataWithProfile(ctx context.Context, dependencyPath *Dependency, source string, locked *LockEntry) (File, error) {
+	if cfg.Vendor && isRemoteType(dependencyPath.Type) {
+		return cfg.readVendored(dependencyPath, source)
+	}
+
+	if yamlFile, ok := cfg.readLockedFromCache(dependencyPath, locked); ok {
+		return yamlFile, nil
+	}
+
+	// Pattern imports the lock file pinned match the same files every time, their lock entries validate what is read.
+	if dependencyPath.Path != source && isPatternImport(source) && isRemoteType(dependencyPath.Type) {
+		if yamlFile, ok := cfg.readRemembered(dependencyPath); ok {
+			return yamlFile, nil
+		}
+	}
+
+	if cfg.Offline && isRemoteType(dependencyPath.Type) && !isLocalGitImport(dependencyPath) && !isLocalArchiveImport(dependencyPath) {
+		return cfg.readOffline(dependencyPath, source, locked)
+	}
+
 	readStart := time.Now()
 
-	yamlFile, err := dependencyPath.ReadData(cfg.Merge, cfg.log)
+	dependencyPath.cache = cfg.cache()
+	dependencyPath.gitRepos = cfg.gitRepositories()
+	dependencyPath.retry = cfg.retryPolicy()
+	dependencyPath.strictHostKeys = cfg.StrictHostKeyChecking
+
+	if dependencyPath.Auth == nil && cfg.credentials != nil {
+		dependencyPath.Auth = cfg.credentials.authFor(ctx, dependencyPath)
+	}
+
+	yamlFile, err := dependencyPath.ReadData(ctx, cfg.Merge, cfg.log)
 	if err != nil {
 		return File{}, err
 	}
 
-	if cfg.Profile && cfg.profile != nil && (dependencyPath.Type == TypeURL || dependencyPath.Type == TypeGit) {
+	cfg.rememberImport(dependencyPath, yamlFile)
+
+	if cfg.Profile && cfg.profile != nil && isRemoteType(dependencyPath.Type) {
 		cfg.profile.addRemoteFetch(time.Since(readStart))
 	}
 
@@ -200,19 +321,12 @@ func pinGitImportToCommit(source, commit string) string {
 		return source
 	}
 
-	withoutPrefix := strings.TrimPrefix(source, TypeGit)
-
-	beforeAt, afterAt, found := strings.Cut(withoutPrefix, "@")
-	if !found {
-		return source
-	}
-
-	_, afterRef, found := strings.Cut(afterAt, "?")
-	if !found {
+	repository, _, query, err := splitGitImport(source)
+	if err != nil {
 		return source
 	}
 
-	return TypeGit + beforeAt + "@" + commit + "?" + afterRef
+	return TypeGit + repository + "@" + commit + "?" + query
 }
 
 // PinGitImportToCommitForTest is exported only for tests.
@@ -303,52 +417,106 @@ func normalizeGitShorthand(path string) (string, bool) {
 }
 
 // ReadData actually reads the data from the identified import.
-func (dependency *Dependency) ReadData(effective bool, log *slog.Logger) (File, error) {
+func (dependency *Dependency) ReadData(ctx context.Context, effective bool, log *slog.Logger) (File, error) {
 	log.Debug("dependency file type identified", slog.String("type", dependency.Type), slog.Any("path", dependency.Path))
 
 	if effective {
 		log.Debug("reading yaml data in effective mode")
 	}
 
-	switch dependency.Type {
-	case TypeURL:
-		return dependency.URL(log)
-	case TypeGit:
-		return dependency.Git(log)
-	case TypeOCI:
-		return dependency.OCI(log)
-	case TypeFile:
-		return dependency.File(log)
-	case TypeFilePattern:
-		return dependency.FilePattern(log)
-	default:
+	source, ok := sourceFor(dependency.Type)
+	if !ok {
 		return File{}, &errors.YamllError{Message: fmt.Sprintf("reading data from of type '%s' is not supported", dependency.Type)}
 	}
+
+	return source.Read(ctx, dependency, log)
+}
+
+// extractDependencies parses the dependencies from YAML file data, resolving local imports relative to the importer.
+// The files a pattern import matched are scanned one by one, their imports resolving relative to the file declaring them.
+func (cfg *Config) extractDependencies(importerFile File, importer *Dependency) ([]*Dependency, string, error) {
+	var cleaned strings.Builder
+
+	if len(importerFile.Source) == 0 {
+		dependencies, err := cfg.scanDependencies(importerFile, importer, &cleaned)
+
+		return dependencies, strings.TrimSpace(cleaned.String()), err
+	}
+
+	var dependencies []*Dependency
+
+	for _, source := range importerFile.Source {
+		// Separated the way patternFile concatenates them.
+		cleaned.WriteByte('\n')
+
+		sourceDependencies, err := cfg.scanDependencies(source, patternSourceImporter(importer, source), &cleaned)
+		if err != nil {
+			return nil, "", err
+		}
+
+		dependencies = append(dependencies, sourceDependencies...)
+	}
+
+	return dependencies, strings.TrimSpace(cleaned.String()), nil
 }
 
-// extractDependencies parses the dependencies from YAML file data.
-func (cfg *Config) extractDependencies(yamlFileData, sourcePath string) ([]*Dependency, string, error) {
+// patternSourceImporter is the import of the single file a pattern import matched, e.g. envs/dev/app.yaml of envs/*/app.yaml.
+func patternSourceImporter(importer *Dependency, source File) *Dependency {
+	if importer == nil {
+		return nil
+	}
+
+	sourceImporter := &Dependency{Path: source.Name, Type: importer.Type, Auth: importer.Auth}
+	if importer.Type == TypeFilePattern {
+		sourceImporter.Type = TypeFile
+	}
+
+	return sourceImporter
+}
+
+// scanDependencies parses the imports and exclusions of a single file, writing the rest of its lines to cleaned.
+func (cfg *Config) scanDependencies(importerFile File, importer *Dependency, cleaned *strings.Builder) ([]*Dependency, error) {
 	var (
 		dependencies []*Dependency
-		cleaned      strings.Builder
+		exclusions   []string
 	)
 
-	scanner := bufio.NewScanner(strings.NewReader(yamlFileData))
+	scanner := bufio.NewScanner(strings.NewReader(importerFile.Data))
+
+	lineNumber := 0
 
 	for scanner.Scan() {
 		line := scanner.Text()
 		trimmed := strings.TrimSpace(line)
+		lineNumber++
+
+		if strings.HasPrefix(trimmed, "##++!") {
+			exclusion, err := cfg.exclusionPattern(importer, importerFile, trimmed)
+			if err != nil {
+				return nil, err
+			}
+
+			exclusions = append(exclusions, exclusion)
+
+			continue
+		}
 
 		if strings.HasPrefix(trimmed, "##++") {
 			dependency, err := cfg.GetDependencyData(trimmed)
 			if err != nil {
-				return nil, "", err
+				return nil, err
+			}
+
+			dependency.statement, dependency.line = trimmed, lineNumber
+
+			if err = cfg.resolveImportPath(importer, importerFile, dependency); err != nil {
+				return nil, err
 			}
 
 			dependencies = append(dependencies, dependency)
 
-			if dependency.Type == TypeFilePattern && sourcePath != "" {
-				dependency.excludePath = sourcePath
+			if (dependency.Type == TypeFilePattern || dependency.Type == TypeDirectory) && importerFile.Name != "" {
+				dependency.excludePath = importerFile.Name
 			}
 
 			continue
@@ -359,27 +527,85 @@ func (cfg *Config) extractDependencies(yamlFileData, sourcePath string) ([]*Depe
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	return dependencies, strings.TrimSpace(cleaned.String()), nil
+	for _, dependency := range dependencies {
+		if dependency.Type == TypeFilePattern {
+			dependency.excludePatterns = exclusions
+		}
+	}
+
+	return dependencies, nil
+}
+
+// exclusionPattern resolves ##++!<pattern> the way a local import is, into the absolute pattern
+// of the files dropped from every pattern import of the same file.
+func (cfg *Config) exclusionPattern(importer *Dependency, importerFile File, statement string) (string, error) {
+	exclusion := &Dependency{Path: strings.TrimSpace(strings.TrimPrefix(statement, "##++!")), Type: TypeFilePattern}
+
+	if exclusion.Path == "" {
+		return "", &errors.YamllError{Message: "exclusion pattern cannot be empty"}
+	}
+
+	if importer != nil && isRemoteType(importer.Type) {
+		return "", &errors.YamllError{Message: fmt.Sprintf(
+			"exclusion '%s' of remote file '%s' is not supported, exclusions apply to local pattern imports", exclusion.Path, importer.Path,
+		)}
+	}
+
+	if err := cfg.resolveImportPath(importer, importerFile, exclusion); err != nil {
+		return "", err
+	}
+
+	absExclusion, err := filepath.Abs(exclusion.Path)
+	if err != nil {
+		return "", err
+	}
+
+	if err = validateGlob(filepath.ToSlash(absExclusion)); err != nil {
+		return "", &errors.YamllError{Message: fmt.Sprintf("error matching exclusion pattern: '%v'", err)}
+	}
+
+	return absExclusion, nil
 }
 
+// IdentifyType sets Type to the first registered Source matching the import, see Sources.
 func (dependency *Dependency) IdentifyType() {
-	switch {
-	case isPattern(dependency.Path):
-		dependency.Type = TypeFilePattern
-	case strings.HasPrefix(dependency.Path, TypeOCI):
-		dependency.Type = TypeOCI
-	case strings.HasPrefix(dependency.Path, TypeURL):
-		dependency.Type = TypeURL
-	case strings.HasPrefix(dependency.Path, TypeGit):
-		dependency.Type = TypeGit
-	default:
-		dependency.Type = TypeFile
+	for _, source := range Sources() {
+		if source.Match(dependency.Path) {
+			dependency.Type = source.Type()
+
+			return
+		}
 	}
+
+	dependency.Type = TypeFile
 }
 
 func isPattern(input string) bool {
-	return strings.ContainsAny(input, "*?[]") && !strings.Contains(input, "://")
+	return strings.ContainsAny(input, "*?[]{}") && !strings.Contains(input, "://") && !isDirectoryImport(input) && !isArchiveImport(input)
+}
+
+// isPatternImport reports whether the import expands to several files, a local pattern
+// or a git or OCI import whose ?path= is a pattern, e.g. git+https://host/org/repo@v1?path=configs/*.yaml.
+func isPatternImport(source string) bool {
+	switch {
+	case isPattern(source):
+		return true
+	case isArchiveImport(source):
+		_, member, err := splitArchiveImport(source)
+
+		return err == nil && isPattern(member)
+	case strings.HasPrefix(source, TypeGit):
+		meta, err := parseGitImport(source)
+
+		return err == nil && isPattern(meta.path)
+	case strings.HasPrefix(source, TypeOCI):
+		ref, err := parseOCIReference(source)
+
+		return err == nil && isPattern(ref.Path)
+	default:
+		return false
+	}
 }
@@ -70,7 +70,7 @@ func TestDependency_ReadData(t *testing.T) {
 
 		cfg.SetLogger()
 
-		out, err := dependency.ReadData(false, cfg.GetLogger())
+		out, err := dependency.ReadData(t.Context(), false, cfg.GetLogger())
 		require.NoError(t, err)
 		require.Equal(t, server.URL, out.Name)
 		require.Equal(t, "name: yamll", out.Data)
@@ -88,7 +88,7 @@ func TestDependency_ReadData(t *testing.T) {
 		cfg := yamll.New(false, "DEBUG", "")
 		cfg.SetLogger()
 
-		_, err := dependency.ReadData(false, cfg.GetLogger())
+		_, err := dependency.ReadData(t.Context(), false, cfg.GetLogger())
 		require.Error(t, err)
 	})
 }
@@ -104,7 +104,7 @@ func TestConfig_ResolveDependencies2(t *testing.T) {
 		cfg := yamll.New(false, "DEBUG", "internal/fixtures/base.yaml")
 		cfg.SetLogger()
 
-		dependencyRoutes, err := cfg.ResolveDependencies(make(map[string]*yamll.YamlData), dependency...)
+		dependencyRoutes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), dependency...)
 		require.NoError(t, err)
 		require.NotContains(t, dependencyRoutes["internal/fixtures/base.yaml"].DataRaw, "##++")
 	})
@@ -134,14 +134,14 @@ func TestConfig_YamlWithFilePattern(t *testing.T) {
 	cfg := yamll.New(false, "DEBUG", "---", rootFile)
 	cfg.SetLogger()
 
-	out, err := cfg.Yaml()
+	out, err := cfg.Yaml(t.Context())
 	require.NoError(t, err)
 	require.Contains(t, string(out), "one: 1")
 	require.Contains(t, string(out), "two: 2")
 	require.Contains(t, string(out), "root: true")
 	require.Equal(t, 1, strings.Count(string(out), "root: true"))
 
-	repeatedOut, err := cfg.Yaml()
+	repeatedOut, err := cfg.Yaml(t.Context())
 	require.NoError(t, err)
 	require.Equal(t, out, repeatedOut)
 
@@ -149,12 +149,68 @@ func TestConfig_YamlWithFilePattern(t *testing.T) {
 		cfg := yamll.New(false, "DEBUG", "---", rootFile)
 		cfg.SetLogger()
 
-		nextOut, err := cfg.Yaml()
+		nextOut, err := cfg.Yaml(t.Context())
 		require.NoError(t, err)
 		require.Equal(t, out, nextOut)
 	}
 }
 
+func TestConfig_YamlWithRecursivePatternAndExclusions(t *testing.T) {
+	dir := t.TempDir()
+	rootFile := filepath.Join(dir, "root.yaml")
+
+	files := map[string]string{
+		"envs/b.yaml":                "b: 1\n",
+		"envs/a/app.yaml":            "a_app: 1\n",
+		"envs/a/secret-db.yaml":      "secret: 1\n",
+		"envs/prod/eu/app.yaml":      "prod_eu: 1\n",
+		"envs/qa/app.yaml":           "qa: 1\n",
+		"envs/prod/eu/notes.txt":     "notes: 1\n",
+		"envs/prod/eu/secret-x.yaml": "secret_x: 1\n",
+	}
+
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	require.NoError(t, os.WriteFile(rootFile, []byte(strings.Join([]string{
+		"##++envs/**/*.yaml",
+		"##++!envs/**/secret*.yaml",
+		"##++!envs/{qa,dev}/*.yaml",
+		"root: true",
+		"",
+	}, "\n")), 0o600))
+
+	cfg := yamll.New(false, "DEBUG", "---", rootFile)
+	cfg.SetLogger()
+	cfg.NoLock = true
+
+	routes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+	require.NoError(t, err)
+
+	route := routes[filepath.Join(dir, "envs", "**", "*.yaml")]
+	require.NotNil(t, route)
+
+	matched := make([]string, 0, len(route.SourceFile))
+	for _, src := range route.SourceFile {
+		matched = append(matched, src.Name)
+	}
+
+	require.Equal(t, []string{
+		filepath.Join(dir, "envs", "a", "app.yaml"),
+		filepath.Join(dir, "envs", "b.yaml"),
+		filepath.Join(dir, "envs", "prod", "eu", "app.yaml"),
+	}, matched)
+
+	out, err := cfg.Yaml(t.Context())
+	require.NoError(t, err)
+	require.Less(t, strings.Index(string(out), "a_app: 1"), strings.Index(string(out), "b: 1"))
+	require.Less(t, strings.Index(string(out), "b: 1"), strings.Index(string(out), "prod_eu: 1"))
+	require.NotContains(t, string(out), "secret")
+	require.NotContains(t, string(out), "qa: 1")
+}
+
 func TestConfig_YamlBuildWithPatternKeepsFirstAnchor(t *testing.T) {
 	dir := t.TempDir()
 	rootFile := filepath.Join(dir, "root.yaml")
@@ -186,7 +242,7 @@ func TestConfig_YamlBuildWithPatternKeepsFirstAnchor(t *testing.T) {
 	cfg := yamll.New(false, "DEBUG", "---", rootFile)
 	cfg.SetLogger()
 
-	out, err := cfg.YamlBuild()
+	out, err := cfg.YamlBuild(t.Context())
 	require.NoError(t, err)
 	require.Contains(t, string(out), "movies:")
 	require.Contains(t, string(out), "- animation")
@@ -213,7 +269,7 @@ func TestConfig_YamlBuildKeepsRootKeyOrder(t *testing.T) {
 	cfg := yamll.New(false, "DEBUG", "---", rootFile)
 	cfg.SetLogger()
 
-	out, err := cfg.YamlBuild()
+	out, err := cfg.YamlBuild(t.Context())
 	require.NoError(t, err)
 	require.Less(t, strings.Index(string(out), "third:"), strings.Index(string(out), "first:"))
 	require.Less(t, strings.Index(string(out), "first:"), strings.Index(string(out), "second:"))
@@ -242,11 +298,11 @@ func TestConfig_TraceFindsDirectAndMergedOrigins(t *testing.T) {
 	cfg := yamll.New(false, "DEBUG", "---", rootFile)
 	cfg.SetLogger()
 
-	directOrigin, err := cfg.Trace("service.name")
+	directOrigin, err := cfg.Trace(t.Context(), "service.name")
 	require.NoError(t, err)
 	require.Equal(t, displayTestPath(rootFile)+":4", directOrigin.Origin)
 
-	mergedOrigin, err := cfg.Trace("service.metadata.labels")
+	mergedOrigin, err := cfg.Trace(t.Context(), "service.metadata.labels")
 	require.NoError(t, err)
 	require.Equal(t, displayTestPath(baseFile)+":3", mergedOrigin.Origin)
 }
@@ -272,7 +328,7 @@ func TestConfig_TraceFindsPatternFileOrigin(t *testing.T) {
 	cfg := yamll.New(false, "DEBUG", "---", rootFile)
 	cfg.SetLogger()
 
-	origin, err := cfg.Trace("base.base_name")
+	origin, err := cfg.Trace(t.Context(), "base.base_name")
 	require.NoError(t, err)
 	require.Equal(t, displayTestPath(baseFile)+":2", origin.Origin)
 }
@@ -297,7 +353,7 @@ func TestConfig_LockGeneratesEntries(t *testing.T) {
 	cfg := yamll.New(false, "DEBUG", "---", rootFile)
 	cfg.SetLogger()
 
-	out, err := cfg.Lock()
+	out, err := cfg.Lock(t.Context())
 	require.NoError(t, err)
 	require.Contains(t, string(out), "version:")
 	require.Contains(t, string(out), "entries:")
@@ -310,4 +366,103 @@ func TestPinGitImportToCommit(t *testing.T) {
 		out := yamll.PinGitImportToCommitForTest(in, "deadbeef")
 		require.Equal(t, "git+https://example.com/org/repo@deadbeef?path=base.yaml", out)
 	})
+
+	t.Run("keeps the user of ssh imports", func(t *testing.T) {
+		in := "git+ssh://git@github.com:org/repo@main?path=base.yaml"
+		out := yamll.PinGitImportToCommitForTest(in, "deadbeef")
+		require.Equal(t, "git+ssh://git@github.com:org/repo@deadbeef?path=base.yaml", out)
+	})
+}
+
+func TestConfigResolveDependenciesRelativeToImporter(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "libs", "shared"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "configs"), 0o755))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "root.yaml"), []byte("##++libs/app.yaml\nroot: *app\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "libs", "app.yaml"),
+		[]byte("##++shared/base.yaml\n##++//configs/defaults.yaml\napp: &app\n  <<: *base\n  defaults: *defaults\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "libs", "shared", "base.yaml"), []byte("base: &base\n  name: base\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "configs", "defaults.yaml"), []byte("defaults: &defaults\n  replicas: 2\n"), 0o600))
+
+	render := func(workingDir, rootFile string) (yamll.Yaml, map[string]*yamll.YamlData) {
+		t.Chdir(workingDir)
+
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+
+		routes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+		require.NoError(t, err)
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+
+		return out, routes
+	}
+
+	fromProject, routes := render(dir, "root.yaml")
+	require.Contains(t, routes, filepath.Join("libs", "shared", "base.yaml"))
+	require.Contains(t, routes, filepath.Join("configs", "defaults.yaml"))
+	require.Contains(t, string(fromProject), "replicas: 2")
+
+	fromLibs, _ := render(filepath.Join(dir, "libs"), filepath.Join("..", "root.yaml"))
+	require.Equal(t, withoutSourceComments(fromProject), withoutSourceComments(fromLibs))
+
+	fromElsewhere, _ := render(t.TempDir(), filepath.Join(dir, "root.yaml"))
+	require.Equal(t, withoutSourceComments(fromProject), withoutSourceComments(fromElsewhere))
+}
+
+func TestConfigResolveDependenciesRelativeToPatternMatch(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(t.TempDir())
+
+	for env, replicas := range map[string]string{"dev": "1", "prod": "3"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "envs", env), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "envs", env, "app.yaml"),
+			[]byte("##++common.yaml\n"+env+": &"+env+"\n  <<: *"+env+"_common\n"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "envs", env, "common.yaml"),
+			[]byte(env+"_common: &"+env+"_common\n  replicas: "+replicas+"\n"), 0o600))
+	}
+
+	rootFile := filepath.Join(dir, "root.yaml")
+	require.NoError(t, os.WriteFile(rootFile, []byte("##++envs/*/app.yaml\napp:\n  dev: *dev\n  prod: *prod\n"), 0o600))
+
+	cfg := yamll.New(false, "DEBUG", "---", rootFile)
+	cfg.SetLogger()
+	cfg.NoLock = true
+
+	routes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+	require.NoError(t, err)
+	require.Contains(t, routes, filepath.Join(dir, "envs", "dev", "common.yaml"))
+	require.Contains(t, routes, filepath.Join(dir, "envs", "prod", "common.yaml"))
+
+	pattern := routes[filepath.Join(dir, "envs", "*", "app.yaml")]
+	require.NotNil(t, pattern)
+	require.Equal(t, "dev: &dev\n  <<: *dev_common\n\nprod: &prod\n  <<: *prod_common", pattern.DataRaw)
+
+	buildCfg := yamll.New(false, "DEBUG", "---", rootFile)
+	buildCfg.SetLogger()
+	buildCfg.NoLock = true
+
+	out, err := buildCfg.Yaml(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, string(out), "replicas: 1")
+	require.Contains(t, string(out), "replicas: 3")
+}
+
+// withoutSourceComments drops the "# Source:" headers, which name files the way they were reached.
+func withoutSourceComments(out yamll.Yaml) string {
+	lines := strings.Split(string(out), "\n")
+	kept := lines[:0]
+
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "# Source:") {
+			kept = append(kept, line)
+		}
+	}
+
+	return strings.Join(kept, "\n")
 }
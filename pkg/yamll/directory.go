@@ -0,0 +1,288 @@
+package yamll
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+// TypeDirectory is the type of directory imports, ##++conf.d/ or ##++conf.d/?recursive=true&ext=yaml&order=lexical.
+const TypeDirectory = "directory"
+
+const (
+	directoryOrderNumeric = "numeric"
+	directoryOrderLexical = "lexical"
+)
+
+// directoryOptions are the settings a directory import takes from its query.
+type directoryOptions struct {
+	recursive  bool
+	extensions []string
+	order      string
+}
+
+// isDirectoryImport reports whether the import names a local directory, spelled with a trailing slash.
+func isDirectoryImport(input string) bool {
+	if strings.Contains(input, "://") {
+		return false
+	}
+
+	dir, _, _ := strings.Cut(input, "?")
+
+	return strings.HasSuffix(dir, "/") || strings.HasSuffix(dir, string(os.PathSeparator))
+}
+
+// splitDirectoryImport splits a directory import into its directory and query.
+func splitDirectoryImport(input string) (string, string) {
+	dir, query, _ := strings.Cut(input, "?")
+
+	return dir, query
+}
+
+// directoryImport spells the directory and query back as a directory import.
+func directoryImport(dir, query string) string {
+	dir = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(dir)), "/") + "/"
+	if query != "" {
+		dir += "?" + query
+	}
+
+	return dir
+}
+
+// Directory lists every YAML file of the directory import, each as an import of its own relative to the directory,
+// so that the files stay separate nodes of the dependency graph. Its checksum covers the names and content of the files,
+// which keeps the lock of a directory import valid wherever the checkout is.
+func (dependency *Dependency) Directory(ctx context.Context, log *slog.Logger) (File, error) {
+	dir, query := splitDirectoryImport(dependency.Path)
+
+	options, err := parseDirectoryOptions(query)
+	if err != nil {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf("directory import '%s': %v", dependency.Path, err)}
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return File{}, err
+	}
+
+	files, err := dependency.directoryFiles(ctx, absDir, options, log)
+	if err != nil {
+		return File{}, err
+	}
+
+	log.Debug("the files of the directory are", slog.String("directory", dependency.Path), slog.Any("files", files))
+
+	var imports, listing strings.Builder
+
+	for _, file := range files {
+		member, err := filepath.Rel(absDir, file)
+		if err != nil {
+			return File{}, err
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return File{}, &errors.YamllError{Message: fmt.Sprintf("reading directory import errored with: '%v'", err)}
+		}
+
+		// Spelled ./<file>, the import reads as a file relative to the directory and never as a shorthand of a remote one.
+		member = "./" + filepath.ToSlash(member)
+
+		imports.WriteString("##++" + member + "\n")
+		listing.WriteString(member + " " + checksumForContent(string(content)) + "\n")
+	}
+
+	return File{
+		Name: directoryImport(absDir, query),
+		Data: imports.String(),
+		Meta: FileMeta{SHA256: checksumForContent(listing.String())},
+	}, nil
+}
+
+// directoryFiles lists the files of the directory with one of the extensions, hidden files and the importing file left out,
+// in the order of the import.
+func (dependency *Dependency) directoryFiles(ctx context.Context, absDir string, options directoryOptions, log *slog.Logger) ([]string, error) {
+	info, err := os.Stat(absDir)
+	if err != nil {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("reading directory import errored with: '%v'", err)}
+	}
+
+	if !info.IsDir() {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("directory import '%s' is not a directory", dependency.Path)}
+	}
+
+	var excludePath string
+	if dependency.excludePath != "" {
+		if excludePath, err = filepath.Abs(dependency.excludePath); err != nil {
+			return nil, err
+		}
+	}
+
+	var files []string
+
+	err = filepath.WalkDir(absDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		if filePath == absDir {
+			return nil
+		}
+
+		hidden := strings.HasPrefix(entry.Name(), ".")
+
+		if entry.IsDir() {
+			if hidden || !options.recursive {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if hidden || !options.hasExtension(entry.Name()) || filepath.Clean(filePath) == excludePath {
+			return nil
+		}
+
+		if info, err := os.Stat(filePath); err != nil || !info.Mode().IsRegular() {
+			return nil //nolint:nilerr
+		}
+
+		// Every file is imported by its path, which has to read back as a plain file import.
+		if strings.Contains(filePath, ";") || isPattern(filePath) {
+			log.Warn("skipping file of directory import, its name cannot be imported", slog.String("file", filePath))
+
+			return nil
+		}
+
+		files = append(files, filePath)
+
+		return nil
+	})
+	if err != nil {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("reading directory import errored with: '%v'", err)}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		left, _ := filepath.Rel(absDir, files[i])
+		right, _ := filepath.Rel(absDir, files[j])
+
+		if options.order == directoryOrderLexical {
+			return filepath.ToSlash(left) < filepath.ToSlash(right)
+		}
+
+		return numericPathLess(filepath.ToSlash(left), filepath.ToSlash(right))
+	})
+
+	return files, nil
+}
+
+func parseDirectoryOptions(query string) (directoryOptions, error) {
+	options := directoryOptions{extensions: []string{"yaml", "yml"}, order: directoryOrderNumeric}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return options, err
+	}
+
+	for key, value := range values {
+		switch key {
+		case "recursive":
+			if options.recursive, err = strconv.ParseBool(value[len(value)-1]); err != nil {
+				return options, fmt.Errorf("recursive has to be true or false, got '%s'", value[len(value)-1])
+			}
+		case "ext":
+			options.extensions = nil
+
+			for _, extensions := range value {
+				for extension := range strings.SplitSeq(extensions, ",") {
+					if extension = strings.TrimPrefix(strings.TrimSpace(extension), "."); extension != "" {
+						options.extensions = append(options.extensions, extension)
+					}
+				}
+			}
+		case "order":
+			options.order = value[len(value)-1]
+			if options.order != directoryOrderNumeric && options.order != directoryOrderLexical {
+				return options, fmt.Errorf("order has to be %s or %s, got '%s'", directoryOrderNumeric, directoryOrderLexical, options.order)
+			}
+		default:
+			return options, fmt.Errorf("unknown option '%s', supported are recursive, ext and order", key)
+		}
+	}
+
+	return options, nil
+}
+
+func (options directoryOptions) hasExtension(name string) bool {
+	extension := strings.TrimPrefix(filepath.Ext(name), ".")
+
+	for _, allowed := range options.extensions {
+		if strings.EqualFold(extension, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// numericPathLess orders slash separated paths segment by segment, where segments starting with a number,
+// 10-db.yaml, are ordered by that number and come before the others, which are ordered lexically.
+func numericPathLess(left, right string) bool {
+	leftSegments, rightSegments := strings.Split(left, "/"), strings.Split(right, "/")
+
+	for index := 0; index < len(leftSegments) && index < len(rightSegments); index++ {
+		if leftSegments[index] != rightSegments[index] {
+			return numericSegmentLess(leftSegments[index], rightSegments[index])
+		}
+	}
+
+	return len(leftSegments) < len(rightSegments)
+}
+
+func numericSegmentLess(left, right string) bool {
+	leftNumber, rightNumber := numericPrefix(left), numericPrefix(right)
+
+	switch {
+	case leftNumber != "" && rightNumber != "" && leftNumber != rightNumber:
+		if len(leftNumber) != len(rightNumber) {
+			return len(leftNumber) < len(rightNumber)
+		}
+
+		return leftNumber < rightNumber
+	case (leftNumber == "") != (rightNumber == ""):
+		return leftNumber != ""
+	default:
+		return left < right
+	}
+}
+
+// numericPrefix returns the leading digits of the name without leading zeros, "0" for zero.
+func numericPrefix(name string) string {
+	end := 0
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+
+	if end == 0 {
+		return ""
+	}
+
+	if number := strings.TrimLeft(name[:end], "0"); number != "" {
+		return number
+	}
+
+	return "0"
+}
@@ -0,0 +1,154 @@
+package yamll_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigDirectoryImport(t *testing.T) {
+	dir := t.TempDir()
+	confDir := filepath.Join(dir, "conf.d")
+
+	files := map[string]string{
+		"10-db.yaml":      "db: 10\n",
+		"2-base.yaml":     "base: 2\n",
+		"app.yml":         "app: true\n",
+		"notes.txt":       "notes: true\n",
+		".hidden.yaml":    "hidden: true\n",
+		"sub/1-sub.yaml":  "sub: 1\n",
+		"sub/config.json": "{}\n",
+	}
+
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(confDir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(confDir, name), []byte(content), 0o600))
+	}
+
+	build := func(t *testing.T, directoryImport string) (map[string]*yamll.YamlData, string) {
+		t.Helper()
+
+		rootFile := filepath.Join(dir, "root.yaml")
+		require.NoError(t, os.WriteFile(rootFile, []byte("##++"+directoryImport+"\nroot: true\n"), 0o600))
+
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+
+		routes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+		require.NoError(t, err)
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+
+		return routes, string(out)
+	}
+
+	requireOrder := func(t *testing.T, out string, keys ...string) {
+		t.Helper()
+
+		for index := 1; index < len(keys); index++ {
+			require.Less(t, strings.Index(out, keys[index-1]), strings.Index(out, keys[index]), out)
+		}
+	}
+
+	t.Run("numeric prefixes order the files", func(t *testing.T) {
+		routes, out := build(t, "conf.d/")
+
+		route := routes[filepath.ToSlash(confDir)+"/"]
+		require.NotNil(t, route)
+		require.Len(t, route.Dependency, 3)
+
+		for _, name := range []string{"2-base.yaml", "10-db.yaml", "app.yml"} {
+			require.Contains(t, routes, filepath.Join(confDir, name))
+		}
+
+		requireOrder(t, out, "base: 2", "db: 10", "app: true")
+		require.NotContains(t, out, "notes")
+		require.NotContains(t, out, "hidden")
+		require.NotContains(t, out, "sub: 1")
+		require.NotContains(t, out, "# Source: "+filepath.ToSlash(confDir)+"/\n")
+	})
+
+	t.Run("lock stays valid when the checkout moves", func(t *testing.T) {
+		checkout := filepath.Join(t.TempDir(), "checkout")
+		require.NoError(t, os.CopyFS(checkout, os.DirFS(dir)))
+		require.NoError(t, os.WriteFile(filepath.Join(checkout, "root.yaml"), []byte("##++conf.d/\nroot: true\n"), 0o600))
+		t.Chdir(checkout)
+
+		cfg := yamll.New(false, "DEBUG", "---", "root.yaml")
+		cfg.SetLogger()
+		cfg.NoCache = true
+
+		lockData, err := cfg.Lock(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(lockData), "source: conf.d/10-db.yaml")
+		require.NoError(t, os.WriteFile(cfg.LockFile, lockData, 0o600))
+
+		moved := filepath.Join(t.TempDir(), "moved")
+		require.NoError(t, os.Rename(checkout, moved))
+		t.Chdir(moved)
+
+		cfg = yamll.New(false, "DEBUG", "---", "root.yaml")
+		cfg.SetLogger()
+		cfg.NoCache = true
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "db: 10")
+
+		require.NoError(t, os.WriteFile(filepath.Join("conf.d", "10-db.yaml"), []byte("db: 11\n"), 0o600))
+
+		_, err = cfg.LockVerify(t.Context())
+		require.ErrorContains(t, err, "changed since the lock file was generated")
+	})
+
+	t.Run("recursive, lexical and filtered by extension", func(t *testing.T) {
+		_, out := build(t, "conf.d/?recursive=true&order=lexical&ext=yaml")
+
+		requireOrder(t, out, "db: 10", "base: 2", "sub: 1")
+		require.NotContains(t, out, "app: true")
+	})
+
+	t.Run("every file is a node of the tree and of the impact", func(t *testing.T) {
+		routes, _ := build(t, "./conf.d/")
+
+		tree, err := yamll.YamlRoutes(routes).RenderDependencyTree(filepath.Join(dir, "root.yaml"), "text", true, true)
+		require.NoError(t, err)
+		require.Contains(t, tree, filepath.Join(confDir, "10-db.yaml"))
+		require.Contains(t, tree, filepath.Join(confDir, "2-base.yaml"))
+
+		cfg := yamll.New(false, "DEBUG", "---", filepath.Join(dir, "root.yaml"))
+		cfg.SetLogger()
+		cfg.NoLock = true
+
+		report, err := cfg.Impact(t.Context(), filepath.Join(confDir, "10-db.yaml"))
+		require.NoError(t, err)
+		require.Contains(t, report.Affected, filepath.ToSlash(confDir)+"/")
+	})
+
+	t.Run("invalid directory imports", func(t *testing.T) {
+		rootFile := filepath.Join(dir, "invalid.yaml")
+
+		for directoryImport, message := range map[string]string{
+			"conf.d/?order=random":     "order has to be numeric or lexical",
+			"conf.d/?recursive=maybe":  "recursive has to be true or false",
+			"conf.d/?depth=1":          "unknown option 'depth'",
+			"missing.d/":               "reading directory import errored with",
+			"conf.d/10-db.yaml/?ext=x": "is not a directory",
+		} {
+			require.NoError(t, os.WriteFile(rootFile, []byte("##++"+directoryImport+"\n"), 0o600))
+
+			cfg := yamll.New(false, "DEBUG", "---", rootFile)
+			cfg.SetLogger()
+			cfg.NoLock = true
+
+			_, err := cfg.Yaml(t.Context())
+			require.ErrorContains(t, err, message, directoryImport)
+		}
+	})
+}
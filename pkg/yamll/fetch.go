@@ -0,0 +1,160 @@
+package yamll
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+// DefaultJobs is the number of dependencies fetched concurrently when Config.Jobs is not set.
+const DefaultJobs = 8
+
+type fetchedDependency struct {
+	source string
+	file   File
+	err    error
+}
+
+// fetchDependencies reads sibling dependencies concurrently with at most Config.Jobs workers.
+// Results are returned in the order of the dependencies, so that the graph built from them stays deterministic.
+// Read errors are carried in the results and only surface once the dependency is actually processed.
+func (cfg *Config) fetchDependencies(
+	ctx context.Context, routes map[string]*YamlData, lockEntries map[string]LockEntry, dependencies []*Dependency,
+) ([]fetchedDependency, error) {
+	fetched := make([]fetchedDependency, len(dependencies))
+	firstIndex := make(map[string]int, len(dependencies))
+	pending := make([]int, 0, len(dependencies))
+
+	var remote bool
+
+	for index, dependency := range dependencies {
+		if dependency == nil {
+			return nil, &errors.YamllError{Message: "dependency path is nil"}
+		}
+
+		fetched[index].source = dependency.Path
+
+		if lockEntries != nil && dependency.Type == TypeGit {
+			if entry, ok := lockedPin(lockEntries, dependency.Path); ok && entry.GitCommit != "" {
+				dependency.Path = pinGitImportToCommit(dependency.Path, entry.GitCommit)
+				dependency.IdentifyType()
+			}
+		}
+
+		if lockEntries != nil && dependency.Type == TypeOCI {
+			if entry, ok := lockedPin(lockEntries, dependency.Path); ok && entry.ManifestDigest != "" {
+				dependency.Path = pinOCIImportToDigest(dependency.Path, entry.ManifestDigest)
+			}
+		}
+
+		if _, ok := routes[dependency.Path]; ok {
+			continue
+		}
+
+		if _, ok := firstIndex[dependency.Path]; ok {
+			continue
+		}
+
+		firstIndex[dependency.Path] = index
+		pending = append(pending, index)
+		remote = remote || isRemoteType(dependency.Type)
+	}
+
+	// Initialise shared state before any worker touches it.
+	cfg.cache()
+	cfg.gitRepositories()
+	cfg.vendoredManifest()
+
+	if remote && !cfg.Offline && !cfg.Vendor {
+		if err := cfg.loadCredentials(); err != nil {
+			return nil, err
+		}
+	}
+
+	fetchStart := time.Now()
+	work := make(chan int)
+
+	var waitGroup sync.WaitGroup
+
+	for range min(cfg.jobs(), len(pending)) {
+		waitGroup.Go(func() {
+			for index := range work {
+				var locked *LockEntry
+				if entry, ok := lockEntries[lockEntryKey(fetched[index].source, "")]; ok {
+					locked = &entry
+				}
+
+				fetched[index].file, fetched[index].err = cfg.readDataWithProfile(ctx, dependencies[index], fetched[index].source, locked)
+				fetched[index].file = unpinPatternSources(fetched[index].file, dependencies[index].Path, fetched[index].source)
+
+				if pin, ok := lockedPin(lockEntries, fetched[index].source); ok && fetched[index].err != nil && pin.ManifestDigest != "" {
+					fetched[index].err = cfg.lockedManifestError(ctx, dependencies[index], fetched[index].source, &pin, fetched[index].err)
+				}
+			}
+		})
+	}
+
+	for _, index := range pending {
+		work <- index
+	}
+
+	close(work)
+	waitGroup.Wait()
+
+	if remote && cfg.Profile && cfg.profile != nil {
+		cfg.profile.addRemoteFetchWall(time.Since(fetchStart))
+	}
+
+	for index, dependency := range dependencies {
+		if first, ok := firstIndex[dependency.Path]; ok && first != index {
+			fetched[index].file, fetched[index].err = fetched[first].file, fetched[first].err
+		}
+	}
+
+	return fetched, nil
+}
+
+// lockedPin returns the lock entry pinning the import to a git commit or OCI manifest, any of the entries of a pattern import.
+func lockedPin(lockEntries map[string]LockEntry, source string) (LockEntry, bool) {
+	if entry, ok := lockEntries[lockEntryKey(source, "")]; ok {
+		return entry, true
+	}
+
+	for _, entry := range lockEntries {
+		if entry.Source == source && isPinnedLockEntry(entry) {
+			return entry, true
+		}
+	}
+
+	return LockEntry{}, false
+}
+
+// unpinPatternSources names the files a pinned pattern import matched after the import as written,
+// git+https://host/org/repo@main?path=configs/a.yaml rather than @<commit>, the way the lock file records them.
+func unpinPatternSources(file File, pinned, source string) File {
+	if pinned == source {
+		return file
+	}
+
+	pinnedBase, _, _ := strings.Cut(pinned, "?")
+	sourceBase, _, _ := strings.Cut(source, "?")
+
+	for index := range file.Source {
+		if query, ok := strings.CutPrefix(file.Source[index].Name, pinnedBase+"?"); ok {
+			file.Source[index].Name = sourceBase + "?" + query
+		}
+	}
+
+	return file
+}
+
+func (cfg *Config) jobs() int {
+	if cfg.Jobs > 0 {
+		return cfg.Jobs
+	}
+
+	return DefaultJobs
+}
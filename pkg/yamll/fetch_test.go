@@ -0,0 +1,116 @@
+package yamll_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+// fetchGate holds every request until as many as the workers are in flight at once, so the imports only
+// resolve when that many are fetched concurrently, and records the most requests seen in flight.
+type fetchGate struct {
+	jobs        int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+	open        chan struct{}
+	openOnce    sync.Once
+}
+
+func newFetchGate(jobs int) *fetchGate {
+	return &fetchGate{jobs: int32(jobs), open: make(chan struct{})}
+}
+
+func (gate *fetchGate) wait(ctx context.Context) {
+	current := gate.inFlight.Add(1)
+	defer gate.inFlight.Add(-1)
+
+	for {
+		seen := gate.maxInFlight.Load()
+		if current <= seen || gate.maxInFlight.CompareAndSwap(seen, current) {
+			break
+		}
+	}
+
+	if current >= gate.jobs {
+		gate.openOnce.Do(func() { close(gate.open) })
+	}
+
+	select {
+	case <-gate.open:
+	case <-ctx.Done():
+	}
+}
+
+func TestConfigResolveDependenciesFetchesSiblingsConcurrently(t *testing.T) {
+	var gate atomic.Pointer[fetchGate]
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gate.Load().wait(r.Context())
+
+		name := strings.Trim(r.URL.Path, "/")
+		_, _ = fmt.Fprintf(w, "%s: &%s\n  from: %s\n", name, name, name)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	rootFile := filepath.Join(dir, "root.yaml")
+
+	imports := make([]string, 0, 6)
+	for index := range 6 {
+		imports = append(imports, fmt.Sprintf("##++%s/lib%d", server.URL, index))
+	}
+
+	require.NoError(t, os.WriteFile(rootFile, []byte(strings.Join(imports, "\n")+"\napp: *lib0\n"), 0o600))
+
+	render := func(jobs int) (yamll.Yaml, map[string]*yamll.YamlData, int32) {
+		gate.Store(newFetchGate(jobs))
+
+		// A pool running fewer workers than jobs never opens the gate, and fails here rather than hanging.
+		ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+		defer cancel()
+
+		newConfig := func() *yamll.Config {
+			cfg := yamll.New(false, "DEBUG", "---", rootFile)
+			cfg.SetLogger()
+			cfg.NoLock = true
+			cfg.NoCache = true
+			cfg.Retries = 0
+			cfg.Jobs = jobs
+
+			return cfg
+		}
+
+		out, err := newConfig().Yaml(ctx)
+		require.NoError(t, err)
+
+		routeCfg := newConfig()
+
+		routes, err := routeCfg.ResolveDependencies(ctx, make(map[string]*yamll.YamlData), routeCfg.Files...)
+		require.NoError(t, err)
+
+		return out, routes, gate.Load().maxInFlight.Load()
+	}
+
+	serialOut, serialRoutes, serialInFlight := render(1)
+	require.Equal(t, int32(1), serialInFlight)
+
+	parallelOut, parallelRoutes, parallelInFlight := render(3)
+	require.LessOrEqual(t, parallelInFlight, int32(3))
+	require.Equal(t, serialOut, parallelOut)
+
+	for file, route := range serialRoutes {
+		require.Contains(t, parallelRoutes, file)
+		require.Equal(t, route.Index, parallelRoutes[file].Index)
+	}
+}
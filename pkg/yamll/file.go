@@ -1,6 +1,7 @@
 package yamll
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -12,7 +13,11 @@ import (
 )
 
 // File reads the data from the File import.
-func (dependency *Dependency) File(_ *slog.Logger) (File, error) {
+func (dependency *Dependency) File(ctx context.Context, _ *slog.Logger) (File, error) {
+	if err := ctx.Err(); err != nil {
+		return File{}, err
+	}
+
 	absYamlFilePath, err := filepath.Abs(dependency.Path)
 	if err != nil {
 		return File{}, err
@@ -1,13 +1,13 @@
 package yamll
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/nikhilsbhat/yamll/pkg/errors"
 )
@@ -20,12 +20,15 @@ type File struct {
 }
 
 type FileMeta struct {
-	SHA256    string
-	GitCommit string
+	SHA256         string
+	GitCommit      string
+	ManifestDigest string
+	Version        string
+	ArchiveSHA256  string
 }
 
 // FilePattern reads the data from the Files matching the pattern import.
-func (dependency *Dependency) FilePattern(log *slog.Logger) (File, error) {
+func (dependency *Dependency) FilePattern(ctx context.Context, log *slog.Logger) (File, error) {
 	log.Debug("Since the path is a pattern, the filenames matching the pattern will be hidden from the tree, import, and build commands. " +
 		"Instead, the data from all files matching the pattern will be consolidated under the file pattern.")
 
@@ -34,30 +37,38 @@ func (dependency *Dependency) FilePattern(log *slog.Logger) (File, error) {
 		return File{}, err
 	}
 
-	var (
-		sources       []File
-		yamlFilesData string
-	)
-
 	log.Debug("the files matching the pattern are", slog.Any("pattern", dependency.Path), slog.Any("files-matched", filesMatching))
 
+	sources := make([]File, 0, len(filesMatching))
+
 	for _, fileMatching := range filesMatching {
+		if err = ctx.Err(); err != nil {
+			return File{}, err
+		}
+
 		yamlFileData, err := os.ReadFile(fileMatching)
 		if err != nil {
 			return File{}, &errors.YamllError{Message: fmt.Sprintf("reading YAML dependency errored with: '%v'", err)}
 		}
 
-		sum := sha256.Sum256(yamlFileData)
-
-		yamlFilesData = yamlFilesData + "\n" + string(yamlFileData)
-		sources = append(sources, File{Name: fileMatching, Data: string(yamlFileData), Meta: FileMeta{SHA256: hex.EncodeToString(sum[:])}})
+		sources = append(sources, File{Name: fileMatching, Data: string(yamlFileData), Meta: FileMeta{SHA256: checksumForContent(string(yamlFileData))}})
 	}
 
+	return patternFile(absFilePattern, sources), nil
+}
+
+// patternFile folds the files matched by a pattern import into one File named after the pattern.
+// Their data is concatenated in name order, and each is kept as a Source of the pattern.
+func patternFile(name string, sources []File) File {
 	sort.SliceStable(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
 
-	sum := sha256.Sum256([]byte(yamlFilesData))
+	var data strings.Builder
 
-	return File{Name: absFilePattern, Data: yamlFilesData, Source: sources, Meta: FileMeta{SHA256: hex.EncodeToString(sum[:])}}, nil
+	for _, source := range sources {
+		data.WriteString("\n" + source.Data)
+	}
+
+	return File{Name: name, Data: data.String(), Source: sources, Meta: FileMeta{SHA256: checksumForContent(data.String())}}
 }
 
 func (dependency *Dependency) FilesFromPattern() ([]File, error) {
@@ -75,15 +86,15 @@ func (dependency *Dependency) FilesFromPattern() ([]File, error) {
 	return files, nil
 }
 
+// filesMatchingPattern lists the files matching the pattern import in path order,
+// leaving out the importing file and the files matching an exclusion of it.
 func (dependency *Dependency) filesMatchingPattern() (string, []string, error) {
-	absPatternPath, err := filepath.Abs(filepath.Dir(dependency.Path))
+	absFilePattern, err := filepath.Abs(dependency.Path)
 	if err != nil {
 		return "", nil, err
 	}
 
-	absFilePattern := filepath.Join(absPatternPath, filepath.Base(dependency.Path))
-
-	filesMatching, err := filepath.Glob(absFilePattern)
+	filesMatching, err := globFiles(absFilePattern)
 	if err != nil {
 		return "", nil, &errors.YamllError{Message: fmt.Sprintf("error matching pattern: '%v'", err)}
 	}
@@ -109,8 +120,26 @@ func (dependency *Dependency) filesMatchingPattern() (string, []string, error) {
 			continue
 		}
 
-		filteredFiles = append(filteredFiles, fileMatching)
+		excluded, err := dependency.excluded(fileMatching)
+		if err != nil {
+			return "", nil, &errors.YamllError{Message: fmt.Sprintf("error matching exclusion pattern: '%v'", err)}
+		}
+
+		if !excluded {
+			filteredFiles = append(filteredFiles, fileMatching)
+		}
 	}
 
 	return absFilePattern, filteredFiles, nil
 }
+
+func (dependency *Dependency) excluded(file string) (bool, error) {
+	for _, exclusion := range dependency.excludePatterns {
+		matched, err := matchGlob(filepath.ToSlash(exclusion), filepath.ToSlash(file))
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+
+	return false, nil
+}
@@ -4,65 +4,194 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	stdErrors "errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
-	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
-	"github.com/google/uuid"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/nikhilsbhat/yamll/pkg/errors"
-	"golang.org/x/crypto/ssh"
 )
 
-type gitMeta struct {
-	gitBaseURL    string
-	referenceName string
-	path          string
-	ssh           bool
-}
-
 // Git reads the data from the Git import.
-func (dependency *Dependency) Git(log *slog.Logger) (File, error) {
+// Only the requested ref is fetched, shallow and without a checkout, into an in-memory repository
+// that is shared by every import of the same repository within a run. The file is read straight from the object store.
+func (dependency *Dependency) Git(ctx context.Context, log *slog.Logger) (File, error) {
 	gitMetaData, err := dependency.getGitMetaData()
 	if err != nil {
 		return File{}, err
 	}
 
+	pattern := isPattern(gitMetaData.path)
+
+	cacheKey := gitCacheKey(gitMetaData.gitBaseURL, gitMetaData.referenceName, gitMetaData.path)
+	if isGitCommitHash(gitMetaData.referenceName) && !pattern {
+		if entry, content, ok := dependency.cache.Get(cacheKey); ok {
+			log.Debug("serving git import from cache", slog.String("repo", gitMetaData.gitBaseURL), slog.String("commit", entry.GitCommit))
+
+			return File{Name: entry.Name, Data: content, Meta: FileMeta{SHA256: entry.SHA256, GitCommit: entry.GitCommit}}, nil
+		}
+	}
+
+	repositories := dependency.gitRepos
+	if repositories == nil {
+		repositories = newGitRepositories()
+	}
+
+	repository := repositories.get(gitMetaData.gitBaseURL)
+
+	var remoteOptions gitRemoteOptions
+
+	if gitMetaData.scheme != "file" {
+		if remoteOptions, err = dependency.gitRemoteOptions(gitMetaData, log); err != nil {
+			return File{}, err
+		}
+	}
+
+	// A constraint, @^1.2, resolves to the tag of the highest version it allows.
+	var version string
+
+	if isSemverConstraint(gitMetaData.referenceName) {
+		if version, err = repository.resolveConstraint(ctx, gitMetaData, remoteOptions); err != nil {
+			return File{}, err
+		}
+
+		log.Debug("resolved version constraint of git import", slog.String("constraint", gitMetaData.referenceName), slog.String("tag", version))
+
+		gitMetaData.referenceName = "refs/tags/" + version
+	}
+
+	var hash plumbing.Hash
+
+	if gitMetaData.scheme == "file" {
+		hash, err = repository.resolveLocal(gitMetaData.repoPath, gitMetaData.referenceName)
+	} else {
+		hash, err = repository.resolve(ctx, gitMetaData.referenceName, remoteOptions, log)
+	}
+
+	if err != nil {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf(
+			"resolving ref '%s' of git repository '%s' errored with '%v'", gitMetaData.referenceName, gitMetaData.gitBaseURL, err,
+		)}
+	}
+
+	if pattern {
+		file, err := dependency.gitPattern(repository, hash, gitMetaData)
+		file.Meta.Version = version
+
+		return file, err
+	}
+
+	gitFileContent, err := repository.readFile(hash, gitMetaData.path)
+	if err != nil {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf("reading content from file of git errored with '%v'", err)}
+	}
+
+	sum := sha256.Sum256([]byte(gitFileContent))
+	commit := hash.String()
+	name := pinGitImportToCommit(dependency.Path, commit)
+
+	if cacheErr := dependency.cache.Put(CacheEntry{
+		Key:       gitCacheKey(gitMetaData.gitBaseURL, commit, gitMetaData.path),
+		Type:      TypeGit,
+		Name:      name,
+		GitCommit: commit,
+	}, gitFileContent); cacheErr != nil {
+		log.Warn("caching git import failed", slog.String("repo", gitMetaData.gitBaseURL), slog.Any("err", cacheErr))
+	}
+
+	return File{
+		Name: name,
+		Data: gitFileContent,
+		Meta: FileMeta{
+			SHA256:    hex.EncodeToString(sum[:]),
+			GitCommit: commit,
+			Version:   version,
+		},
+	}, nil
+}
+
+// gitPattern reads every file of the commit matching the ?path= pattern, each kept as a Source of the import
+// named after the import of that single file, so its lock entry does not change when the ref moves.
+func (dependency *Dependency) gitPattern(repository *gitRepository, hash plumbing.Hash, gitMetaData *gitMeta) (File, error) {
+	filePaths, err := repository.matchFiles(hash, gitMetaData.path)
+	if err != nil {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf("matching files of git errored with '%v'", err)}
+	}
+
+	if len(filePaths) == 0 {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf("pattern matched no files: '%s'", dependency.Path)}
+	}
+
+	commit := hash.String()
+	importBase, _, _ := strings.Cut(dependency.Path, "?")
+	sources := make([]File, 0, len(filePaths))
+
+	for _, filePath := range filePaths {
+		content, err := repository.readFile(hash, filePath)
+		if err != nil {
+			return File{}, &errors.YamllError{Message: fmt.Sprintf("reading content from file of git errored with '%v'", err)}
+		}
+
+		sources = append(sources, File{
+			Name: importBase + "?path=" + filePath,
+			Data: content,
+			Meta: FileMeta{SHA256: checksumForContent(content), GitCommit: commit},
+		})
+	}
+
+	file := patternFile(pinGitImportToCommit(dependency.Path, commit), sources)
+	file.Meta.GitCommit = commit
+
+	return file, nil
+}
+
+// gitRemoteOptions holds the transport settings shared by list and fetch operations against a remote.
+type gitRemoteOptions struct {
+	auth     transport.AuthMethod
+	tls      tlsSettings
+	progress io.Writer
+	retry    retryPolicy
+	log      *slog.Logger
+}
+
+func (dependency *Dependency) gitRemoteOptions(gitMetaData *gitMeta, log *slog.Logger) (gitRemoteOptions, error) {
 	var depAuth Auth
 	if dependency.Auth != nil {
 		depAuth = *dependency.Auth
 	}
 
-	cloneOptions := &git.CloneOptions{
-		URL:      gitMetaData.gitBaseURL,
-		Progress: gitCloneProgressWriter(log),
-	}
+	options := gitRemoteOptions{progress: gitCloneProgressWriter(log), retry: dependency.retry, log: log}
 
-	if len(depAuth.CaContent) != 0 {
-		cloneOptions.CABundle = []byte(depAuth.CaContent)
+	tlsSettings, err := depAuth.tlsSettings()
+	if err != nil {
+		return options, err
 	}
 
+	options.tls = tlsSettings
+
 	switch gitMetaData.ssh {
 	case true:
 		log.Debug("the git import is of type ssh, so setting ssh based auth")
 
-		sshKEY, err := os.ReadFile(depAuth.SSHKey)
+		auth, err := dependency.sshAuth(gitMetaData, depAuth, log)
 		if err != nil {
-			return File{}, &errors.YamllError{Message: fmt.Sprintf("reading ssh key '%s' errored with %v", depAuth.SSHKey, err)}
+			return options, err
 		}
 
-		signer, err := ssh.ParsePrivateKey(sshKEY)
-		if err != nil {
-			return File{}, err
-		}
-
-		cloneOptions.Auth = &gitssh.PublicKeys{User: "git", Signer: signer}
+		options.auth = auth
 
 	case false:
 		log.Debug("the git import is of type https, so setting http based auth")
@@ -76,130 +205,374 @@ func (dependency *Dependency) Git(log *slog.Logger) (File, error) {
 			auth.Password = depAuth.BarerToken
 		}
 
-		cloneOptions.Auth = auth
+		if auth.Username != "" || auth.Password != "" {
+			options.auth = auth
+		}
 	}
 
-	tempDir := filepath.Join(os.TempDir(), "yamll_git"+uuid.New().String())
-	if err = os.MkdirAll(tempDir, defaultDirPermissions); err != nil {
-		return File{}, &errors.YamllError{Message: "failed to crete temp directory for cloning git material"}
+	return options, nil
+}
+
+// gitRepositories shares in-memory repositories between all imports of the same repository URL.
+type gitRepositories struct {
+	mutex        sync.Mutex
+	repositories map[string]*gitRepository
+}
+
+// gitRepository is a bare, in-memory repository holding only the refs fetched so far.
+// Fetches write to the object storage other workers read files from, so reads hold the mutex too.
+type gitRepository struct {
+	mutex      sync.RWMutex
+	url        string
+	repo       *git.Repository
+	remoteRefs []*plumbing.Reference
+	// local is set for repositories opened on disk, whose storage loads its pack indexes on first read.
+	local bool
+}
+
+// gitRepositories returns the repositories shared by every git import resolved with this config.
+func (cfg *Config) gitRepositories() *gitRepositories {
+	if cfg.gitRepos == nil {
+		cfg.gitRepos = newGitRepositories()
 	}
 
-	log.Debug("cloning git repo", slog.String("repo", gitMetaData.gitBaseURL), slog.String("dir", tempDir))
+	return cfg.gitRepos
+}
 
-	defer func(path string) {
-		if err = os.RemoveAll(path); err != nil {
-			log.Error(err.Error())
+func newGitRepositories() *gitRepositories {
+	return &gitRepositories{repositories: make(map[string]*gitRepository)}
+}
+
+func (repositories *gitRepositories) get(url string) *gitRepository {
+	repositories.mutex.Lock()
+	defer repositories.mutex.Unlock()
+
+	if repository, ok := repositories.repositories[url]; ok {
+		return repository
+	}
+
+	repository := &gitRepository{url: url}
+	repositories.repositories[url] = repository
+
+	return repository
+}
+
+// resolve makes sure the commit the ref points to is present locally and returns its hash.
+func (repository *gitRepository) resolve(ctx context.Context, ref string, options gitRemoteOptions, log *slog.Logger) (plumbing.Hash, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if err := repository.init(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if isGitCommitHash(ref) {
+		hash := plumbing.NewHash(ref)
+		if _, err := repository.repo.CommitObject(hash); err == nil {
+			return hash, nil
+		}
+
+		log.Debug("fetching single commit from git repo", slog.String("repo", repository.url), slog.String("commit", ref))
+
+		if err := repository.fetch(ctx, options, 1, gitconfig.RefSpec(ref+":refs/yamll/"+ref)); err == nil {
+			return hash, nil
 		}
-	}(tempDir)
 
-	repo, err := git.PlainClone(tempDir, false, cloneOptions)
+		// Not every server lets clients fetch arbitrary commits, fall back to the full history.
+		return repository.fetchAllAndResolve(ctx, ref, options, log)
+	}
+
+	remoteRef, err := repository.findRemoteRef(ctx, ref, options)
 	if err != nil {
-		return File{}, err
+		return plumbing.ZeroHash, err
 	}
 
-	if err = checkoutRevision(repo, gitMetaData.referenceName); err != nil {
-		return File{}, err
+	if remoteRef == nil {
+		return repository.fetchAllAndResolve(ctx, ref, options, log)
 	}
 
-	yamlFilePath := filepath.Join(tempDir, gitMetaData.path)
+	name := remoteRef.Name().String()
 
-	gitFileContent, err := os.ReadFile(yamlFilePath)
-	if err != nil {
-		return File{}, &errors.YamllError{Message: fmt.Sprintf("reading content from file of git errored with '%v'", err)}
+	if local, err := repository.repo.Reference(remoteRef.Name(), true); err != nil || local.Hash() != remoteRef.Hash() {
+		log.Debug("shallow fetching ref from git repo", slog.String("repo", repository.url), slog.String("ref", name))
+
+		if err = repository.fetch(ctx, options, 1, gitconfig.RefSpec("+"+name+":"+name)); err != nil {
+			return plumbing.ZeroHash, err
+		}
 	}
 
-	head, err := repo.Head()
+	hash, err := repository.repo.ResolveRevision(plumbing.Revision(name))
 	if err != nil {
-		return File{}, err
+		return plumbing.ZeroHash, err
 	}
 
-	sum := sha256.Sum256(gitFileContent)
+	return *hash, nil
+}
 
-	return File{
-		Name: yamlFilePath,
-		Data: string(gitFileContent),
-		Meta: FileMeta{
-			SHA256:    hex.EncodeToString(sum[:]),
-			GitCommit: head.Hash().String(),
-		},
-	}, nil
+// resolveLocal opens a repository on disk, from any directory within it, and returns the commit the ref points to.
+// Every ref is already present, so nothing is fetched and no git binary or server is involved.
+func (repository *gitRepository) resolveLocal(repoPath, ref string) (plumbing.Hash, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if err := repository.openLocal(repoPath); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	hash, err := repository.repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return *hash, nil
 }
 
-func gitCloneProgressWriter(log *slog.Logger) io.Writer {
-	if log != nil && log.Enabled(context.Background(), slog.LevelDebug) {
-		return os.Stdout
+func (repository *gitRepository) openLocal(repoPath string) error {
+	if repository.repo != nil {
+		return nil
+	}
+
+	repo, err := git.PlainOpenWithOptions(filepath.FromSlash(repoPath), &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return &errors.YamllError{Message: fmt.Sprintf("opening local git repository '%s' errored with: '%v'", repoPath, err)}
 	}
 
+	repository.repo = repo
+	repository.local = true
+
 	return nil
 }
 
-func (dependency *Dependency) getGitMetaData() (*gitMeta, error) {
-	dependencyPath := strings.TrimPrefix(dependency.Path, TypeGit)
+// readLock locks the object storage for reading, exclusively for repositories on disk, and returns the unlock.
+func (repository *gitRepository) readLock() func() {
+	if repository.local {
+		repository.mutex.Lock()
 
-	isSSH := strings.HasPrefix(dependencyPath, "ssh://")
+		return repository.mutex.Unlock
+	}
 
-	var gitBaseURL, remainingPath string
+	repository.mutex.RLock()
 
-	if isSSH {
-		afterScheme := strings.TrimPrefix(dependencyPath, "ssh://")
-		userHost, sshPath, ok := strings.Cut(afterScheme, "@") //nolint:varnamelen
+	return repository.mutex.RUnlock
+}
+
+// resolveConstraint returns the tag of the highest version satisfying the constraint the import references,
+// out of the tags of the repository on disk or advertised by the remote.
+func (repository *gitRepository) resolveConstraint(ctx context.Context, gitMetaData *gitMeta, options gitRemoteOptions) (string, error) {
+	tags, err := repository.tags(ctx, gitMetaData, options)
+	if err != nil {
+		return "", &errors.YamllError{Message: fmt.Sprintf("listing tags of git repository '%s' errored with '%v'", gitMetaData.gitBaseURL, err)}
+	}
+
+	tag, err := highestMatchingTag(gitMetaData.referenceName, tags)
+	if err != nil {
+		return "", &errors.YamllError{Message: fmt.Sprintf("resolving git repository '%s' errored with: %v", gitMetaData.gitBaseURL, err)}
+	}
+
+	return tag, nil
+}
 
-		if !ok || userHost == "" || sshPath == "" {
-			return nil, &errors.YamllError{Message: fmt.Sprintf("unable to split git url '%s'", dependencyPath)}
+// tags lists the names of the tags of the repository, without their refs/tags/ prefix.
+func (repository *gitRepository) tags(ctx context.Context, gitMetaData *gitMeta, options gitRemoteOptions) ([]string, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	var tags []string
+
+	if gitMetaData.scheme == "file" {
+		if err := repository.openLocal(gitMetaData.repoPath); err != nil {
+			return nil, err
 		}
 
-		refHost, refPath, ok := strings.Cut(sshPath, "@")
-		if !ok || refHost == "" || refPath == "" {
-			return nil, &errors.YamllError{Message: fmt.Sprintf("unable to split git url '%s'", dependencyPath)}
+		refs, err := repository.repo.Tags()
+		if err != nil {
+			return nil, err
 		}
 
-		gitBaseURL = "git@" + userHost
+		err = refs.ForEach(func(ref *plumbing.Reference) error {
+			tags = append(tags, ref.Name().Short())
 
-		remainingPath = "https://" + userHost + "@" + refPath
-	} else {
-		baseURL, _, ok := strings.Cut(dependencyPath, "@") //nolint:varnamelen
-		if !ok || baseURL == "" {
-			return nil, &errors.YamllError{Message: fmt.Sprintf("unable to parse git url '%s'", dependencyPath)}
+			return nil
+		})
+
+		return tags, err
+	}
+
+	if err := repository.init(); err != nil {
+		return nil, err
+	}
+
+	if err := repository.listRemote(ctx, options); err != nil {
+		return nil, err
+	}
+
+	for _, remoteRef := range repository.remoteRefs {
+		if remoteRef.Name().IsTag() && !strings.HasSuffix(remoteRef.Name().String(), "^{}") {
+			tags = append(tags, remoteRef.Name().Short())
 		}
+	}
 
-		gitBaseURL = baseURL
+	return tags, nil
+}
 
-		remainingPath = dependencyPath
+func (repository *gitRepository) init() error {
+	if repository.repo != nil {
+		return nil
 	}
 
-	refPath, query, ok := strings.Cut(remainingPath, "?") //nolint:varnamelen
-	if !ok || query == "" {
-		return nil, &errors.YamllError{Message: fmt.Sprintf("unable to parse path from '%s'", remainingPath)}
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{repository.url}}); err != nil {
+		return err
 	}
 
-	_, referenceName, ok := strings.Cut(refPath, "@") //nolint:varnamelen
-	if !ok || referenceName == "" {
-		return nil, &errors.YamllError{Message: fmt.Sprintf("unable to parse ref from '%s'", remainingPath)}
+	repository.repo = repo
+
+	return nil
+}
+
+// findRemoteRef looks the ref up in the remote's advertisement, which is listed once per repository.
+func (repository *gitRepository) findRemoteRef(ctx context.Context, ref string, options gitRemoteOptions) (*plumbing.Reference, error) {
+	if err := repository.listRemote(ctx, options); err != nil {
+		return nil, err
 	}
 
-	key, pathValue, ok := strings.Cut(query, "=") //nolint:varnamelen
-	if !ok || key == "" || pathValue == "" {
-		return nil, &errors.YamllError{Message: fmt.Sprintf("unable to parse path from '%s'", remainingPath)}
+	candidates := []string{ref, "refs/heads/" + ref, "refs/tags/" + ref}
+
+	for _, candidate := range candidates {
+		for _, remoteRef := range repository.remoteRefs {
+			if remoteRef.Name().String() == candidate && remoteRef.Type() == plumbing.HashReference {
+				return remoteRef, nil
+			}
+		}
 	}
 
-	return &gitMeta{
-		gitBaseURL:    gitBaseURL,
-		referenceName: referenceName,
-		path:          pathValue,
-		ssh:           isSSH,
-	}, nil
+	return nil, nil //nolint:nilnil
 }
 
-func checkoutRevision(repo *git.Repository, referenceName string) error {
-	worktree, err := repo.Worktree()
+// listRemote lists the refs the remote advertises, unless they were listed already.
+func (repository *gitRepository) listRemote(ctx context.Context, options gitRemoteOptions) error {
+	if repository.remoteRefs != nil {
+		return nil
+	}
+
+	remote, err := repository.repo.Remote(git.DefaultRemoteName)
 	if err != nil {
 		return err
 	}
 
-	hash, err := repo.ResolveRevision(plumbing.Revision(referenceName))
+	return options.retry.do(ctx, options.log, repository.url, func(ctx context.Context) error {
+		remoteRefs, err := remote.ListContext(ctx, &git.ListOptions{
+			Auth:            options.auth,
+			CABundle:        options.tls.caBundle,
+			ClientCert:      options.tls.clientCert,
+			ClientKey:       options.tls.clientKey,
+			InsecureSkipTLS: options.tls.insecureSkipVerify,
+		})
+		repository.remoteRefs = remoteRefs
+
+		return err
+	})
+}
+
+func (repository *gitRepository) fetchAllAndResolve(ctx context.Context, ref string, options gitRemoteOptions, log *slog.Logger) (plumbing.Hash, error) {
+	log.Debug("fetching full history of git repo", slog.String("repo", repository.url), slog.String("ref", ref))
+
+	if err := repository.fetch(ctx, options, 0, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	hash, err := repository.repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return *hash, nil
+}
+
+func (repository *gitRepository) fetch(ctx context.Context, options gitRemoteOptions, depth int, refSpecs ...gitconfig.RefSpec) error {
+	return options.retry.do(ctx, options.log, repository.url, func(ctx context.Context) error {
+		return repository.fetchOnce(ctx, options, depth, refSpecs...)
+	})
+}
+
+func (repository *gitRepository) fetchOnce(ctx context.Context, options gitRemoteOptions, depth int, refSpecs ...gitconfig.RefSpec) error {
+	err := repository.repo.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs:        refSpecs,
+		Depth:           depth,
+		Auth:            options.auth,
+		CABundle:        options.tls.caBundle,
+		ClientCert:      options.tls.clientCert,
+		ClientKey:       options.tls.clientKey,
+		InsecureSkipTLS: options.tls.insecureSkipVerify,
+		Progress:        options.progress,
+		Tags:            git.NoTags,
+	})
+	if err != nil && !stdErrors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *gitRepository) readFile(hash plumbing.Hash, filePath string) (string, error) {
+	defer repository.readLock()()
+
+	commit, err := repository.repo.CommitObject(hash)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := commit.File(strings.TrimPrefix(path.Clean("/"+filePath), "/"))
 	if err != nil {
+		return "", fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return file.Contents()
+}
+
+// matchFiles lists the files of the commit matching the pattern, in name order.
+func (repository *gitRepository) matchFiles(hash plumbing.Hash, pattern string) ([]string, error) {
+	pattern = strings.TrimPrefix(path.Clean("/"+pattern), "/")
+	if err := validateGlob(pattern); err != nil {
+		return nil, err
+	}
+
+	defer repository.readLock()()
+
+	commit, err := repository.repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := commit.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+
+	err = files.ForEach(func(file *object.File) error {
+		matched, err := matchGlob(pattern, file.Name)
+		if matched {
+			matches = append(matches, file.Name)
+		}
+
 		return err
+	})
+
+	sort.Strings(matches)
+
+	return matches, err
+}
+
+func gitCloneProgressWriter(log *slog.Logger) io.Writer {
+	if log != nil && log.Enabled(context.Background(), slog.LevelDebug) {
+		return os.Stdout
 	}
 
-	return worktree.Checkout(&git.CheckoutOptions{Hash: *hash})
+	return nil
 }
@@ -0,0 +1,697 @@
+package yamll_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+// newGitRepo creates a local repository with the given files committed on main, tagged v1.0.0, and returns its path and commit.
+func newGitRepo(t *testing.T, files map[string]string) (string, string) {
+	t.Helper()
+
+	repoDir := t.TempDir()
+
+	repo, err := git.PlainInitWithOptions(repoDir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	require.NoError(t, err)
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(repoDir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0o600))
+
+		_, err = worktree.Add(name)
+		require.NoError(t, err)
+	}
+
+	commit, err := worktree.Commit("add configs", &git.CommitOptions{
+		Author: &object.Signature{Name: "yamll", Email: "yamll@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	_, err = repo.CreateTag("v1.0.0", commit, nil)
+	require.NoError(t, err)
+
+	return repoDir, commit.String()
+}
+
+// startGitDaemon serves the repositories in baseDir over the git protocol and returns the git+git:// import url of the server.
+func startGitDaemon(t *testing.T, baseDir string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	daemon := exec.CommandContext(ctx, "git", "daemon", "--export-all", "--reuseaddr", "--listen=127.0.0.1",
+		"--port="+strconv.Itoa(port), "--base-path="+baseDir, baseDir)
+
+	if err = daemon.Start(); err != nil {
+		cancel()
+		t.Skipf("git daemon could not be started: %v", err)
+	}
+
+	t.Cleanup(func() {
+		cancel()
+		_ = daemon.Wait()
+	})
+
+	address := fmt.Sprintf("127.0.0.1:%d", port)
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			return false
+		}
+
+		_ = conn.Close()
+
+		return true
+	}, 10*time.Second, 20*time.Millisecond, "git daemon did not start listening")
+
+	return "git+git://" + address
+}
+
+func TestDependencyGitSharesRepositoryAcrossImports(t *testing.T) {
+	repoDir, commit := newGitRepo(t, map[string]string{
+		"configs/base.yaml":  "base: &base\n  replicas: 1\n",
+		"configs/extra.yaml": "extra: &extra\n  debug: true\n",
+	})
+
+	// Served over the git protocol, so refs are shallow fetched into the shared in-memory repository.
+	dir := t.TempDir()
+	rootFile := filepath.Join(dir, "root.yaml")
+	repoURL := startGitDaemon(t, filepath.Dir(repoDir)) + "/" + filepath.Base(repoDir)
+
+	require.NoError(t, os.WriteFile(rootFile, []byte(
+		"##++"+repoURL+"@main?path=configs/base.yaml\n"+
+			"##++"+repoURL+"@v1.0.0?path=configs/extra.yaml\n"+
+			"app:\n  base: *base\n  extra: *extra\n",
+	), 0o600))
+
+	cfg := yamll.New(false, "DEBUG", "---", rootFile)
+	cfg.SetLogger()
+	cfg.NoLock = true
+	cfg.NoCache = true
+	cfg.Retries = 0
+
+	routes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+	require.NoError(t, err)
+
+	for _, source := range []string{repoURL + "@main?path=configs/base.yaml", repoURL + "@v1.0.0?path=configs/extra.yaml"} {
+		route, ok := routes[source]
+		require.True(t, ok, source)
+		require.Len(t, route.SourceFile, 1)
+		require.Equal(t, commit, route.SourceFile[0].Meta.GitCommit)
+		require.Equal(t, yamll.PinGitImportToCommitForTest(source, commit), route.SourceFile[0].Name)
+	}
+
+	out, err := cfg.Yaml(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, string(out), "replicas: 1")
+	require.Contains(t, string(out), "debug: true")
+
+	readPinned := func(t *testing.T) {
+		t.Helper()
+
+		pinnedFile := filepath.Join(t.TempDir(), "pinned.yaml")
+		require.NoError(t, os.WriteFile(pinnedFile, []byte("##++"+repoURL+"@"+commit+"?path=configs/base.yaml\napp: *base\n"), 0o600))
+
+		pinnedCfg := yamll.New(false, "DEBUG", "---", pinnedFile)
+		pinnedCfg.SetLogger()
+		pinnedCfg.NoLock = true
+		pinnedCfg.NoCache = true
+		pinnedCfg.Retries = 0
+
+		out, err := pinnedCfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "replicas: 1")
+	}
+
+	// git daemon refuses wants of commits no ref advertises, so the full history is fetched instead.
+	t.Run("pinned commit", readPinned)
+
+	t.Run("pinned commit the server serves", func(t *testing.T) {
+		require.NoError(t, exec.Command("git", "-C", repoDir, "config", "uploadpack.allowAnySHA1InWant", "true").Run())
+
+		readPinned(t)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		missingFile := filepath.Join(dir, "missing.yaml")
+		require.NoError(t, os.WriteFile(missingFile, []byte("##++"+repoURL+"@main?path=configs/missing.yaml\n"), 0o600))
+
+		missingCfg := yamll.New(false, "DEBUG", "---", missingFile)
+		missingCfg.SetLogger()
+		missingCfg.NoLock = true
+		missingCfg.NoCache = true
+		missingCfg.Retries = 0
+
+		_, err := missingCfg.Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "configs/missing.yaml")
+	})
+}
+
+func TestDependencyGitConcurrentRefsOverGitProtocol(t *testing.T) {
+	repoDir, _ := newGitRepo(t, map[string]string{"configs/v0.yaml": "v0: 0\n"})
+
+	const releases = 8
+
+	commits := make(map[string]string, releases)
+	for release := 1; release <= releases; release++ {
+		tag := fmt.Sprintf("v1.%d.0", release)
+		commits[tag] = tagGitRelease(t, repoDir, fmt.Sprintf("configs/v%d.yaml", release), fmt.Sprintf("v%d: %d\n", release, release), tag)
+	}
+
+	repository := startGitDaemon(t, filepath.Dir(repoDir)) + "/" + filepath.Base(repoDir)
+
+	// Every worker fetches another tag into the shared repository while the others read files of theirs.
+	var root strings.Builder
+	for tag := range commits {
+		root.WriteString("##++" + repository + "@" + tag + "?path=configs/*.yaml\n")
+	}
+
+	rootFile := filepath.Join(t.TempDir(), "root.yaml")
+	require.NoError(t, os.WriteFile(rootFile, []byte(root.String()), 0o600))
+
+	cfg := yamll.New(false, "DEBUG", "---", rootFile)
+	cfg.SetLogger()
+	cfg.NoLock = true
+	cfg.NoCache = true
+	cfg.Retries = 0
+	cfg.Jobs = releases
+
+	routes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+	require.NoError(t, err)
+
+	for tag, commit := range commits {
+		source := repository + "@" + tag + "?path=configs/*.yaml"
+
+		route, ok := routes[source]
+		require.True(t, ok, source)
+
+		release, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(tag, "v1."), ".0"))
+		require.NoError(t, err)
+		require.Len(t, route.SourceFile, release+1, source)
+
+		for _, sourceFile := range route.SourceFile {
+			require.Equal(t, commit, sourceFile.Meta.GitCommit)
+		}
+	}
+}
+
+func TestDependencyGitResolvesRelativeImportsInsideRepository(t *testing.T) {
+	repoDir, commit := newGitRepo(t, map[string]string{
+		"libs/base.yaml":       "##++common.yaml\n##++//shared/defaults.yaml\nbase: &base\n  <<: *common\n  defaults: *defaults\n",
+		"libs/common.yaml":     "common: &common\n  origin: repository\n",
+		"shared/defaults.yaml": "defaults: &defaults\n  replicas: 3\n",
+	})
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	// A local file with the same name must never be picked up by an import inside the repository.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "common.yaml"), []byte("common: &common\n  origin: local\n"), 0o600))
+
+	repoURL := "git+file://" + filepath.ToSlash(repoDir)
+	require.NoError(t, os.WriteFile("root.yaml", []byte("##++"+repoURL+"@main?path=libs/base.yaml\napp: *base\n"), 0o600))
+
+	cfg := yamll.New(false, "DEBUG", "---", "root.yaml")
+	cfg.SetLogger()
+	cfg.NoCache = true
+	cfg.LockFile = "yamll.lock"
+
+	routes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+	require.NoError(t, err)
+	require.Contains(t, routes, repoURL+"@"+commit+"?path=libs/common.yaml")
+	require.Contains(t, routes, repoURL+"@"+commit+"?path=shared/defaults.yaml")
+
+	out, err := cfg.Yaml(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, string(out), "origin: repository")
+	require.NotContains(t, string(out), "origin: local")
+	require.Contains(t, string(out), "replicas: 3")
+
+	lockData, err := cfg.Lock(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, string(lockData), "source: "+repoURL+"@"+commit+"?path=libs/common.yaml")
+	require.Contains(t, string(lockData), "git_commit: "+commit)
+
+	t.Run("vendored", func(t *testing.T) {
+		vendorCfg := yamll.New(false, "DEBUG", "---", "root.yaml")
+		vendorCfg.SetLogger()
+		vendorCfg.NoLock = true
+		vendorCfg.NoCache = true
+
+		report, err := vendorCfg.VendorDependencies(t.Context())
+		require.NoError(t, err)
+		require.Len(t, report.Entries, 3)
+
+		vendorCfg.Vendor = true
+
+		out, err := vendorCfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "origin: repository")
+		require.Contains(t, string(out), "replicas: 3")
+	})
+
+	t.Run("offline from the import cache", func(t *testing.T) {
+		daemonURL := startGitDaemon(t, filepath.Dir(repoDir)) + "/" + filepath.Base(repoDir)
+		require.NoError(t, os.WriteFile("daemon.yaml", []byte("##++"+daemonURL+"@main?path=libs/base.yaml\napp: *base\n"), 0o600))
+
+		cacheDir := t.TempDir()
+
+		newConfig := func() *yamll.Config {
+			cfg := yamll.New(false, "DEBUG", "---", "daemon.yaml")
+			cfg.SetLogger()
+			cfg.NoLock = true
+			cfg.CacheDir = cacheDir
+			cfg.VendorDir = "no_vendor"
+			cfg.Retries = 0
+
+			return cfg
+		}
+
+		_, err := newConfig().Yaml(t.Context())
+		require.NoError(t, err)
+
+		offlineCfg := newConfig()
+		offlineCfg.Offline = true
+
+		out, err := offlineCfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "origin: repository")
+		require.Contains(t, string(out), "replicas: 3")
+	})
+
+	t.Run("files matched by a pattern import", func(t *testing.T) {
+		patternRepo, patternCommit := newGitRepo(t, map[string]string{
+			"envs/dev/app.yaml":     "##++common.yaml\ndev: *dev_common\n",
+			"envs/dev/common.yaml":  "dev_common: &dev_common\n  replicas: 1\n",
+			"envs/prod/app.yaml":    "##++common.yaml\nprod: *prod_common\n",
+			"envs/prod/common.yaml": "prod_common: &prod_common\n  replicas: 3\n",
+		})
+
+		patternURL := "git+file://" + filepath.ToSlash(patternRepo)
+		require.NoError(t, os.WriteFile("pattern.yaml", []byte("##++"+patternURL+"@main?path=envs/*/app.yaml\n"), 0o600))
+
+		patternCfg := yamll.New(false, "DEBUG", "---", "pattern.yaml")
+		patternCfg.SetLogger()
+		patternCfg.NoLock = true
+		patternCfg.NoCache = true
+
+		routes, err := patternCfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), patternCfg.Files...)
+		require.NoError(t, err)
+		require.Contains(t, routes, patternURL+"@"+patternCommit+"?path=envs/dev/common.yaml")
+		require.Contains(t, routes, patternURL+"@"+patternCommit+"?path=envs/prod/common.yaml")
+	})
+}
+
+func TestDependencyGitLocalRepository(t *testing.T) {
+	// Local repositories are read directly, without any git binary or server.
+	t.Setenv("PATH", "")
+
+	repoDir, taggedCommit := newGitRepo(t, map[string]string{"configs/base.yaml": "base: &base\n  replicas: 1\n"})
+
+	repo, err := git.PlainOpen(repoDir)
+	require.NoError(t, err)
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "configs", "base.yaml"), []byte("base: &base\n  replicas: 2\n"), 0o600))
+
+	_, err = worktree.Add("configs/base.yaml")
+	require.NoError(t, err)
+
+	signature := &object.Signature{Name: "yamll", Email: "yamll@example.com", When: time.Now()}
+
+	mainCommit, err := worktree.Commit("scale up", &git.CommitOptions{Author: signature})
+	require.NoError(t, err)
+
+	_, err = repo.CreateTag("v2.0.0", mainCommit, &git.CreateTagOptions{Tagger: signature, Message: "v2.0.0"})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	require.NoError(t, os.MkdirAll("app", 0o755))
+
+	relativeRepo, err := filepath.Rel(filepath.Join(dir, "app"), repoDir)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join("app", "root.yaml"), []byte(
+		"##++git+file://"+filepath.ToSlash(relativeRepo)+"@v1.0.0?path=configs/base.yaml\napp: *base\n",
+	), 0o600))
+
+	t.Run("relative repository and older tag", func(t *testing.T) {
+		cfg := yamll.New(false, "DEBUG", "---", filepath.Join("app", "root.yaml"))
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.NoCache = true
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "replicas: 1")
+	})
+
+	t.Run("offline", func(t *testing.T) {
+		cfg := yamll.New(false, "DEBUG", "---", filepath.Join("app", "root.yaml"))
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.Offline = true
+		cfg.CacheDir = t.TempDir()
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "replicas: 1")
+	})
+
+	t.Run("annotated tag from a directory inside the repository", func(t *testing.T) {
+		inside := filepath.Join(repoDir, "configs", "app.yaml")
+		require.NoError(t, os.WriteFile(inside, []byte("##++git+file://.@v2.0.0?path=configs/base.yaml\napp: *base\n"), 0o600))
+
+		cfg := yamll.New(false, "DEBUG", "---", inside)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.NoCache = true
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "replicas: 2")
+	})
+
+	t.Run("lock pins the commit", func(t *testing.T) {
+		rootFile := filepath.Join("app", "root.yaml")
+		lockFile := filepath.Join(dir, "yamll.lock")
+
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoCache = true
+		cfg.LockFile = lockFile
+
+		lockData, err := cfg.Lock(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(lockData), "source: git+file://"+filepath.ToSlash(filepath.Join("app", relativeRepo))+"@v1.0.0?path=configs/base.yaml")
+		require.Contains(t, string(lockData), "git_commit: "+taggedCommit)
+		require.NoError(t, os.WriteFile(lockFile, lockData, 0o600))
+
+		verifyCfg := yamll.New(false, "DEBUG", "---", rootFile)
+		verifyCfg.SetLogger()
+		verifyCfg.NoCache = true
+		verifyCfg.NoLock = true
+		verifyCfg.LockFile = lockFile
+
+		report, err := verifyCfg.LockVerify(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, report.String(), "Lock file is valid")
+	})
+
+	t.Run("missing repository", func(t *testing.T) {
+		missing := filepath.Join(dir, "missing.yaml")
+		require.NoError(t, os.WriteFile(missing, []byte("##++git+file://./no-repo@main?path=base.yaml\n"), 0o600))
+
+		cfg := yamll.New(false, "DEBUG", "---", missing)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.NoCache = true
+
+		_, err := cfg.Yaml(t.Context())
+		require.ErrorContains(t, err, "opening local git repository")
+	})
+}
+
+func TestDependencyGitPattern(t *testing.T) {
+	repoDir, commit := newGitRepo(t, map[string]string{
+		"configs/a.yaml": "a: &a\n  replicas: 1\n",
+		"configs/b.yaml": "b: &b\n  replicas: 2\n",
+		"other.yaml":     "other: true\n",
+	})
+
+	dir := t.TempDir()
+	rootFile := filepath.Join(dir, "root.yaml")
+	lockFile := filepath.Join(dir, "yamll.lock")
+	vendorDir := filepath.Join(dir, "yamll_vendor")
+	repository := "git+file://" + filepath.ToSlash(repoDir)
+	patternImport := repository + "@v1.0.0?path=configs/*.yaml"
+
+	require.NoError(t, os.WriteFile(rootFile, []byte("##++"+patternImport+"\napp:\n  <<: [*a, *b]\n"), 0o600))
+
+	newConfig := func() *yamll.Config {
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.NoCache = true
+		cfg.LockFile = lockFile
+		cfg.VendorDir = vendorDir
+
+		return cfg
+	}
+
+	t.Run("expands the matched files", func(t *testing.T) {
+		cfg := newConfig()
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "replicas: 1")
+		require.NotContains(t, string(out), "other: true")
+
+		routes, err := newConfig().ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+		require.NoError(t, err)
+
+		tree, err := yamll.YamlRoutes(routes).RenderDependencyTree(rootFile, "text", true, true)
+		require.NoError(t, err)
+		require.Contains(t, tree, repository+"@v1.0.0?path=configs/a.yaml")
+		require.Contains(t, tree, repository+"@v1.0.0?path=configs/b.yaml")
+	})
+
+	t.Run("lock has an entry per matched file", func(t *testing.T) {
+		cfg := newConfig()
+		cfg.NoLock = false
+
+		lockData, err := cfg.Lock(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(lockData), "pattern_file: "+repository+"@v1.0.0?path=configs/a.yaml")
+		require.Contains(t, string(lockData), "pattern_file: "+repository+"@v1.0.0?path=configs/b.yaml")
+		require.Contains(t, string(lockData), "git_commit: "+commit)
+		require.NoError(t, os.WriteFile(lockFile, lockData, 0o600))
+
+		report, err := newConfig().LockVerify(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, report.String(), "Lock file is valid")
+	})
+
+	t.Run("vendors every matched file", func(t *testing.T) {
+		report, err := newConfig().VendorDependencies(t.Context())
+		require.NoError(t, err)
+		require.Len(t, report.Entries, 2)
+
+		for _, entry := range report.Entries {
+			require.Equal(t, patternImport, entry.Source)
+			require.FileExists(t, filepath.Join(vendorDir, entry.Path))
+		}
+
+		cfg := newConfig()
+		cfg.NoLock = false
+		cfg.Vendor = true
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "replicas: 1")
+	})
+
+	t.Run("pattern matching nothing", func(t *testing.T) {
+		missing := filepath.Join(dir, "missing.yaml")
+		require.NoError(t, os.WriteFile(missing, []byte("##++"+repository+"@v1.0.0?path=configs/*.json\n"), 0o600))
+
+		cfg := yamll.New(false, "DEBUG", "---", missing)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.NoCache = true
+
+		_, err := cfg.Yaml(t.Context())
+		require.ErrorContains(t, err, "pattern matched no files")
+	})
+
+	t.Run("lock pins the commit of a moving ref", func(t *testing.T) {
+		movingRoot := filepath.Join(dir, "moving.yaml")
+		movingLock := filepath.Join(dir, "moving.lock")
+		require.NoError(t, os.WriteFile(movingRoot, []byte("##++"+repository+"@main?path=configs/*.yaml\napp:\n  <<: [*a, *b]\n"), 0o600))
+
+		newMovingConfig := func() *yamll.Config {
+			cfg := yamll.New(false, "DEBUG", "---", movingRoot)
+			cfg.SetLogger()
+			cfg.NoCache = true
+			cfg.LockFile = movingLock
+
+			return cfg
+		}
+
+		lockData, err := newMovingConfig().Lock(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(lockData), "git_commit: "+commit)
+		require.NoError(t, os.WriteFile(movingLock, lockData, 0o600))
+
+		repo, err := git.PlainOpen(repoDir)
+		require.NoError(t, err)
+
+		worktree, err := repo.Worktree()
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(filepath.Join(repoDir, "configs", "a.yaml"), []byte("a: &a\n  replicas: 9\n"), 0o600))
+
+		_, err = worktree.Add("configs/a.yaml")
+		require.NoError(t, err)
+
+		_, err = worktree.Commit("scale a", &git.CommitOptions{
+			Author: &object.Signature{Name: "yamll", Email: "yamll@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+
+		out, err := newMovingConfig().Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "replicas: 1")
+		require.NotContains(t, string(out), "replicas: 9")
+
+		report, err := newMovingConfig().LockVerify(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, report.String(), "Lock file is valid")
+	})
+
+	t.Run("locked pattern offline", func(t *testing.T) {
+		daemonURL := startGitDaemon(t, filepath.Dir(repoDir)) + "/" + filepath.Base(repoDir)
+		offlineRoot := filepath.Join(dir, "offline.yaml")
+		cacheDir := t.TempDir()
+		require.NoError(t, os.WriteFile(offlineRoot, []byte("##++"+daemonURL+"@v1.0.0?path=configs/*.yaml\napp:\n  <<: [*a, *b]\n"), 0o600))
+
+		newOfflineConfig := func() *yamll.Config {
+			cfg := yamll.New(false, "DEBUG", "---", offlineRoot)
+			cfg.SetLogger()
+			cfg.CacheDir = cacheDir
+			cfg.LockFile = filepath.Join(dir, "offline.lock")
+			cfg.VendorDir = filepath.Join(dir, "no_vendor")
+			cfg.Retries = 0
+
+			return cfg
+		}
+
+		lockData, err := newOfflineConfig().Lock(t.Context())
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "offline.lock"), lockData, 0o600))
+
+		_, err = newOfflineConfig().Yaml(t.Context())
+		require.NoError(t, err)
+
+		cfg := newOfflineConfig()
+		cfg.Offline = true
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "replicas: 1")
+		require.Contains(t, string(out), "replicas: 2")
+	})
+}
+
+func TestDependencyGitSemverConstraint(t *testing.T) {
+	repoDir, _ := newGitRepo(t, map[string]string{"configs/base.yaml": "base: &base\n  release: v1.0.0\n"})
+
+	repo, err := git.PlainOpen(repoDir)
+	require.NoError(t, err)
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	signature := &object.Signature{Name: "yamll", Email: "yamll@example.com", When: time.Now()}
+	commits := make(map[string]string)
+
+	for _, tag := range []string{"v1.2.0", "v1.2.4", "v1.3.0", "v2.0.0-rc.1", "v2.0.0"} {
+		require.NoError(t, os.WriteFile(filepath.Join(repoDir, "configs", "base.yaml"), []byte("base: &base\n  release: "+tag+"\n"), 0o600))
+
+		_, err = worktree.Add("configs/base.yaml")
+		require.NoError(t, err)
+
+		commit, err := worktree.Commit("release "+tag, &git.CommitOptions{Author: signature})
+		require.NoError(t, err)
+
+		_, err = repo.CreateTag(tag, commit, &git.CreateTagOptions{Tagger: signature, Message: tag})
+		require.NoError(t, err)
+
+		commits[tag] = commit.String()
+	}
+
+	dir := t.TempDir()
+	repoURL := "git+file://" + filepath.ToSlash(repoDir)
+
+	tests := []struct {
+		constraint string
+		locked     string
+		tag        string
+	}{
+		{constraint: "^1.2", locked: "^1.2", tag: "v1.3.0"},
+		{constraint: "~1.2.0", locked: "~1.2.0", tag: "v1.2.4"},
+		{constraint: ">=1.2,<1.3", locked: `">=1.2,<1.3"`, tag: "v1.2.4"},
+		{constraint: ">=2", locked: `">=2"`, tag: "v2.0.0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.constraint, func(t *testing.T) {
+			source := repoURL + "@" + test.constraint + "?path=configs/base.yaml"
+			rootFile := filepath.Join(t.TempDir(), "root.yaml")
+			require.NoError(t, os.WriteFile(rootFile, []byte("##++"+source+"\napp: *base\n"), 0o600))
+
+			cfg := yamll.New(false, "DEBUG", "---", rootFile)
+			cfg.SetLogger()
+			cfg.NoCache = true
+			cfg.LockFile = filepath.Join(dir, "yamll.lock")
+
+			out, err := cfg.Yaml(t.Context())
+			require.NoError(t, err)
+			require.Contains(t, string(out), "release: "+test.tag)
+
+			lockData, err := cfg.Lock(t.Context())
+			require.NoError(t, err)
+			require.Contains(t, string(lockData), "source: "+source)
+			require.Contains(t, string(lockData), "constraint: "+test.locked)
+			require.Contains(t, string(lockData), "resolved: "+yamll.PinGitImportToCommitForTest(source, commits[test.tag]))
+			require.Contains(t, string(lockData), "git_commit: "+commits[test.tag])
+			require.Contains(t, string(lockData), "version: "+test.tag)
+		})
+	}
+
+	t.Run("no matching tag", func(t *testing.T) {
+		rootFile := filepath.Join(dir, "missing.yaml")
+		require.NoError(t, os.WriteFile(rootFile, []byte("##++"+repoURL+"@^3?path=configs/base.yaml\n"), 0o600))
+
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.NoCache = true
+
+		_, err := cfg.Yaml(t.Context())
+		require.ErrorContains(t, err, "no tag satisfies '^3', the latest versions are 2.0.0, 2.0.0-rc.1, 1.3.0, 1.2.4, 1.2.0")
+	})
+}
@@ -0,0 +1,211 @@
+package yamll
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+// gitMeta is a parsed git import, git+<repository>@<ref>?path=<file>, where the repository is any of:
+//
+//	https://[user@]host[:port]/org/repo[.git]
+//	ssh://[user@]host[:port]/org/repo[.git]
+//	ssh://[user@]host:org/repo[.git]
+//	[user@]host:org/repo[.git]
+//	git://host[:port]/org/repo[.git]
+//	file:///path/to/repo, or file://relative/path/to/repo
+//
+// Refs cannot hold ':', which is what separates the ref from the user of scp-like repositories.
+type gitMeta struct {
+	// gitBaseURL is the URL the repository is fetched from.
+	gitBaseURL    string
+	referenceName string
+	path          string
+	ssh           bool
+	scheme        string
+	user          string
+	host          string
+	port          string
+	repoPath      string
+}
+
+const defaultSSHUser = "git"
+
+func (dependency *Dependency) getGitMetaData() (*gitMeta, error) {
+	return parseGitImport(dependency.Path)
+}
+
+func parseGitImport(importPath string) (*gitMeta, error) {
+	repository, referenceName, query, err := splitGitImport(importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := parseGitRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	for pair := range strings.SplitSeq(query, "&") {
+		if key, value, _ := strings.Cut(pair, "="); key == "path" {
+			meta.path = value
+		}
+	}
+
+	if meta.path == "" {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("unable to parse path from '%s', it has to end with ?path=<file>", importPath)}
+	}
+
+	meta.referenceName = referenceName
+
+	return meta, nil
+}
+
+// splitGitImport splits a git import into its repository, ref and query.
+func splitGitImport(importPath string) (string, string, string, error) {
+	location, query, _ := strings.Cut(strings.TrimPrefix(importPath, TypeGit), "?")
+
+	index := strings.LastIndex(location, "@")
+	if index < 0 || strings.Contains(location[index+1:], ":") {
+		return "", "", "", &errors.YamllError{Message: fmt.Sprintf("unable to parse ref from '%s', it has to be set as <repository>@<ref>", importPath)}
+	}
+
+	repository, referenceName := location[:index], location[index+1:]
+	if repository == "" || referenceName == "" {
+		return "", "", "", &errors.YamllError{Message: fmt.Sprintf("unable to split git url '%s'", importPath)}
+	}
+
+	return repository, referenceName, query, nil
+}
+
+func parseGitRepository(repository string) (*gitMeta, error) {
+	scheme, rest, found := strings.Cut(repository, "://")
+	if !found {
+		return parseSCPLikeRepository(repository, repository)
+	}
+
+	switch scheme {
+	case "file":
+		// The path of local repositories is taken as is, so it can be relative: git+file://../config@v1?path=base.yaml.
+		meta := &gitMeta{gitBaseURL: repository, scheme: scheme, repoPath: strings.TrimSuffix(rest, "/")}
+
+		return meta, meta.validate(repository)
+	case "https", "http", "git":
+		parsed, err := url.Parse(repository)
+		if err != nil {
+			return nil, &errors.YamllError{Message: fmt.Sprintf("unable to parse git url '%s': '%v'", repository, err)}
+		}
+
+		meta := &gitMeta{
+			gitBaseURL: repository,
+			scheme:     scheme,
+			user:       parsed.User.Username(),
+			host:       parsed.Hostname(),
+			port:       parsed.Port(),
+			repoPath:   strings.Trim(parsed.Path, "/"),
+		}
+
+		return meta, meta.validate(repository)
+	case "ssh":
+		authority, repoPath, _ := strings.Cut(rest, "/")
+
+		user, hostPort := "", authority
+		if index := strings.LastIndex(authority, "@"); index >= 0 {
+			user, hostPort = authority[:index], authority[index+1:]
+		}
+
+		host, port, err := splitGitHostPort(hostPort)
+		if err != nil {
+			// ssh://git@github.com:org/repo, the scp-like path spelled with the ssh scheme.
+			return parseSCPLikeRepository(repository, rest)
+		}
+
+		meta := &gitMeta{ssh: true, scheme: scheme, user: user, host: host, port: port, repoPath: strings.Trim(repoPath, "/")}
+		if meta.user == "" {
+			meta.user = defaultSSHUser
+		}
+
+		meta.gitBaseURL = "ssh://" + meta.user + "@" + meta.hostPort() + "/" + meta.repoPath
+
+		return meta, meta.validate(repository)
+	default:
+		return nil, &errors.YamllError{Message: fmt.Sprintf("git url '%s' has the unsupported scheme '%s'", repository, scheme)}
+	}
+}
+
+// parseSCPLikeRepository parses [user@]host:path, which is fetched as is so that the path stays relative to the home of the user.
+func parseSCPLikeRepository(repository, location string) (*gitMeta, error) {
+	userHost, repoPath, found := strings.Cut(location, ":")
+	if !found || strings.Contains(userHost, "/") {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("unable to parse git url '%s'", repository)}
+	}
+
+	meta := &gitMeta{ssh: true, scheme: "ssh", user: defaultSSHUser, host: userHost, repoPath: strings.Trim(repoPath, "/")}
+	if index := strings.LastIndex(userHost, "@"); index >= 0 {
+		meta.user, meta.host = userHost[:index], userHost[index+1:]
+	}
+
+	meta.gitBaseURL = meta.user + "@" + meta.host + ":" + meta.repoPath
+
+	return meta, meta.validate(repository)
+}
+
+// splitGitHostPort splits host[:port], failing when what follows the colon is not a port.
+func splitGitHostPort(hostPort string) (string, string, error) {
+	if !strings.Contains(strings.TrimSuffix(strings.TrimPrefix(hostPort, "["), "]"), ":") {
+		return hostPort, "", nil
+	}
+
+	if strings.HasPrefix(hostPort, "[") && strings.HasSuffix(hostPort, "]") {
+		return hostPort[1 : len(hostPort)-1], "", nil
+	}
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return "", "", err
+	}
+
+	if port == "" || strings.Trim(port, "0123456789") != "" {
+		return "", "", &errors.YamllError{Message: fmt.Sprintf("'%s' is not a port", port)}
+	}
+
+	return host, port, nil
+}
+
+func (meta *gitMeta) validate(repository string) error {
+	switch {
+	case meta.host == "" && meta.scheme != "file":
+		return &errors.YamllError{Message: fmt.Sprintf("git url '%s' has no host", repository)}
+	case meta.repoPath == "":
+		return &errors.YamllError{Message: fmt.Sprintf("git url '%s' has no repository path", repository)}
+	default:
+		return nil
+	}
+}
+
+// hostPort is the host with its port when set, the address ssh connects to without the default port.
+func (meta *gitMeta) hostPort() string {
+	if meta.port != "" {
+		return net.JoinHostPort(meta.host, meta.port)
+	}
+
+	if strings.Contains(meta.host, ":") {
+		return "[" + meta.host + "]"
+	}
+
+	return meta.host
+}
+
+// location is host[:port]/org/repo without the .git suffix, the same for every spelling of a repository.
+// Local repositories are located by their cleaned path.
+func (meta *gitMeta) location() string {
+	if meta.scheme == "file" {
+		return path.Clean(meta.repoPath)
+	}
+
+	return strings.ToLower(meta.hostPort()) + "/" + strings.TrimSuffix(meta.repoPath, ".git")
+}
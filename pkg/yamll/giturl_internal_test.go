@@ -0,0 +1,89 @@
+package yamll
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitImport(t *testing.T) {
+	tests := map[string]gitMeta{
+		"git+https://github.com/org/repo@v1.2.0?path=libs/base.yaml": {
+			gitBaseURL: "https://github.com/org/repo", scheme: "https", host: "github.com", repoPath: "org/repo",
+			referenceName: "v1.2.0", path: "libs/base.yaml",
+		},
+		"git+https://token@git.internal:8443/group/sub/repo.git@feature/x?path=base.yaml": {
+			gitBaseURL: "https://token@git.internal:8443/group/sub/repo.git", scheme: "https", user: "token", host: "git.internal", port: "8443",
+			repoPath: "group/sub/repo.git", referenceName: "feature/x", path: "base.yaml",
+		},
+		"git+ssh://git@github.com:nikhilsbhat/yamll@main?path=internal/fixtures/base.yaml": {
+			gitBaseURL: "git@github.com:nikhilsbhat/yamll", ssh: true, scheme: "ssh", user: "git", host: "github.com",
+			repoPath: "nikhilsbhat/yamll", referenceName: "main", path: "internal/fixtures/base.yaml",
+		},
+		"git+deploy@git.internal:org/repo.git@v1?path=base.yaml": {
+			gitBaseURL: "deploy@git.internal:org/repo.git", ssh: true, scheme: "ssh", user: "deploy", host: "git.internal",
+			repoPath: "org/repo.git", referenceName: "v1", path: "base.yaml",
+		},
+		"git+ssh://deploy@git.internal:2222/org/repo@v1?path=base.yaml": {
+			gitBaseURL: "ssh://deploy@git.internal:2222/org/repo", ssh: true, scheme: "ssh", user: "deploy", host: "git.internal", port: "2222",
+			repoPath: "org/repo", referenceName: "v1", path: "base.yaml",
+		},
+		"git+ssh://git.internal/srv/git/repo.git@v1?path=base.yaml": {
+			gitBaseURL: "ssh://git@git.internal/srv/git/repo.git", ssh: true, scheme: "ssh", user: "git", host: "git.internal",
+			repoPath: "srv/git/repo.git", referenceName: "v1", path: "base.yaml",
+		},
+		"git+ssh://git@[::1]:2222/org/repo@v1?path=base.yaml": {
+			gitBaseURL: "ssh://git@[::1]:2222/org/repo", ssh: true, scheme: "ssh", user: "git", host: "::1", port: "2222",
+			repoPath: "org/repo", referenceName: "v1", path: "base.yaml",
+		},
+		"git+git://git.internal/org/repo@0123456789abcdef0123456789abcdef01234567?path=base.yaml": {
+			gitBaseURL: "git://git.internal/org/repo", scheme: "git", host: "git.internal", repoPath: "org/repo",
+			referenceName: "0123456789abcdef0123456789abcdef01234567", path: "base.yaml",
+		},
+		"git+file:///srv/config@v1?path=base.yaml": {
+			gitBaseURL: "file:///srv/config", scheme: "file", repoPath: "/srv/config", referenceName: "v1", path: "base.yaml",
+		},
+		"git+file://../config/@v1?path=base.yaml": {
+			gitBaseURL: "file://../config/", scheme: "file", repoPath: "../config", referenceName: "v1", path: "base.yaml",
+		},
+	}
+
+	for importPath, expected := range tests {
+		meta, err := parseGitImport(importPath)
+		require.NoError(t, err, importPath)
+		require.Equal(t, expected, *meta, importPath)
+	}
+
+	t.Run("every spelling of a repository shares its location", func(t *testing.T) {
+		for _, importPath := range []string{
+			"git+ssh://git@GitHub.com:org/repo.git@main?path=base.yaml",
+			"git+git@github.com:org/repo@main?path=base.yaml",
+			"git+ssh://github.com/org/repo@main?path=base.yaml",
+			"git+https://github.com/org/repo.git@main?path=base.yaml",
+		} {
+			meta, err := parseGitImport(importPath)
+			require.NoError(t, err, importPath)
+			require.Equal(t, "github.com/org/repo", meta.location(), importPath)
+		}
+	})
+
+	t.Run("invalid imports", func(t *testing.T) {
+		tests := map[string]string{
+			"git+https://github.com/org/repo?path=base.yaml":    "unable to parse ref",
+			"git+git@github.com:org/repo?path=base.yaml":        "unable to parse ref",
+			"git+https://github.com/org/repo@main":              "unable to parse path",
+			"git+https://github.com/org/repo@main?file=x.yaml":  "unable to parse path",
+			"git+https://github.com@main?path=base.yaml":        "has no repository path",
+			"git+ftp://github.com/org/repo@main?path=base.yaml": "unsupported scheme 'ftp'",
+			"git+github.com/org/repo@main?path=base.yaml":       "unable to parse git url",
+			"git+ssh://git@github.com:@main?path=base.yaml":     "has no repository path",
+			"git+ssh://git@github.com:22/@main?path=base.yaml":  "has no repository path",
+			"git+https:///org/repo@main?path=base.yaml":         "has no host",
+		}
+
+		for importPath, message := range tests {
+			_, err := parseGitImport(importPath)
+			require.ErrorContains(t, err, message, importPath)
+		}
+	})
+}
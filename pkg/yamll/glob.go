@@ -0,0 +1,239 @@
+package yamll
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const globMeta = `*?[{\`
+
+// matchGlob reports whether the slash separated name matches pattern, a path.Match pattern extended with
+// ** matching any number of directories, envs/**/*.yaml, and {a,b} alternatives, envs/{dev,prod}.yaml.
+func matchGlob(pattern, name string) (bool, error) {
+	alternatives, err := expandBraces(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	for _, alternative := range alternatives {
+		matched, err := matchGlobSegments(strings.Split(alternative, "/"), strings.Split(name, "/"))
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+
+	return false, nil
+}
+
+// validateGlob reports a malformed pattern up front, since path.Match only reports it when reaching the bad segment.
+func validateGlob(pattern string) error {
+	alternatives, err := expandBraces(pattern)
+	if err != nil {
+		return err
+	}
+
+	for _, alternative := range alternatives {
+		for segment := range strings.SplitSeq(alternative, "/") {
+			if _, err = path.Match(segment, ""); err != nil {
+				return fmt.Errorf("%s: %w", pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func matchGlobSegments(pattern, name []string) (bool, error) {
+	for len(pattern) != 0 {
+		if pattern[0] != "**" {
+			if len(name) == 0 {
+				return false, nil
+			}
+
+			matched, err := path.Match(pattern[0], name[0])
+			if err != nil || !matched {
+				return false, err
+			}
+
+			pattern, name = pattern[1:], name[1:]
+
+			continue
+		}
+
+		for len(pattern) > 1 && pattern[1] == "**" {
+			pattern = pattern[1:]
+		}
+
+		if len(pattern) == 1 {
+			return true, nil
+		}
+
+		for skip := 0; skip <= len(name); skip++ {
+			matched, err := matchGlobSegments(pattern[1:], name[skip:])
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+
+		return false, nil
+	}
+
+	return len(name) == 0, nil
+}
+
+// expandBraces expands every {a,b} of the pattern into the patterns it stands for, nested braces included.
+func expandBraces(pattern string) ([]string, error) {
+	open := -1
+
+	for index := 0; index < len(pattern) && open < 0; index++ {
+		switch pattern[index] {
+		case '\\':
+			index++
+		case '{':
+			open = index
+		}
+	}
+
+	if open < 0 {
+		if strings.Contains(strings.ReplaceAll(pattern, `\}`, ""), "}") {
+			return nil, fmt.Errorf("%s: unbalanced braces", pattern)
+		}
+
+		return []string{pattern}, nil
+	}
+
+	var (
+		depth        int
+		alternatives []string
+		start        = open + 1
+	)
+
+	for index := open; index < len(pattern); index++ {
+		switch pattern[index] {
+		case '\\':
+			index++
+		case '{':
+			depth++
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, pattern[start:index])
+				start = index + 1
+			}
+		case '}':
+			depth--
+			if depth != 0 {
+				continue
+			}
+
+			alternatives = append(alternatives, pattern[start:index])
+
+			var expanded []string
+
+			for _, alternative := range alternatives {
+				patterns, err := expandBraces(pattern[:open] + alternative + pattern[index+1:])
+				if err != nil {
+					return nil, err
+				}
+
+				expanded = append(expanded, patterns...)
+			}
+
+			return expanded, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: unbalanced braces", pattern)
+}
+
+// globFiles lists the files matching an absolute pattern, sorted by path.
+// Walking starts from the directory preceding the first wildcard, and stops at the depth of the pattern unless it holds **.
+func globFiles(pattern string) ([]string, error) {
+	slashPattern := filepath.ToSlash(pattern)
+	if err := validateGlob(slashPattern); err != nil {
+		return nil, err
+	}
+
+	alternatives, _ := expandBraces(slashPattern)
+
+	maxDepth := 0
+
+	for _, alternative := range alternatives {
+		if strings.Contains(alternative, "**") {
+			maxDepth = -1
+
+			break
+		}
+
+		maxDepth = max(maxDepth, strings.Count(alternative, "/"))
+	}
+
+	root := globRoot(slashPattern)
+
+	var matches []string
+
+	err := filepath.WalkDir(filepath.FromSlash(root), func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped, like filepath.Glob does.
+			return nil
+		}
+
+		name := filepath.ToSlash(filePath)
+
+		if entry.IsDir() {
+			if maxDepth >= 0 && strings.Count(name, "/") >= maxDepth && name != root {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+				return nil
+			}
+		}
+
+		matched, err := matchGlob(slashPattern, name)
+		if err != nil {
+			return err
+		}
+
+		if matched {
+			matches = append(matches, filePath)
+		}
+
+		return nil
+	})
+	if err != nil && !stdErrors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	sort.Strings(matches)
+
+	return matches, nil
+}
+
+// globRoot is the directory of the pattern preceding its first wildcard.
+func globRoot(pattern string) string {
+	index := strings.IndexAny(pattern, globMeta)
+	if index < 0 {
+		return path.Dir(pattern)
+	}
+
+	root := pattern[:strings.LastIndex(pattern[:index], "/")+1]
+	if root == "" {
+		return "."
+	}
+
+	if root != "/" {
+		root = strings.TrimSuffix(root, "/")
+	}
+
+	return root
+}
@@ -0,0 +1,58 @@
+package yamll
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{pattern: "envs/*.yaml", name: "envs/dev.yaml", matched: true},
+		{pattern: "envs/*.yaml", name: "envs/dev/app.yaml"},
+		{pattern: "envs/**/*.yaml", name: "envs/dev.yaml", matched: true},
+		{pattern: "envs/**/*.yaml", name: "envs/dev/eu/app.yaml", matched: true},
+		{pattern: "envs/**/*.yaml", name: "other/dev/app.yaml"},
+		{pattern: "envs/**", name: "envs/dev/app.yaml", matched: true},
+		{pattern: "**/secret*.yaml", name: "envs/dev/secret-db.yaml", matched: true},
+		{pattern: "envs/**/**/app.yaml", name: "envs/app.yaml", matched: true},
+		{pattern: "envs/{dev,prod}.yaml", name: "envs/prod.yaml", matched: true},
+		{pattern: "envs/{dev,prod}.yaml", name: "envs/qa.yaml"},
+		{pattern: "envs/{dev,prod/{eu,us}}/*.yaml", name: "envs/prod/us/app.yaml", matched: true},
+		{pattern: "envs/{dev,prod/{eu,us}}/*.yaml", name: "envs/prod/app.yaml"},
+		{pattern: `envs/\{dev\}.yaml`, name: "envs/{dev}.yaml", matched: true},
+		{pattern: "/abs/**/*.yaml", name: "/abs/a/b.yaml", matched: true},
+	}
+
+	for _, test := range tests {
+		matched, err := matchGlob(test.pattern, test.name)
+		require.NoError(t, err, test.pattern)
+		require.Equal(t, test.matched, matched, "%s against %s", test.pattern, test.name)
+	}
+
+	t.Run("invalid patterns", func(t *testing.T) {
+		for _, pattern := range []string{"envs/{dev,prod.yaml", "envs/dev}.yaml", "envs/[a-.yaml", "envs/**/[.yaml"} {
+			require.Error(t, validateGlob(pattern), pattern)
+		}
+	})
+}
+
+func TestGlobRoot(t *testing.T) {
+	tests := map[string]string{
+		"/srv/envs/**/*.yaml":   "/srv/envs",
+		"/srv/envs/{dev,qa}/x":  "/srv/envs",
+		"/*.yaml":               "/",
+		"*.yaml":                ".",
+		"/srv/envs/base.yaml":   "/srv/envs",
+		"/srv/env?/base.yaml":   "/srv",
+		`/srv/\[literal]/*.yml`: "/srv",
+	}
+
+	for pattern, root := range tests {
+		require.Equal(t, root, globRoot(pattern), pattern)
+	}
+}
@@ -0,0 +1,367 @@
+package yamll
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Graph is the import graph of a resolved dependency tree: one node per source and one edge per import statement.
+type Graph struct {
+	Nodes []*GraphNode
+	Edges []GraphEdge
+	nodes map[string]*GraphNode
+	ids   map[string]string
+	out   map[string][]GraphEdge
+	in    map[string][]GraphEdge
+}
+
+// GraphNode is a single source in the import graph.
+// Key is the route key, the import as it was first written, and ID the canonical identity shared by every spelling of it.
+type GraphNode struct {
+	Key   string
+	ID    string
+	Type  string
+	Root  bool
+	Index int
+}
+
+// GraphEdge is the import of To declared in From by Statement, on line Line of From.
+type GraphEdge struct {
+	From      string
+	To        string
+	Statement string
+	Line      int
+}
+
+// GraphCycle is an import cycle, Path starts and ends with the same node.
+type GraphCycle struct {
+	Path  []string
+	Edges []GraphEdge
+}
+
+// Graph builds the import graph from the resolved routes.
+func (yamlRoutes YamlRoutes) Graph() *Graph {
+	graph := &Graph{
+		Nodes: make([]*GraphNode, 0, len(yamlRoutes)),
+		nodes: make(map[string]*GraphNode, len(yamlRoutes)),
+		ids:   make(map[string]string, len(yamlRoutes)),
+		out:   make(map[string][]GraphEdge, len(yamlRoutes)),
+		in:    make(map[string][]GraphEdge, len(yamlRoutes)),
+	}
+
+	for key, route := range yamlRoutes {
+		if route == nil {
+			continue
+		}
+
+		node := &GraphNode{Key: key, ID: route.ID, Type: dependencyType(key), Root: route.Root, Index: route.Index}
+		if node.ID == "" {
+			node.ID = dependencyID(key)
+		}
+
+		graph.Nodes = append(graph.Nodes, node)
+		graph.nodes[key] = node
+		graph.ids[node.ID] = key
+	}
+
+	sort.SliceStable(graph.Nodes, func(i, j int) bool { return nodeLess(graph.Nodes[i], graph.Nodes[j]) })
+
+	for _, node := range graph.Nodes {
+		for _, dependency := range yamlRoutes[node.Key].Dependency {
+			if dependency == nil {
+				continue
+			}
+
+			edge := GraphEdge{From: node.Key, To: dependency.Path, Statement: dependency.statement, Line: dependency.line}
+
+			graph.Edges = append(graph.Edges, edge)
+			graph.out[edge.From] = append(graph.out[edge.From], edge)
+			graph.in[edge.To] = append(graph.in[edge.To], edge)
+		}
+	}
+
+	return graph
+}
+
+// Node returns the node stored under the route key.
+func (graph *Graph) Node(key string) (*GraphNode, bool) {
+	node, ok := graph.nodes[key]
+
+	return node, ok
+}
+
+// Lookup finds the route key of a source however it is spelled, e.g. ./base.yaml, base.yaml or its absolute path.
+func (graph *Graph) Lookup(source string) (string, bool) {
+	if _, ok := graph.nodes[source]; ok {
+		return source, true
+	}
+
+	key, ok := graph.ids[dependencyID(source)]
+
+	return key, ok
+}
+
+// Imports returns the edges leaving key, in the order the imports are declared.
+func (graph *Graph) Imports(key string) []GraphEdge {
+	return graph.out[key]
+}
+
+// ImportedBy returns the edges pointing at key.
+func (graph *Graph) ImportedBy(key string) []GraphEdge {
+	return graph.in[key]
+}
+
+// Roots returns the root files in the order they were given.
+func (graph *Graph) Roots() []string {
+	roots := make([]string, 0, len(graph.Nodes))
+
+	for _, node := range graph.Nodes {
+		if node.Root {
+			roots = append(roots, node.Key)
+		}
+	}
+
+	return roots
+}
+
+// TopologicalOrder lists every node after the nodes it imports.
+// Nodes reachable from the roots come first, in depth-first import order, followed by any unreachable node.
+// Edges closing a cycle are ignored, see Cycles.
+func (graph *Graph) TopologicalOrder() []string {
+	ordered := make([]string, 0, len(graph.Nodes))
+	seen := make(map[string]struct{}, len(graph.Nodes))
+
+	for _, root := range graph.Roots() {
+		ordered = graph.appendPostOrder(root, seen, make(map[string]struct{}), ordered)
+	}
+
+	for _, node := range graph.Nodes {
+		if _, ok := seen[node.Key]; !ok {
+			seen[node.Key] = struct{}{}
+			ordered = append(ordered, node.Key)
+		}
+	}
+
+	return ordered
+}
+
+// orderFrom lists the nodes reachable from key, dependencies first, skipping the ones in seen.
+func (graph *Graph) orderFrom(key string, seen map[string]struct{}) []string {
+	return graph.appendPostOrder(key, seen, make(map[string]struct{}), nil)
+}
+
+func (graph *Graph) appendPostOrder(key string, seen, visiting map[string]struct{}, ordered []string) []string {
+	if _, ok := seen[key]; ok {
+		return ordered
+	}
+
+	if _, ok := visiting[key]; ok {
+		return ordered
+	}
+
+	if _, ok := graph.nodes[key]; !ok {
+		return ordered
+	}
+
+	visiting[key] = struct{}{}
+
+	defer delete(visiting, key)
+
+	for _, edge := range graph.out[key] {
+		ordered = graph.appendPostOrder(edge.To, seen, visiting, ordered)
+	}
+
+	seen[key] = struct{}{}
+
+	return append(ordered, key)
+}
+
+// Cycles returns every import cycle once, each with the full path of imports that closes it.
+func (graph *Graph) Cycles() []GraphCycle {
+	var (
+		cycles   []GraphCycle
+		stack    []GraphEdge
+		onStack  = make(map[string]int)
+		visited  = make(map[string]struct{})
+		reported = make(map[string]struct{})
+	)
+
+	var visit func(key string)
+
+	visit = func(key string) {
+		onStack[key] = len(stack)
+		defer delete(onStack, key)
+
+		for _, edge := range graph.out[key] {
+			if start, ok := onStack[edge.To]; ok {
+				cycle := newGraphCycle(append(append([]GraphEdge{}, stack[start:]...), edge))
+				if _, seen := reported[cycle.id()]; !seen {
+					reported[cycle.id()] = struct{}{}
+					cycles = append(cycles, cycle)
+				}
+
+				continue
+			}
+
+			if _, ok := visited[edge.To]; ok {
+				continue
+			}
+
+			if _, ok := graph.nodes[edge.To]; !ok {
+				continue
+			}
+
+			stack = append(stack, edge)
+			visit(edge.To)
+			stack = stack[:len(stack)-1]
+		}
+
+		visited[key] = struct{}{}
+	}
+
+	for _, node := range graph.Nodes {
+		if _, ok := visited[node.Key]; !ok {
+			visit(node.Key)
+		}
+	}
+
+	return cycles
+}
+
+func newGraphCycle(edges []GraphEdge) GraphCycle {
+	cycle := GraphCycle{Path: make([]string, 0, len(edges)+1), Edges: edges}
+
+	for _, edge := range edges {
+		cycle.Path = append(cycle.Path, edge.From)
+	}
+
+	cycle.Path = append(cycle.Path, edges[len(edges)-1].To)
+
+	return cycle
+}
+
+// id is the same for every rotation of a cycle, so a cycle entered from different nodes is reported once.
+func (cycle GraphCycle) id() string {
+	members := append([]string{}, cycle.Path[:len(cycle.Path)-1]...)
+	sort.Strings(members)
+
+	return strings.Join(members, "\x00")
+}
+
+// String renders the cycle as file:line hops, each naming the import statement that leads to the next file.
+func (cycle GraphCycle) String() string {
+	parts := make([]string, 0, len(cycle.Path))
+
+	for _, edge := range cycle.Edges {
+		if edge.Line > 0 {
+			parts = append(parts, fmt.Sprintf("%s:%d", edge.From, edge.Line))
+
+			continue
+		}
+
+		parts = append(parts, edge.From)
+	}
+
+	parts = append(parts, cycle.Path[len(cycle.Path)-1])
+
+	return strings.Join(parts, " -> ")
+}
+
+func nodeLess(left, right *GraphNode) bool {
+	switch {
+	case left.Root != right.Root:
+		return left.Root
+	case left.Index != right.Index:
+		return left.Index < right.Index
+	default:
+		return left.Key < right.Key
+	}
+}
+
+// dependencyID returns the canonical identity of an import, the same for every spelling of the same source:
+// absolute cleaned paths for local files, and normalised host, path and ref for remote imports.
+func dependencyID(source string) string {
+	switch dependencyType(source) {
+	case TypeArchive:
+		return archiveID(source)
+	case TypeDirectory:
+		dir, query := splitDirectoryImport(source)
+
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return directoryImport(dir, query)
+		}
+
+		return directoryImport(absDir, query)
+	case TypeFile, TypeFilePattern:
+		absPath, err := filepath.Abs(source)
+		if err != nil {
+			return filepath.Clean(source)
+		}
+
+		return absPath
+	case TypeURL:
+		return urlID(source)
+	case TypeGit:
+		return gitID(source)
+	case TypeOCI:
+		ref, err := parseOCIReference(source)
+		if err != nil {
+			return source
+		}
+
+		separator := ":"
+		if ref.isDigest() {
+			separator = "@"
+		}
+
+		id := TypeOCI + strings.ToLower(ref.Registry) + "/" + ref.Repository + separator + ref.Reference
+		if ref.Path != "" {
+			id += "?path=" + cleanOriginPath(ref.Path)
+		}
+
+		return id
+	default:
+		return source
+	}
+}
+
+func urlID(source string) string {
+	parsed, err := url.Parse(source)
+	if err != nil {
+		return source
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+
+	switch {
+	case parsed.Scheme == "http" && parsed.Port() == "80", parsed.Scheme == "https" && parsed.Port() == "443":
+		parsed.Host = parsed.Hostname()
+	}
+
+	if parsed.Path != "" {
+		parsed.Path = "/" + cleanOriginPath(parsed.Path)
+	}
+
+	return parsed.String()
+}
+
+func gitID(source string) string {
+	meta, err := (&Dependency{Path: source}).getGitMetaData()
+	if err != nil {
+		return source
+	}
+
+	return TypeGit + meta.scheme + "://" + meta.location() + "@" + meta.referenceName + "?path=" + cleanOriginPath(meta.path)
+}
+
+// cleanOriginPath cleans a slash separated path within a remote origin, without its leading slash.
+func cleanOriginPath(originPath string) string {
+	return strings.TrimPrefix(path.Clean("/"+originPath), "/")
+}
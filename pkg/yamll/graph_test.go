@@ -0,0 +1,102 @@
+package yamll_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYamlRoutesGraph(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	require.NoError(t, writeFile("base.yaml", "base: &base\n  name: base\n"))
+	require.NoError(t, writeFile("app.yaml", "##++base.yaml\napp: &app\n  <<: *base\n"))
+	require.NoError(t, writeFile("root.yaml", "##++app.yaml\n\n##++./base.yaml\n##++"+filepath.Join(dir, "base.yaml")+"\nroot: *app\n"))
+
+	cfg := yamll.New(false, "DEBUG", "---", "root.yaml")
+	cfg.SetLogger()
+	cfg.NoLock = true
+
+	routes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+	require.NoError(t, err)
+
+	t.Run("every spelling of a source shares one route", func(t *testing.T) {
+		require.Len(t, routes, 3)
+		require.Contains(t, routes, "base.yaml")
+	})
+
+	graph := yamll.YamlRoutes(routes).Graph()
+
+	t.Run("edges carry the import statement and line", func(t *testing.T) {
+		edges := graph.Imports("root.yaml")
+		require.Len(t, edges, 3)
+		require.Equal(t, yamll.GraphEdge{From: "root.yaml", To: "app.yaml", Statement: "##++app.yaml", Line: 1}, edges[0])
+		require.Equal(t, "base.yaml", edges[1].To)
+		require.Equal(t, 3, edges[1].Line)
+		require.Equal(t, "##++./base.yaml", edges[1].Statement)
+		require.Equal(t, "base.yaml", edges[2].To)
+		require.Len(t, graph.ImportedBy("base.yaml"), 3)
+	})
+
+	t.Run("lookup finds any spelling", func(t *testing.T) {
+		for _, spelling := range []string{"base.yaml", "./base.yaml", filepath.Join(dir, "base.yaml")} {
+			key, ok := graph.Lookup(spelling)
+			require.True(t, ok, spelling)
+			require.Equal(t, "base.yaml", key)
+		}
+	})
+
+	t.Run("topological order", func(t *testing.T) {
+		require.Equal(t, []string{"base.yaml", "app.yaml", "root.yaml"}, graph.TopologicalOrder())
+		require.Empty(t, graph.Cycles())
+	})
+
+	t.Run("impact and lint agree on the canonical source", func(t *testing.T) {
+		report, err := cfg.Impact(t.Context(), filepath.Join(dir, "base.yaml"))
+		require.NoError(t, err)
+		require.Equal(t, []string{"app.yaml"}, report.Affected)
+
+		lintReport, err := cfg.Lint(t.Context())
+		require.NoError(t, err)
+		require.NotContains(t, codes(lintReport.Issues), yamll.LintDuplicateLibraries)
+	})
+}
+
+func TestYamlRoutesGraphCycles(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	require.NoError(t, writeFile("root.yaml", "##++a.yaml\nroot: true\n"))
+	require.NoError(t, writeFile("a.yaml", "a: true\n##++b.yaml\n"))
+	require.NoError(t, writeFile("b.yaml", "##++c.yaml\nb: true\n"))
+	require.NoError(t, writeFile("c.yaml", "c: true\n\n##++a.yaml\n"))
+
+	cfg := yamll.New(false, "DEBUG", "---", "root.yaml")
+	cfg.SetLogger()
+	cfg.NoLock = true
+
+	routes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+	require.NoError(t, err)
+
+	cycles := yamll.YamlRoutes(routes).Graph().Cycles()
+	require.Len(t, cycles, 1)
+	require.Equal(t, []string{"a.yaml", "b.yaml", "c.yaml", "a.yaml"}, cycles[0].Path)
+	require.Equal(t, "a.yaml:2 -> b.yaml:1 -> c.yaml:3 -> a.yaml", cycles[0].String())
+
+	_, err = cfg.Yaml(t.Context())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "import cycle detected: a.yaml:2 -> b.yaml:1 -> c.yaml:3 -> a.yaml")
+
+	report, err := cfg.Lint(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, codes(report.Issues), yamll.LintCircularRefs)
+
+	for _, issue := range report.Issues {
+		if issue.Code == yamll.LintCircularRefs {
+			require.Equal(t, "import cycle detected: a.yaml:2 -> b.yaml:1 -> c.yaml:3 -> a.yaml", issue.Message)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package yamll
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
@@ -14,21 +15,23 @@ type ImpactReport struct {
 	Total    int
 }
 
-func (cfg *Config) Impact(target string) (ImpactReport, error) {
+func (cfg *Config) Impact(ctx context.Context, target string) (ImpactReport, error) {
 	cfg.Root = false
 
-	routes, err := cfg.ResolveDependencies(make(map[string]*YamlData), cfg.Files...)
+	routes, err := cfg.ResolveDependencies(ctx, make(map[string]*YamlData), cfg.Files...)
 	if err != nil {
 		return ImpactReport{}, &errors.YamllError{Message: fmt.Sprintf("fetching dependency tree errored with: '%v'", err)}
 	}
 
 	yamlRoutes := YamlRoutes(routes)
-	if _, exists := yamlRoutes[target]; !exists {
+	graph := yamlRoutes.Graph()
+
+	targetKey, exists := graph.Lookup(target)
+	if !exists {
 		return ImpactReport{}, &errors.YamllError{Message: fmt.Sprintf("target file '%s' not found in dependency tree", target)}
 	}
 
-	reverseDeps := buildReverseDependencies(yamlRoutes)
-	affected := collectImpactedFiles(target, routes, reverseDeps)
+	affected := collectImpactedFiles(targetKey, graph)
 
 	sort.SliceStable(affected, func(i, j int) bool {
 		return routeLess(yamlRoutes, affected[i], affected[j])
@@ -41,41 +44,18 @@ func (cfg *Config) Impact(target string) (ImpactReport, error) {
 	}, nil
 }
 
-func buildReverseDependencies(routes YamlRoutes) map[string]map[string]struct{} {
-	reverse := make(map[string]map[string]struct{}, len(routes))
-
-	for file, route := range routes {
-		if route == nil {
-			continue
-		}
-
-		for _, dep := range route.Dependency {
-			if dep == nil {
-				continue
-			}
-
-			if _, ok := reverse[dep.Path]; !ok {
-				reverse[dep.Path] = make(map[string]struct{})
-			}
-
-			reverse[dep.Path][file] = struct{}{}
-		}
-	}
-
-	return reverse
-}
-
-func collectImpactedFiles(target string, routes map[string]*YamlData, reverse map[string]map[string]struct{}) []string {
+func collectImpactedFiles(target string, graph *Graph) []string {
 	visited := make(map[string]struct{})
 	queue := []string{target}
-	affected := make([]string, 0, len(reverse))
+	affected := make([]string, 0, len(graph.Nodes))
 
 	for len(queue) > 0 {
 		current := queue[0]
 		queue = queue[1:]
 
-		for dependent := range reverse[current] {
-			if route, ok := routes[dependent]; ok && route != nil && route.Root {
+		for _, edge := range graph.ImportedBy(current) {
+			dependent := edge.From
+			if node, ok := graph.Node(dependent); ok && node.Root {
 				continue
 			}
 
@@ -28,7 +28,7 @@ func TestConfig_Impact(t *testing.T) {
 	cfg := yamll.New(false, "INFO", "---", root)
 	cfg.SetLogger()
 
-	report, err := cfg.Impact(common)
+	report, err := cfg.Impact(t.Context(), common)
 	require.NoError(t, err)
 	require.Equal(t, common, report.Target)
 	require.Len(t, report.Affected, 4)
@@ -0,0 +1,321 @@
+package yamll
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+// projectRootAnchor marks an import as relative to the project root instead of the importing file, e.g. ##++//configs/base.yaml.
+const projectRootAnchor = "//"
+
+// resolveImportPath rewrites a local import declared in importer so that it no longer depends on the working directory.
+// Relative paths resolve against the directory of the importing file, or the directory a directory import lists,
+// //-anchored paths against the project root, and absolute paths are left untouched.
+// Inside remote files the same imports resolve against the origin of the file instead, see resolveRemoteImportPath.
+func (cfg *Config) resolveImportPath(importer *Dependency, importerFile File, dependency *Dependency) error {
+	if dependency.Type == TypeGit {
+		return resolveLocalGitImportPath(importer, dependency)
+	}
+
+	if dependency.Type == TypeDirectory {
+		return cfg.resolveDirectoryImportPath(importer, importerFile, dependency)
+	}
+
+	if dependency.Type == TypeArchive {
+		return cfg.resolveArchiveImportPath(importer, importerFile, dependency)
+	}
+
+	if dependency.Type != TypeFile && dependency.Type != TypeFilePattern {
+		return nil
+	}
+
+	if importer != nil && isRemoteType(importer.Type) {
+		return resolveRemoteImportPath(importer, importerFile, dependency)
+	}
+
+	if strings.HasPrefix(dependency.Path, projectRootAnchor) {
+		dependency.Path = filepath.Join(cfg.projectRoot(), strings.TrimPrefix(dependency.Path, projectRootAnchor))
+
+		return nil
+	}
+
+	if filepath.IsAbs(dependency.Path) || importer == nil {
+		return nil
+	}
+
+	if importer.Type == TypeDirectory {
+		dir, _ := splitDirectoryImport(importer.Path)
+		dependency.Path = filepath.Join(dir, dependency.Path)
+
+		return nil
+	}
+
+	if importer.Type != TypeFile && importer.Type != TypeFilePattern {
+		return nil
+	}
+
+	dependency.Path = filepath.Join(filepath.Dir(importer.Path), dependency.Path)
+
+	return nil
+}
+
+// resolveDirectoryImportPath resolves the directory of a directory import the way a file import is, keeping its options.
+func (cfg *Config) resolveDirectoryImportPath(importer *Dependency, importerFile File, dependency *Dependency) error {
+	if importer != nil && isRemoteType(importer.Type) {
+		return &errors.YamllError{Message: fmt.Sprintf("directory import '%s' inside remote file '%s' is not supported", dependency.Path, importer.Path)}
+	}
+
+	dir, query := splitDirectoryImport(dependency.Path)
+
+	local := &Dependency{Path: dir, Type: TypeFile}
+	if err := cfg.resolveImportPath(importer, importerFile, local); err != nil {
+		return err
+	}
+
+	dependency.Path = directoryImport(local.Path, query)
+
+	return nil
+}
+
+// resolveArchiveImportPath resolves a local archive the way a file import is, archives over HTTP are left untouched.
+func (cfg *Config) resolveArchiveImportPath(importer *Dependency, importerFile File, dependency *Dependency) error {
+	archive, member, err := splitArchiveImport(dependency.Path)
+	if err != nil || isRemoteArchive(archive) {
+		return err
+	}
+
+	if importer != nil && isRemoteType(importer.Type) {
+		return &errors.YamllError{Message: fmt.Sprintf(
+			"remote file '%s' cannot import the local archive '%s', archives inside remote files have to be fetched over HTTP",
+			importer.Path, dependency.Path,
+		)}
+	}
+
+	local := &Dependency{Path: archive, Type: TypeFile}
+	if err = cfg.resolveImportPath(importer, importerFile, local); err != nil {
+		return err
+	}
+
+	dependency.Path = archiveImport(filepath.ToSlash(local.Path), member)
+
+	return nil
+}
+
+// resolveLocalGitImportPath resolves the relative path of a local repository, git+file://../config@v1?path=base.yaml,
+// against the directory of the importing file the way relative file imports are.
+func resolveLocalGitImportPath(importer *Dependency, dependency *Dependency) error {
+	meta, err := dependency.getGitMetaData()
+	if err != nil || meta.scheme != "file" || path.IsAbs(meta.repoPath) || importer == nil {
+		return nil //nolint:nilerr
+	}
+
+	if importer.Type != TypeFile && importer.Type != TypeFilePattern {
+		return &errors.YamllError{Message: fmt.Sprintf(
+			"remote file '%s' cannot import the local repository '%s' by a relative path", importer.Path, dependency.Path,
+		)}
+	}
+
+	_, ref, query, err := splitGitImport(dependency.Path)
+	if err != nil {
+		return err
+	}
+
+	repoPath := filepath.ToSlash(filepath.Join(filepath.Dir(importer.Path), filepath.FromSlash(meta.repoPath)))
+	dependency.Path = TypeGit + "file://" + repoPath + "@" + ref + "?" + query
+
+	return nil
+}
+
+// resolveRemoteImportPath turns a relative import found inside a remote file into an import of the same origin:
+// the same repository and commit for git, a sibling layer of the same manifest for OCI and a sibling URL otherwise.
+// Remote files never reach the local filesystem, so absolute paths and paths escaping the origin are rejected.
+func resolveRemoteImportPath(importer *Dependency, importerFile File, dependency *Dependency) error {
+	rawPath := dependency.Path
+
+	if dependency.Type == TypeFilePattern {
+		return &errors.YamllError{Message: fmt.Sprintf("pattern import '%s' inside remote file '%s' is not supported", rawPath, importer.Path)}
+	}
+
+	if strings.HasPrefix(rawPath, "/") && !strings.HasPrefix(rawPath, projectRootAnchor) {
+		return &errors.YamllError{Message: fmt.Sprintf(
+			"remote file '%s' cannot import '%s' from the local filesystem, use a relative or //-anchored path", importer.Path, rawPath,
+		)}
+	}
+
+	var (
+		resolved string
+		err      error
+	)
+
+	switch importer.Type {
+	case TypeGit:
+		resolved, err = gitSiblingImport(importer.Path, importerFile.Meta.GitCommit, rawPath)
+	case TypeOCI:
+		resolved, err = ociSiblingImport(importer.Path, importerFile.Meta.ManifestDigest, rawPath)
+	case TypeArchive:
+		resolved, err = archiveSiblingImport(importer.Path, rawPath)
+	default:
+		// HTTP and registered sources resolve siblings the way URLs do.
+		resolved, err = urlSiblingImport(importer.Path, rawPath)
+	}
+
+	if err != nil {
+		return &errors.YamllError{Message: fmt.Sprintf("resolving import '%s' inside remote file '%s' errored with: '%v'", rawPath, importer.Path, err)}
+	}
+
+	dependency.Path = resolved
+	dependency.IdentifyType()
+
+	if dependency.Auth == nil {
+		dependency.Auth = importer.Auth
+	}
+
+	return nil
+}
+
+// gitSiblingImport resolves an import against the same repository, pinned to the commit the importing file was read from.
+func gitSiblingImport(importerPath, commit, importPath string) (string, error) {
+	meta, err := (&Dependency{Path: importerPath}).getGitMetaData()
+	if err != nil {
+		return "", err
+	}
+
+	filePath, err := siblingPath(meta.path, importPath)
+	if err != nil {
+		return "", err
+	}
+
+	base, _, _ := strings.Cut(importerPath, "?")
+	if commit != "" {
+		base, _, _ = strings.Cut(pinGitImportToCommit(importerPath, commit), "?")
+	}
+
+	return base + "?path=" + filePath, nil
+}
+
+// urlSiblingImport resolves an import against the URL of the importing file.
+func urlSiblingImport(importerPath, importPath string) (string, error) {
+	importerURL, err := url.Parse(importerPath)
+	if err != nil {
+		return "", err
+	}
+
+	filePath, err := siblingPath(importerURL.Path, importPath)
+	if err != nil {
+		return "", err
+	}
+
+	return importerURL.ResolveReference(&url.URL{Path: "/" + filePath}).String(), nil
+}
+
+// ociSiblingImport resolves an import to the layer titled with its path, within the manifest the importing file was read from.
+func ociSiblingImport(importerPath, manifestDigest, importPath string) (string, error) {
+	ref, err := parseOCIReference(importerPath)
+	if err != nil {
+		return "", err
+	}
+
+	layerPath, err := siblingPath(ref.Path, importPath)
+	if err != nil {
+		return "", err
+	}
+
+	return ref.withPath(manifestDigest, layerPath), nil
+}
+
+// siblingPath joins importPath onto the directory of importerPath, both slash separated and relative to the origin root.
+// //-anchored imports start from the origin root, and ".." never climbs above it.
+func siblingPath(importerPath, importPath string) (string, error) {
+	var segments []string
+
+	if !strings.HasPrefix(importPath, projectRootAnchor) {
+		for segment := range strings.SplitSeq(path.Dir(strings.TrimPrefix(importerPath, "/")), "/") {
+			if segment != "" && segment != "." {
+				segments = append(segments, segment)
+			}
+		}
+	}
+
+	for segment := range strings.SplitSeq(strings.TrimPrefix(importPath, projectRootAnchor), "/") {
+		switch segment {
+		case "", ".":
+			continue
+		case "..":
+			if len(segments) == 0 {
+				return "", fmt.Errorf("path '%s' escapes the root of its origin", importPath)
+			}
+
+			segments = segments[:len(segments)-1]
+		default:
+			segments = append(segments, segment)
+		}
+	}
+
+	if len(segments) == 0 {
+		return "", fmt.Errorf("path '%s' does not name a file", importPath)
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// projectRoot returns the directory //-anchored imports resolve against.
+// Unless set explicitly, it is the nearest ancestor of the first root file holding a .git entry,
+// falling back to the directory of that root file.
+func (cfg *Config) projectRoot() string {
+	if cfg.ProjectRoot != "" {
+		return cfg.ProjectRoot
+	}
+
+	if cfg.projectRootDir != "" {
+		return cfg.projectRootDir
+	}
+
+	cfg.projectRootDir = "."
+
+	if len(cfg.Files) == 0 {
+		return cfg.projectRootDir
+	}
+
+	rootDir := filepath.Dir(cfg.Files[0].Path)
+	cfg.projectRootDir = rootDir
+
+	absRootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return cfg.projectRootDir
+	}
+
+	for dir := absRootDir; ; dir = filepath.Dir(dir) {
+		if _, err = os.Stat(filepath.Join(dir, ".git")); err == nil {
+			cfg.projectRootDir = relativeToWorkingDir(dir)
+
+			break
+		}
+
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	return cfg.projectRootDir
+}
+
+// relativeToWorkingDir keeps paths below the working directory relative, so route keys read the way users wrote them.
+func relativeToWorkingDir(dir string) string {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return dir
+	}
+
+	relDir, err := filepath.Rel(workingDir, dir)
+	if err != nil || relDir == ".." || strings.HasPrefix(relDir, ".."+string(filepath.Separator)) {
+		return dir
+	}
+
+	return relDir
+}
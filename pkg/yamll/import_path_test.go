@@ -0,0 +1,74 @@
+package yamll_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigResolveDependenciesRelativeToRemoteURL(t *testing.T) {
+	files := map[string]string{
+		"/libs/base.yaml":   "##++common.yaml\n##++../shared.yaml\nbase: &base\n  <<: *common\n  shared: *shared\n",
+		"/libs/common.yaml": "common: &common\n  origin: remote\n",
+		"/shared.yaml":      "shared: &shared\n  enabled: true\n",
+		"/libs/escape.yaml": "##++../../outside.yaml\n",
+		"/libs/local.yaml":  "##++/etc/hosts\n",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	newConfig := func(remotePath string) *yamll.Config {
+		rootFile := filepath.Join(dir, "root.yaml")
+		require.NoError(t, os.WriteFile(rootFile, []byte("##++"+server.URL+remotePath+"\napp: true\n"), 0o600))
+
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.NoCache = true
+
+		return cfg
+	}
+
+	t.Run("resolves siblings against the importing URL", func(t *testing.T) {
+		cfg := newConfig("/libs/base.yaml")
+
+		routes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+		require.NoError(t, err)
+		require.Contains(t, routes, server.URL+"/libs/common.yaml")
+		require.Contains(t, routes, server.URL+"/shared.yaml")
+
+		out, err := newConfig("/libs/base.yaml").Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "origin: remote")
+		require.Contains(t, string(out), "enabled: true")
+	})
+
+	t.Run("rejects paths escaping the origin", func(t *testing.T) {
+		_, err := newConfig("/libs/escape.yaml").Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "escapes the root of its origin")
+	})
+
+	t.Run("rejects local filesystem paths", func(t *testing.T) {
+		_, err := newConfig("/libs/local.yaml").Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cannot import '/etc/hosts' from the local filesystem")
+	})
+}
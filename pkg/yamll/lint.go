@@ -1,6 +1,7 @@
 package yamll
 
 import (
+	"context"
 	stdErrors "errors"
 	"fmt"
 	"regexp"
@@ -35,11 +36,11 @@ const (
 	duplicateLibraryThreshold = 2
 )
 
-func (cfg *Config) Lint() (LintReport, error) {
+func (cfg *Config) Lint(ctx context.Context) (LintReport, error) {
 	cfg.Root = false
 
 	routes, unresolvedImportMessage := func() (map[string]*YamlData, string) {
-		resolvedRoutes, err := cfg.ResolveDependencies(make(map[string]*YamlData), cfg.Files...)
+		resolvedRoutes, err := cfg.ResolveDependencies(ctx, make(map[string]*YamlData), cfg.Files...)
 		if err != nil {
 			return nil, err.Error()
 		}
@@ -93,47 +94,12 @@ func (cfg *Config) Lint() (LintReport, error) {
 func lintCircularRefs(routes YamlRoutes) []LintIssue {
 	issues := make([]LintIssue, 0)
 
-	visiting := make(map[string]struct{})
-	visited := make(map[string]struct{})
-
-	var dfs func(file string, stack []string)
-
-	dfs = func(file string, stack []string) {
-		if _, exists := visiting[file]; exists {
-			// cycle
-			issues = append(issues, LintIssue{
-				Code:    LintCircularRefs,
-				File:    file,
-				Message: fmt.Sprintf("import cycle detected: %s -> %s", strings.Join(stack, " -> "), file),
-			})
-
-			return
-		}
-
-		if _, exists := visited[file]; exists {
-			return
-		}
-
-		visiting[file] = struct{}{}
-
-		defer delete(visiting, file)
-
-		route := routes[file]
-		if route != nil {
-			for _, dep := range route.Dependency {
-				if dep == nil {
-					continue
-				}
-
-				dfs(dep.Path, append(stack, file))
-			}
-		}
-
-		visited[file] = struct{}{}
-	}
-
-	for _, file := range routes.OrderedFiles() {
-		dfs(file, nil)
+	for _, cycle := range routes.Graph().Cycles() {
+		issues = append(issues, LintIssue{
+			Code:    LintCircularRefs,
+			File:    cycle.Path[0],
+			Message: fmt.Sprintf("import cycle detected: %s", cycle),
+		})
 	}
 
 	return issues
@@ -440,6 +406,7 @@ func lintDuplicateLibraries(routes YamlRoutes) []LintIssue {
 	issues := make([]LintIssue, 0)
 
 	contentSources := make(map[string][]string)
+	seenSources := make(map[string]struct{})
 
 	for _, file := range routes.OrderedFiles() {
 		route := routes[file]
@@ -452,6 +419,12 @@ func lintDuplicateLibraries(routes YamlRoutes) []LintIssue {
 				continue
 			}
 
+			// The same file reached through two spellings, or through a pattern and a direct import, is not a duplicate.
+			if _, seen := seenSources[dependencyID(src.Name)]; seen {
+				continue
+			}
+
+			seenSources[dependencyID(src.Name)] = struct{}{}
 			contentSources[src.Meta.SHA256] = append(contentSources[src.Meta.SHA256], src.Name)
 		}
 	}
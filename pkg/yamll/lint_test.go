@@ -24,7 +24,7 @@ func TestConfig_Lint(t *testing.T) {
 		cfg := yamll.New(false, "INFO", "---", root)
 		cfg.SetLogger()
 
-		report, err := cfg.Lint()
+		report, err := cfg.Lint(t.Context())
 		require.NoError(t, err)
 
 		require.NotEmpty(t, report.Issues)
@@ -40,7 +40,7 @@ func TestConfig_Lint(t *testing.T) {
 		cfg := yamll.New(false, "INFO", "---", root)
 		cfg.SetLogger()
 
-		report, err := cfg.Lint()
+		report, err := cfg.Lint(t.Context())
 		require.NoError(t, err)
 		require.Contains(t, codes(report.Issues), yamll.LintInvalidAnchors)
 	})
@@ -57,7 +57,7 @@ func TestConfig_Lint(t *testing.T) {
 		cfg := yamll.New(false, "INFO", "---", root)
 		cfg.SetLogger()
 
-		report, err := cfg.Lint()
+		report, err := cfg.Lint(t.Context())
 		require.NoError(t, err)
 		require.Contains(t, codes(report.Issues), yamll.LintConflictingMerges)
 	})
@@ -71,7 +71,7 @@ func TestConfig_Lint(t *testing.T) {
 		cfg := yamll.New(false, "INFO", "---", root)
 		cfg.SetLogger()
 
-		report, err := cfg.Lint()
+		report, err := cfg.Lint(t.Context())
 		require.NoError(t, err)
 		require.Contains(t, codes(report.Issues), yamll.LintDuplicateKeys)
 	})
@@ -90,7 +90,7 @@ func TestConfig_Lint(t *testing.T) {
 		cfg := yamll.New(false, "INFO", "---", root)
 		cfg.SetLogger()
 
-		report, err := cfg.Lint()
+		report, err := cfg.Lint(t.Context())
 		require.NoError(t, err)
 		require.Contains(t, codes(report.Issues), yamll.LintCircularRefs)
 	})
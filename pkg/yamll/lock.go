@@ -1,11 +1,11 @@
 package yamll
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -29,8 +29,13 @@ type LockEntry struct {
 	Constraint  string `yaml:"constraint,omitempty"`
 	Resolved    string `yaml:"resolved,omitempty"`
 	GitCommit   string `yaml:"git_commit,omitempty"`
+	Version     string `yaml:"version,omitempty"`
 	SHA256      string `yaml:"sha256,omitempty"`
 	PatternFile string `yaml:"pattern_file,omitempty"`
+	// ArchiveSHA256 is the digest of the whole archive an archive import was read from, SHA256 being the one of its file.
+	ArchiveSHA256 string `yaml:"archive_sha256,omitempty"`
+	// ManifestDigest is the manifest an OCI import resolved to, later runs read oci://repo@<digest> instead of the tag.
+	ManifestDigest string `yaml:"manifest_digest,omitempty"`
 }
 
 type LockVerifyReport struct {
@@ -44,7 +49,7 @@ type LockExplainReport struct {
 	Roots  []string
 }
 
-func (cfg *Config) Lock() ([]byte, error) {
+func (cfg *Config) Lock(ctx context.Context) ([]byte, error) {
 	cfg.Root = false
 
 	previousNoLock := cfg.NoLock
@@ -54,7 +59,17 @@ func (cfg *Config) Lock() ([]byte, error) {
 		cfg.NoLock = previousNoLock
 	}()
 
-	routes, err := cfg.ResolveDependencies(make(map[string]*YamlData), cfg.Files...)
+	entries, err := cfg.resolveLockEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.marshalLock(entries)
+}
+
+// resolveLockEntries resolves the whole import graph and returns the lock entries of what it resolved, in source order.
+func (cfg *Config) resolveLockEntries(ctx context.Context) ([]LockEntry, error) {
+	routes, err := cfg.ResolveDependencies(ctx, make(map[string]*YamlData), cfg.Files...)
 	if err != nil {
 		return nil, &errors.YamllError{Message: fmt.Sprintf("fetching dependency tree errored with: '%v'", err)}
 	}
@@ -68,15 +83,12 @@ func (cfg *Config) Lock() ([]byte, error) {
 		}
 	}
 
-	// Deterministic ordering.
-	sort.SliceStable(entries, func(i, j int) bool {
-		if entries[i].Source != entries[j].Source {
-			return entries[i].Source < entries[j].Source
-		}
+	sortLockEntries(entries)
 
-		return entries[i].PatternFile < entries[j].PatternFile
-	})
+	return entries, nil
+}
 
+func (cfg *Config) marshalLock(entries []LockEntry) ([]byte, error) {
 	lock := LockFile{
 		Version:     lockVersion,
 		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
@@ -92,7 +104,18 @@ func (cfg *Config) Lock() ([]byte, error) {
 	return out, nil
 }
 
-func (cfg *Config) LockVerify() (LockVerifyReport, error) {
+// sortLockEntries orders the entries deterministically, by source and then pattern file.
+func sortLockEntries(entries []LockEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Source != entries[j].Source {
+			return entries[i].Source < entries[j].Source
+		}
+
+		return entries[i].PatternFile < entries[j].PatternFile
+	})
+}
+
+func (cfg *Config) LockVerify(ctx context.Context) (LockVerifyReport, error) {
 	if cfg.LockFile == "" {
 		return LockVerifyReport{}, &errors.YamllError{Message: "lock file path cannot be empty"}
 	}
@@ -115,7 +138,7 @@ func (cfg *Config) LockVerify() (LockVerifyReport, error) {
 
 	cfg.Root = false
 
-	routes, err := cfg.ResolveDependencies(make(map[string]*YamlData), cfg.Files...)
+	routes, err := cfg.ResolveDependencies(ctx, make(map[string]*YamlData), cfg.Files...)
 	if err != nil {
 		return LockVerifyReport{}, &errors.YamllError{Message: fmt.Sprintf("verifying lock file errored with: '%v'", err)}
 	}
@@ -127,7 +150,7 @@ func (cfg *Config) LockVerify() (LockVerifyReport, error) {
 	}, nil
 }
 
-func (cfg *Config) LockExplain(target string) (LockExplainReport, error) {
+func (cfg *Config) LockExplain(ctx context.Context, target string) (LockExplainReport, error) {
 	target = strings.TrimSpace(target)
 	if target == "" {
 		return LockExplainReport{}, &errors.YamllError{Message: "lock explain requires a dependency source"}
@@ -140,7 +163,7 @@ func (cfg *Config) LockExplain(target string) (LockExplainReport, error) {
 			continue
 		}
 
-		routes, err := cfg.resolveSingleRootWithoutLock(root)
+		routes, err := cfg.resolveSingleRootWithoutLock(ctx, root)
 		if err != nil {
 			return LockExplainReport{}, err
 		}
@@ -155,14 +178,14 @@ func (cfg *Config) LockExplain(target string) (LockExplainReport, error) {
 	return LockExplainReport{Target: target, Roots: roots}, nil
 }
 
-func (cfg *Config) resolveSingleRootWithoutLock(root *Dependency) (map[string]*YamlData, error) {
+func (cfg *Config) resolveSingleRootWithoutLock(ctx context.Context, root *Dependency) (map[string]*YamlData, error) {
 	rootCopy := *root
 	resolveCfg := *cfg
 	resolveCfg.Root = false
 	resolveCfg.NoLock = true
 	resolveCfg.Files = []*Dependency{&rootCopy}
 
-	routes, err := resolveCfg.ResolveDependencies(make(map[string]*YamlData), &rootCopy)
+	routes, err := resolveCfg.ResolveDependencies(ctx, make(map[string]*YamlData), &rootCopy)
 	if err != nil {
 		return nil, &errors.YamllError{Message: fmt.Sprintf("resolving root %s errored with: '%v'", root.Path, err)}
 	}
@@ -195,11 +218,7 @@ func lockPathMatches(path, target string) bool {
 		return true
 	}
 
-	if strings.Contains(path, "://") || strings.Contains(target, "://") {
-		return false
-	}
-
-	return filepath.Clean(path) == filepath.Clean(target)
+	return dependencyID(path) == dependencyID(target)
 }
 
 func (r LockVerifyReport) String() string {
@@ -241,8 +260,10 @@ func (cfg *Config) rootPaths() []string {
 
 func lockEntryFromSource(source string, file File) LockEntry {
 	entry := LockEntry{
-		Source: source,
-		SHA256: file.Meta.SHA256,
+		Source:        source,
+		SHA256:        file.Meta.SHA256,
+		Version:       file.Meta.Version,
+		ArchiveSHA256: file.Meta.ArchiveSHA256,
 	}
 
 	switch {
@@ -250,20 +271,22 @@ func lockEntryFromSource(source string, file File) LockEntry {
 		entry.Type = TypeGit
 		entry.Constraint = gitConstraintFromSource(source)
 		entry.GitCommit = file.Meta.GitCommit
-		entry.Resolved = file.Name
+		entry.Resolved = pinGitImportToCommit(file.Name, file.Meta.GitCommit)
+	case file.Meta.ManifestDigest != "":
+		entry.Type = TypeOCI
+		entry.Constraint = ociConstraintFromSource(source)
+		entry.ManifestDigest = file.Meta.ManifestDigest
+		entry.Resolved = pinOCIImportToDigest(file.Name, file.Meta.ManifestDigest)
 	case isPattern(source):
 		entry.Type = TypeFilePattern
 		entry.Resolved = file.Name
-		entry.PatternFile = file.Name
 	default:
-		// Heuristic: Name is URL if it begins with http(s).
-		if len(file.Name) >= 4 && (file.Name[:4] == "http") {
-			entry.Type = TypeURL
-			entry.Resolved = file.Name
-		} else {
-			entry.Type = TypeFile
-			entry.Resolved = file.Name
-		}
+		entry.Type = dependencyType(source)
+		entry.Resolved = file.Name
+	}
+
+	if isPatternImport(source) {
+		entry.PatternFile = file.Name
 	}
 
 	if entry.SHA256 == "" {
@@ -281,18 +304,24 @@ func gitConstraintFromSource(source string) string {
 		return ""
 	}
 
-	withoutPrefix := strings.TrimPrefix(source, TypeGit)
-
-	_, afterAt, found := strings.Cut(withoutPrefix, "@")
-	if !found {
+	_, ref, _, err := splitGitImport(source)
+	if err != nil {
 		return ""
 	}
 
-	ref, _, _ := strings.Cut(afterAt, "?")
-
 	return ref
 }
 
+// ociConstraintFromSource is the tag of an OCI import, empty when it already references a digest.
+func ociConstraintFromSource(source string) string {
+	ref, err := parseOCIReference(source)
+	if err != nil || ref.isDigest() {
+		return ""
+	}
+
+	return ref.Reference
+}
+
 func lockEntryKey(source, patternFile string) string {
 	if patternFile == "" {
 		return source
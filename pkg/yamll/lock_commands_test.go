@@ -4,7 +4,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/goccy/go-yaml"
 	"github.com/nikhilsbhat/yamll/pkg/yamll"
 	"github.com/stretchr/testify/require"
 )
@@ -22,7 +26,7 @@ func TestConfigLockVerify(t *testing.T) {
 	cfg.SetLogger()
 	cfg.LockFile = lockFile
 
-	lockData, err := cfg.Lock()
+	lockData, err := cfg.Lock(t.Context())
 	require.NoError(t, err)
 	require.NoError(t, os.WriteFile(lockFile, lockData, 0o600))
 
@@ -31,7 +35,7 @@ func TestConfigLockVerify(t *testing.T) {
 	verifyCfg.LockFile = lockFile
 	verifyCfg.NoLock = true
 
-	report, err := verifyCfg.LockVerify()
+	report, err := verifyCfg.LockVerify(t.Context())
 	require.NoError(t, err)
 	require.Equal(t, []string{rootFile}, report.Roots)
 	require.Positive(t, report.LockEntriesLoaded)
@@ -54,12 +58,282 @@ func TestConfigLockExplain(t *testing.T) {
 	cfg := yamll.New(false, "DEBUG", "", appRoot, jobsRoot)
 	cfg.SetLogger()
 
-	sharedReport, err := cfg.LockExplain(sharedFile)
+	sharedReport, err := cfg.LockExplain(t.Context(), sharedFile)
 	require.NoError(t, err)
 	require.Equal(t, []string{appRoot, jobsRoot}, sharedReport.Roots)
 
-	jobsReport, err := cfg.LockExplain(jobsOnlyFile)
+	jobsReport, err := cfg.LockExplain(t.Context(), jobsOnlyFile)
 	require.NoError(t, err)
 	require.Equal(t, []string{jobsRoot}, jobsReport.Roots)
 	require.Contains(t, jobsReport.String(), "Pulled by roots:")
 }
+
+// tagGitRelease commits the file to the repository made by newGitRepo and tags the commit, returning its hash.
+func tagGitRelease(t *testing.T, repoDir, name, content, tag string) string {
+	t.Helper()
+
+	repo, err := git.PlainOpen(repoDir)
+	require.NoError(t, err)
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0o600))
+
+	_, err = worktree.Add(name)
+	require.NoError(t, err)
+
+	commit, err := worktree.Commit("release "+tag, &git.CommitOptions{
+		Author: &object.Signature{Name: "yamll", Email: "yamll@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	_, err = repo.CreateTag(tag, commit, nil)
+	require.NoError(t, err)
+
+	return commit.String()
+}
+
+func TestConfigLockOutdatedAndUpdate(t *testing.T) {
+	networkRepo, networkV1 := newGitRepo(t, map[string]string{"network.yaml": "network: &network\n  release: v1.0.0\n"})
+	storageRepo, storageV1 := newGitRepo(t, map[string]string{"storage.yaml": "storage: &storage\n  release: v1.0.0\n"})
+
+	dir := t.TempDir()
+	rootFile := filepath.Join(dir, "root.yaml")
+	localFile := filepath.Join(dir, "local.yaml")
+	lockFile := filepath.Join(dir, "yamll.lock")
+
+	networkSource := "git+file://" + filepath.ToSlash(networkRepo) + "@^1.0?path=network.yaml"
+	storageSource := "git+file://" + filepath.ToSlash(storageRepo) + "@^1.0?path=storage.yaml"
+
+	require.NoError(t, os.WriteFile(localFile, []byte("local: &local\n  replicas: 1\n"), 0o600))
+	require.NoError(t, os.WriteFile(rootFile, []byte(
+		"##++"+networkSource+"\n##++"+storageSource+"\n##++"+localFile+"\napp:\n  network: *network\n  storage: *storage\n  local: *local\n",
+	), 0o600))
+
+	newCfg := func() *yamll.Config {
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoCache = true
+		cfg.LockFile = lockFile
+
+		return cfg
+	}
+
+	lockData, err := newCfg().Lock(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(lockFile, lockData, 0o600))
+
+	report, err := newCfg().LockOutdated(t.Context())
+	require.NoError(t, err)
+	require.Empty(t, report.Entries)
+	require.Equal(t, "All locked imports are up to date\n", report.String())
+
+	networkV2 := tagGitRelease(t, networkRepo, "network.yaml", "network: &network\n  release: v1.1.0\n", "v1.1.0")
+	tagGitRelease(t, networkRepo, "network.yaml", "network: &network\n  release: v2.0.0\n", "v2.0.0")
+	storageV2 := tagGitRelease(t, storageRepo, "storage.yaml", "storage: &storage\n  release: v1.0.1\n", "v1.0.1")
+	require.NoError(t, os.WriteFile(localFile, []byte("local: &local\n  replicas: 2\n"), 0o600))
+
+	report, err = newCfg().LockOutdated(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []yamll.LockOutdatedEntry{
+		{Source: networkSource, Type: yamll.TypeGit, Constraint: "^1.0", Current: "v1.0.0 (" + networkV1[:12] + ")", Latest: "v1.1.0 (" + networkV2[:12] + ")"},
+		{Source: storageSource, Type: yamll.TypeGit, Constraint: "^1.0", Current: "v1.0.0 (" + storageV1[:12] + ")", Latest: "v1.0.1 (" + storageV2[:12] + ")"},
+	}, report.Entries)
+	require.Contains(t, report.String(), "2 outdated")
+
+	before := readLockEntries(t, lockFile)
+
+	lockData, updateReport, err := newCfg().LockUpdate(t.Context(), networkSource)
+	require.NoError(t, err)
+	require.Len(t, updateReport.Entries, 1)
+	require.Equal(t, networkSource, updateReport.Entries[0].Source)
+	require.Contains(t, updateReport.String(), "v1.0.0 ("+networkV1[:12]+") -> v1.1.0 ("+networkV2[:12]+")")
+	require.NoError(t, os.WriteFile(lockFile, lockData, 0o600))
+
+	after := readLockEntries(t, lockFile)
+	require.Equal(t, networkV2, after[networkSource].GitCommit)
+	require.Equal(t, "v1.1.0", after[networkSource].Version)
+	require.Equal(t, before[storageSource], after[storageSource])
+	require.NotEqual(t, before[localFile].SHA256, after[localFile].SHA256)
+
+	out, err := newCfg().Yaml(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, string(out), "release: v1.1.0")
+	require.Contains(t, string(out), "release: v1.0.0")
+
+	t.Run("unknown source", func(t *testing.T) {
+		_, _, err := newCfg().LockUpdate(t.Context(), "git+file:///no/repo@v1?path=a.yaml")
+		require.ErrorContains(t, err, "is not in the lock file")
+	})
+
+	t.Run("every import", func(t *testing.T) {
+		lockData, updateReport, err := newCfg().LockUpdate(t.Context())
+		require.NoError(t, err)
+		require.Len(t, updateReport.Entries, 1)
+		require.Equal(t, storageSource, updateReport.Entries[0].Source)
+		require.Contains(t, string(lockData), "git_commit: "+storageV2)
+	})
+
+	t.Run("missing lock file", func(t *testing.T) {
+		cfg := newCfg()
+		cfg.LockFile = filepath.Join(dir, "missing.lock")
+
+		_, err := cfg.LockOutdated(t.Context())
+		require.ErrorContains(t, err, "lock outdated requires the lock file")
+	})
+}
+
+func readLockEntries(t *testing.T, lockFile string) map[string]yamll.LockEntry {
+	t.Helper()
+
+	data, err := os.ReadFile(lockFile)
+	require.NoError(t, err)
+
+	var lock yamll.LockFile
+	require.NoError(t, yaml.Unmarshal(data, &lock))
+
+	entries := make(map[string]yamll.LockEntry, len(lock.Entries))
+	for _, entry := range lock.Entries {
+		entries[entry.Source] = entry
+	}
+
+	return entries
+}
+
+func TestDiffLockFiles(t *testing.T) {
+	oldLock := `version: v2
+entries:
+  - type: git+
+    source: git+https://github.com/company/lib@^1.2?path=base.yaml
+    constraint: ^1.2
+    git_commit: 1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a
+    version: v1.2.0
+    sha256: aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+  - type: oci://
+    source: oci://ghcr.io/company/bundle:v1?path=base.yaml
+    constraint: v1
+    manifest_digest: sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb
+    sha256: cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc
+  - type: http
+    source: https://config.example.com/base.yaml
+    sha256: dddddddddddddddddddddddddddddddddddddddddddddddddddddddddddddddd
+  - type: pattern
+    source: envs/*.yaml
+    pattern_file: envs/dev.yaml
+    sha256: eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee
+  - type: pattern
+    source: envs/*.yaml
+    pattern_file: envs/qa.yaml
+    sha256: ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff
+  - type: file
+    source: unchanged.yaml
+    sha256: 0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f
+`
+	newLock := `version: v2
+entries:
+  - type: git+
+    source: git+https://github.com/company/lib@^1.2?path=base.yaml
+    constraint: ^1.2
+    git_commit: 2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b
+    version: v1.3.0
+    sha256: abababababababababababababababababababababababababababababababab
+  - type: oci://
+    source: oci://ghcr.io/company/bundle:v1?path=base.yaml
+    constraint: v1
+    manifest_digest: sha256:9999999999999999999999999999999999999999999999999999999999999999
+    sha256: cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc
+  - type: http
+    source: https://config.example.com/base.yaml
+    sha256: 8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e8e
+  - type: pattern
+    source: envs/*.yaml
+    pattern_file: envs/dev.yaml
+    sha256: eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee
+  - type: pattern
+    source: envs/*.yaml
+    pattern_file: envs/prod.yaml
+    sha256: 7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d7d
+  - type: git+
+    source: git+https://github.com/company/other@>=2,<3||^4?path=a.yaml
+    constraint: ">=2,<3||^4"
+    git_commit: 3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c
+    version: v2.1.0
+    sha256: 6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f
+  - type: file
+    source: unchanged.yaml
+    sha256: 0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f0f
+`
+
+	report, err := yamll.DiffLockFiles([]byte(oldLock), []byte(newLock))
+	require.NoError(t, err)
+	require.Equal(t, []yamll.LockDiffEntry{
+		{Change: yamll.LockDiffAdded, Source: "envs/*.yaml", PatternFile: "envs/prod.yaml", Type: "pattern", Details: []string{"sha256 7d7d7d7d7d7d"}},
+		{
+			Change: yamll.LockDiffAdded, Source: "git+https://github.com/company/other@>=2,<3||^4?path=a.yaml", Type: yamll.TypeGit,
+			Details: []string{"v2.1.0 (3c3c3c3c3c3c)"},
+		},
+		{Change: yamll.LockDiffRemoved, Source: "envs/*.yaml", PatternFile: "envs/qa.yaml", Type: "pattern", Details: []string{"sha256 ffffffffffff"}},
+		{
+			Change: yamll.LockDiffChanged, Source: "git+https://github.com/company/lib@^1.2?path=base.yaml", Type: yamll.TypeGit,
+			Details: []string{"version v1.2.0 -> v1.3.0", "commits 1a1a1a1a1a1a..2b2b2b2b2b2b", "sha256 aaaaaaaaaaaa -> abababababab"},
+		},
+		{Change: yamll.LockDiffChanged, Source: "https://config.example.com/base.yaml", Type: "http", Details: []string{"sha256 dddddddddddd -> 8e8e8e8e8e8e"}},
+		{
+			Change: yamll.LockDiffChanged, Source: "oci://ghcr.io/company/bundle:v1?path=base.yaml", Type: yamll.TypeOCI,
+			Details: []string{"manifest sha256:bbbbbbbbbbbb -> sha256:999999999999"},
+		},
+	}, report.Entries)
+
+	require.Contains(t, report.String(), "+ envs/*.yaml (envs/prod.yaml)\n    sha256 7d7d7d7d7d7d\n")
+	require.Contains(t, report.String(),
+		"~ git+https://github.com/company/lib@^1.2?path=base.yaml\n    version v1.2.0 -> v1.3.0\n    commits 1a1a1a1a1a1a..2b2b2b2b2b2b\n")
+	require.Contains(t, report.String(), "2 added, 1 removed, 3 changed")
+
+	markdown := report.Markdown()
+	require.Contains(t, markdown, "**yamll.lock**: 2 added, 1 removed, 3 changed\n\n| Change | Dependency | Details |\n| --- | --- | --- |\n")
+	require.Contains(t, markdown, "| added | `git+https://github.com/company/other@>=2,<3\\|\\|^4?path=a.yaml` | v2.1.0 (3c3c3c3c3c3c) |\n")
+	require.Contains(t, markdown, "| changed | `git+https://github.com/company/lib@^1.2?path=base.yaml` | "+
+		"version v1.2.0 -> v1.3.0<br>commits 1a1a1a1a1a1a..2b2b2b2b2b2b<br>sha256 aaaaaaaaaaaa -> abababababab |\n")
+
+	t.Run("no changes", func(t *testing.T) {
+		report, err := yamll.DiffLockFiles([]byte(oldLock), []byte(oldLock))
+		require.NoError(t, err)
+		require.Empty(t, report.Entries)
+		require.Equal(t, "Lock files have no dependency changes\n", report.String())
+	})
+
+	t.Run("invalid lock file", func(t *testing.T) {
+		_, err := yamll.DiffLockFiles([]byte(oldLock), []byte("entries: {source: [\n"))
+		require.ErrorContains(t, err, "reading new lock file errored with")
+	})
+}
+
+func TestReadLockFileAtGitRef(t *testing.T) {
+	repoDir, _ := newGitRepo(t, map[string]string{"config/yamll.lock": "version: v2\nentries:\n  - type: file\n    source: base.yaml\n    sha256: aaaa\n"})
+	lockFile := filepath.Join(repoDir, "config", "yamll.lock")
+
+	require.NoError(t, os.WriteFile(lockFile, []byte("version: v2\nentries:\n  - type: file\n    source: base.yaml\n    sha256: bbbb\n"), 0o600))
+
+	oldData, err := yamll.ReadLockFileAtGitRef(lockFile, "main")
+	require.NoError(t, err)
+	require.Contains(t, string(oldData), "sha256: aaaa")
+
+	newData, err := os.ReadFile(lockFile)
+	require.NoError(t, err)
+
+	report, err := yamll.DiffLockFiles(oldData, newData)
+	require.NoError(t, err)
+	require.Equal(t, []string{"sha256 aaaa -> bbbb"}, report.Entries[0].Details)
+
+	t.Run("lock file new since the ref", func(t *testing.T) {
+		data, err := yamll.ReadLockFileAtGitRef(filepath.Join(repoDir, "new.lock"), "v1.0.0")
+		require.NoError(t, err)
+		require.Empty(t, data)
+	})
+
+	t.Run("unknown ref", func(t *testing.T) {
+		_, err := yamll.ReadLockFileAtGitRef(lockFile, "no-such-branch")
+		require.ErrorContains(t, err, "resolving git ref 'no-such-branch'")
+	})
+}
@@ -0,0 +1,312 @@
+package yamll
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/goccy/go-yaml"
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+// The changes of a dependency LockDiffEntry reports.
+const (
+	LockDiffAdded   = "added"
+	LockDiffRemoved = "removed"
+	LockDiffChanged = "changed"
+)
+
+// LockDiffEntry is a dependency added to, removed from or changed in the lock file,
+// Details spelling what changed: the commit range of git imports, the digests of OCI and HTTP ones.
+type LockDiffEntry struct {
+	Change      string
+	Source      string
+	PatternFile string
+	Type        string
+	Details     []string
+}
+
+type LockDiffReport struct {
+	Entries []LockDiffEntry
+}
+
+// DiffLockFiles pairs the entries of the old and new lock files by source and pattern file,
+// and reports the dependencies added, removed and changed between them.
+func DiffLockFiles(oldData, newData []byte) (LockDiffReport, error) {
+	oldEntries, err := lockDiffEntries(oldData, "old")
+	if err != nil {
+		return LockDiffReport{}, err
+	}
+
+	newEntries, err := lockDiffEntries(newData, "new")
+	if err != nil {
+		return LockDiffReport{}, err
+	}
+
+	var report LockDiffReport
+
+	for key, oldEntry := range oldEntries {
+		newEntry, ok := newEntries[key]
+		if !ok {
+			report.Entries = append(report.Entries, newLockDiffEntry(LockDiffRemoved, oldEntry, describeLockEntry(oldEntry)))
+
+			continue
+		}
+
+		if details := lockEntryChanges(oldEntry, newEntry); len(details) != 0 {
+			report.Entries = append(report.Entries, newLockDiffEntry(LockDiffChanged, newEntry, details...))
+		}
+	}
+
+	for key, newEntry := range newEntries {
+		if _, ok := oldEntries[key]; !ok {
+			report.Entries = append(report.Entries, newLockDiffEntry(LockDiffAdded, newEntry, describeLockEntry(newEntry)))
+		}
+	}
+
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		left, right := report.Entries[i], report.Entries[j]
+		if left.Change != right.Change {
+			return lockDiffOrder(left.Change) < lockDiffOrder(right.Change)
+		}
+
+		if left.Source != right.Source {
+			return left.Source < right.Source
+		}
+
+		return left.PatternFile < right.PatternFile
+	})
+
+	return report, nil
+}
+
+// ReadLockFileAtGitRef reads the lock file as committed at the ref, main or HEAD~1, of the git repository the lock file is in.
+// A lock file missing at the ref reads as an empty one.
+func ReadLockFileAtGitRef(lockPath, ref string) ([]byte, error) {
+	absPath, err := filepath.Abs(lockPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := git.PlainOpenWithOptions(filepath.Dir(absPath), &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("opening git repository of lock file '%s' errored with: '%v'", lockPath, err)}
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("opening git repository of lock file '%s' errored with: '%v'", lockPath, err)}
+	}
+
+	root := worktree.Filesystem.Root()
+	if resolvedRoot, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolvedRoot
+	}
+
+	if resolvedDir, err := filepath.EvalSymlinks(filepath.Dir(absPath)); err == nil {
+		absPath = filepath.Join(resolvedDir, filepath.Base(absPath))
+	}
+
+	relPath, err := filepath.Rel(root, absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("resolving git ref '%s' errored with: '%v'", ref, err)}
+	}
+
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := commit.File(filepath.ToSlash(relPath))
+	if stdErrors.Is(err, object.ErrFileNotFound) {
+		// The lock file is new, every dependency of it is added.
+		return nil, nil //nolint:nilnil
+	}
+
+	if err != nil {
+		return nil, &errors.YamllError{Message: fmt.Sprintf(
+			"reading lock file '%s' at git ref '%s' errored with: '%v'", filepath.ToSlash(relPath), ref, err,
+		)}
+	}
+
+	content, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(content), nil
+}
+
+func lockDiffEntries(data []byte, which string) (map[string]LockEntry, error) {
+	var lock LockFile
+
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("reading %s lock file errored with: '%v'", which, err)}
+	}
+
+	entries := make(map[string]LockEntry, len(lock.Entries))
+
+	for _, entry := range lock.Entries {
+		if entry.Source != "" {
+			entries[lockEntryKey(entry.Source, entry.PatternFile)] = entry
+		}
+	}
+
+	return entries, nil
+}
+
+func newLockDiffEntry(change string, entry LockEntry, details ...string) LockDiffEntry {
+	return LockDiffEntry{Change: change, Source: entry.Source, PatternFile: entry.PatternFile, Type: entry.Type, Details: details}
+}
+
+// lockEntryChanges spells what changed between the entries of a dependency, none when nothing did.
+func lockEntryChanges(oldEntry, newEntry LockEntry) []string {
+	var details []string
+
+	changed := func(name, oldValue, newValue string) {
+		if oldValue != newValue {
+			details = append(details, fmt.Sprintf("%s %s -> %s", name, orNone(oldValue), orNone(newValue)))
+		}
+	}
+
+	changed("type", oldEntry.Type, newEntry.Type)
+	changed("constraint", oldEntry.Constraint, newEntry.Constraint)
+	changed("version", oldEntry.Version, newEntry.Version)
+
+	// Reviewers compare the commit range with git log or the compare view of the repository.
+	if oldEntry.GitCommit != newEntry.GitCommit {
+		details = append(details, fmt.Sprintf("commits %s..%s", orNone(shortPin(oldEntry.GitCommit)), orNone(shortPin(newEntry.GitCommit))))
+	}
+
+	changed("manifest", shortPin(oldEntry.ManifestDigest), shortPin(newEntry.ManifestDigest))
+	changed("archive sha256", shortPin(oldEntry.ArchiveSHA256), shortPin(newEntry.ArchiveSHA256))
+	changed("sha256", shortPin(oldEntry.SHA256), shortPin(newEntry.SHA256))
+
+	if len(details) == 0 {
+		changed("resolved", oldEntry.Resolved, newEntry.Resolved)
+	}
+
+	return details
+}
+
+// describeLockEntry spells what an added or removed entry is locked to.
+func describeLockEntry(entry LockEntry) string {
+	if isPinnedLockEntry(entry) {
+		return describeLockPin(entry)
+	}
+
+	return "sha256 " + shortPin(entry.SHA256)
+}
+
+// shortPin shortens a commit, checksum or algorithm:digest the way reports show them.
+func shortPin(pin string) string {
+	if algorithm, digest, found := strings.Cut(pin, ":"); found {
+		return algorithm + ":" + digest[:min(len(digest), shortPinLength)]
+	}
+
+	return pin[:min(len(pin), shortPinLength)]
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+
+	return value
+}
+
+func lockDiffOrder(change string) int {
+	switch change {
+	case LockDiffAdded:
+		return 0
+	case LockDiffRemoved:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (entry LockDiffEntry) name() string {
+	if entry.PatternFile == "" {
+		return entry.Source
+	}
+
+	return entry.Source + " (" + entry.PatternFile + ")"
+}
+
+func (r LockDiffReport) counts() (int, int, int) {
+	var added, removed, changed int
+
+	for _, entry := range r.Entries {
+		switch entry.Change {
+		case LockDiffAdded:
+			added++
+		case LockDiffRemoved:
+			removed++
+		default:
+			changed++
+		}
+	}
+
+	return added, removed, changed
+}
+
+func (r LockDiffReport) String() string {
+	if len(r.Entries) == 0 {
+		return "Lock files have no dependency changes\n"
+	}
+
+	markers := map[string]string{LockDiffAdded: "+", LockDiffRemoved: "-", LockDiffChanged: "~"}
+	lines := make([]string, 0, len(r.Entries)+1)
+
+	for _, entry := range r.Entries {
+		lines = append(lines, markers[entry.Change]+" "+entry.name())
+
+		for _, detail := range entry.Details {
+			lines = append(lines, "    "+detail)
+		}
+	}
+
+	added, removed, changed := r.counts()
+	lines = append(lines, "", fmt.Sprintf("%d added, %d removed, %d changed", added, removed, changed))
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Markdown renders the report as a table, for comments on pull requests changing the lock file.
+func (r LockDiffReport) Markdown() string {
+	if len(r.Entries) == 0 {
+		return "**yamll.lock**: no dependency changes\n"
+	}
+
+	added, removed, changed := r.counts()
+	lines := []string{
+		fmt.Sprintf("**yamll.lock**: %d added, %d removed, %d changed", added, removed, changed),
+		"",
+		"| Change | Dependency | Details |",
+		"| --- | --- | --- |",
+	}
+
+	cell := strings.NewReplacer("|", `\|`, "\n", " ").Replace
+
+	for _, entry := range r.Entries {
+		details := make([]string, 0, len(entry.Details))
+		for _, detail := range entry.Details {
+			details = append(details, cell(detail))
+		}
+
+		lines = append(lines, fmt.Sprintf("| %s | `%s` | %s |", entry.Change, cell(entry.name()), strings.Join(details, "<br>")))
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -33,3 +33,26 @@ func TestGetGitMetaDataDoesNotMutateDependencyPath(t *testing.T) {
 	require.Equal(t, "base.yaml", meta.path)
 	require.Equal(t, "git+https://example.com/org/repo@main?path=base.yaml", dependency.Path)
 }
+
+func TestVendorRelPath(t *testing.T) {
+	tests := map[string]string{
+		"git+https://github.com/org/repo@v1.2.0?path=libs/base.yaml":  "git/github.com/org/repo/v1.2.0/libs/base.yaml",
+		"git+ssh://git@github.com:org/repo.git@main?path=base.yaml":   "git/github.com/org/repo/main/base.yaml",
+		"git+ssh://deploy@git.internal:2222/org/repo@v1?path=a.yaml":  "git/git.internal_2222/org/repo/v1/a.yaml",
+		"git+https://github.com/org/repo@>=2,<3?path=base.yaml":       "git/github.com/org/repo/semver-" + checksumForContent(">=2,<3")[:12] + "/base.yaml",
+		"oci://ghcr.io/company/platform-config:v1":                    "oci/ghcr.io/company/platform-config/v1.yaml",
+		"https://config.example.com/team/base.yaml":                   "http/config.example.com/team/base.yaml",
+		"http://localhost:3000/database":                              "http/localhost_3000/database",
+		"https://artifacts.internal/config-1.4.tar.gz?path=base.yaml": "archive/artifacts.internal/config-1.4.tar.gz/base.yaml",
+		"/srv/bundles/lib.zip?path=envs/dev.yaml":                     "archive/srv/bundles/lib.zip/envs/dev.yaml",
+	}
+
+	for source, expected := range tests {
+		relPath, err := vendorRelPath(source)
+		require.NoError(t, err, source)
+		require.Equal(t, expected, relPath, source)
+	}
+
+	_, err := vendorRelPath("internal/fixtures/base.yaml")
+	require.Error(t, err)
+}
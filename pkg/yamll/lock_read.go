@@ -15,7 +15,7 @@ func (cfg *Config) loadLockEntries() (map[string]LockEntry, error) {
 		return nil, nil
 	}
 
-	data, err := os.ReadFile(cfg.LockFile)
+	lock, err := readLockFile(cfg.LockFile)
 	if err != nil {
 		// Lock file is optional unless user runs `yamll lock`.
 		if errors.Is(err, os.ErrNotExist) {
@@ -25,16 +25,10 @@ func (cfg *Config) loadLockEntries() (map[string]LockEntry, error) {
 		return nil, err
 	}
 
-	var lock LockFile
-
-	if err = yaml.Unmarshal(data, &lock); err != nil {
-		return nil, &pkgErrors.YamllError{Message: fmt.Sprintf("reading lock file errored with: '%v'", err)}
-	}
-
 	entries := make(map[string]LockEntry, len(lock.Entries))
 
 	for _, entry := range lock.Entries {
-		if entry.Source == "" {
+		if entry.Source == "" || (cfg.unlock != nil && cfg.unlock(entry)) {
 			continue
 		}
 
@@ -43,3 +37,18 @@ func (cfg *Config) loadLockEntries() (map[string]LockEntry, error) {
 
 	return entries, nil
 }
+
+func readLockFile(lockPath string) (LockFile, error) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return LockFile{}, err
+	}
+
+	var lock LockFile
+
+	if err = yaml.Unmarshal(data, &lock); err != nil {
+		return LockFile{}, &pkgErrors.YamllError{Message: fmt.Sprintf("reading lock file errored with: '%v'", err)}
+	}
+
+	return lock, nil
+}
@@ -0,0 +1,271 @@
+package yamll
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+// shortPinLength is how much of a commit or digest reports show.
+const shortPinLength = 12
+
+// LockOutdatedEntry is a locked import whose ref or constraint now resolves to another commit or manifest.
+type LockOutdatedEntry struct {
+	Source     string
+	Type       string
+	Constraint string
+	Current    string
+	Latest     string
+}
+
+type LockOutdatedReport struct {
+	Entries []LockOutdatedEntry
+}
+
+// LockUpdateReport lists the locked imports lock update moved, Current and Latest being what they were and are now locked to.
+type LockUpdateReport struct {
+	Entries []LockOutdatedEntry
+}
+
+// LockOutdated resolves every git and OCI import of the lock file afresh, and reports the ones whose ref or constraint,
+// @^1.2 or :v1, allows a newer tag, commit or manifest digest than the one locked.
+func (cfg *Config) LockOutdated(ctx context.Context) (LockOutdatedReport, error) {
+	locked, err := cfg.readExistingLockFile("lock outdated")
+	if err != nil {
+		return LockOutdatedReport{}, err
+	}
+
+	cfg.Root = false
+
+	previousNoLock := cfg.NoLock
+	cfg.NoLock = true
+
+	defer func() {
+		cfg.NoLock = previousNoLock
+	}()
+
+	latest, err := cfg.resolveLockEntries(ctx)
+	if err != nil {
+		return LockOutdatedReport{}, err
+	}
+
+	return LockOutdatedReport{Entries: movedLockEntries(locked.Entries, latest, nil)}, nil
+}
+
+// LockUpdate re-resolves the named locked imports, every git and OCI import when none is named, and returns the updated lock file.
+// Every other remote import stays pinned to, and is written back exactly as, its existing entry.
+// Sources are matched like lock explain matches them, local files are always recorded as they are now.
+func (cfg *Config) LockUpdate(ctx context.Context, sources ...string) ([]byte, LockUpdateReport, error) {
+	locked, err := cfg.readExistingLockFile("lock update")
+	if err != nil {
+		return nil, LockUpdateReport{}, err
+	}
+
+	updated, err := lockSourcesToUpdate(locked.Entries, sources)
+	if err != nil {
+		return nil, LockUpdateReport{}, err
+	}
+
+	cfg.Root = false
+
+	previousNoLock := cfg.NoLock
+	cfg.NoLock = false
+	cfg.unlock = func(entry LockEntry) bool { return updated[entry.Source] || !isRemoteType(entry.Type) }
+
+	defer func() {
+		cfg.NoLock = previousNoLock
+		cfg.unlock = nil
+	}()
+
+	resolved, err := cfg.resolveLockEntries(ctx)
+	if err != nil {
+		return nil, LockUpdateReport{}, err
+	}
+
+	resolvedSources := make(map[string]bool, len(resolved))
+	for _, entry := range resolved {
+		resolvedSources[entry.Source] = true
+	}
+
+	// Entries still locked were read pinned, under the commit or digest they are locked to, and are kept as they are
+	// as long as something still imports them.
+	var entries []LockEntry
+
+	keptSources := make(map[string]bool)
+
+	for _, entry := range locked.Entries {
+		pinned := pinnedLockSource(entry)
+		if cfg.unlock(entry) || (!resolvedSources[entry.Source] && !resolvedSources[pinned]) {
+			continue
+		}
+
+		entries = append(entries, entry)
+		keptSources[entry.Source], keptSources[pinned] = true, true
+	}
+
+	for _, entry := range resolved {
+		if !keptSources[entry.Source] {
+			entries = append(entries, entry)
+		}
+	}
+
+	sortLockEntries(entries)
+
+	out, err := cfg.marshalLock(entries)
+	if err != nil {
+		return nil, LockUpdateReport{}, err
+	}
+
+	return out, LockUpdateReport{Entries: movedLockEntries(locked.Entries, entries, updated)}, nil
+}
+
+func (cfg *Config) readExistingLockFile(command string) (LockFile, error) {
+	if cfg.LockFile == "" {
+		return LockFile{}, &errors.YamllError{Message: "lock file path cannot be empty"}
+	}
+
+	lock, err := readLockFile(cfg.LockFile)
+	if stdErrors.Is(err, os.ErrNotExist) {
+		return LockFile{}, &errors.YamllError{Message: fmt.Sprintf("%s requires the lock file %s, run 'yamll lock' to generate it", command, cfg.LockFile)}
+	}
+
+	return lock, err
+}
+
+// lockSourcesToUpdate returns the locked sources matching the requested ones, every git and OCI source when none is requested.
+func lockSourcesToUpdate(entries []LockEntry, sources []string) (map[string]bool, error) {
+	updated := make(map[string]bool)
+
+	if len(sources) == 0 {
+		for _, entry := range entries {
+			if isPinnedLockEntry(entry) {
+				updated[entry.Source] = true
+			}
+		}
+
+		return updated, nil
+	}
+
+	for _, source := range sources {
+		source = strings.TrimSpace(source)
+		matched := false
+
+		for _, entry := range entries {
+			if lockPathMatches(entry.Source, source) {
+				updated[entry.Source], matched = true, true
+			}
+		}
+
+		if !matched {
+			return nil, &errors.YamllError{Message: fmt.Sprintf("'%s' is not in the lock file, run 'yamll lock' to add new imports", source)}
+		}
+	}
+
+	return updated, nil
+}
+
+// movedLockEntries pairs the pinned entries of the lock with the latest ones by source, limited to the sources given if any,
+// and returns those now pinned to another commit or manifest.
+func movedLockEntries(locked, latest []LockEntry, sources map[string]bool) []LockOutdatedEntry {
+	latestBySource := make(map[string]LockEntry, len(latest))
+	for _, entry := range latest {
+		latestBySource[entry.Source] = entry
+	}
+
+	var moved []LockOutdatedEntry
+
+	seen := make(map[string]bool)
+
+	for _, entry := range locked {
+		if seen[entry.Source] || !isPinnedLockEntry(entry) || (sources != nil && !sources[entry.Source]) {
+			continue
+		}
+
+		seen[entry.Source] = true
+
+		now, ok := latestBySource[entry.Source]
+		if !ok || (now.GitCommit == entry.GitCommit && now.ManifestDigest == entry.ManifestDigest) {
+			continue
+		}
+
+		moved = append(moved, LockOutdatedEntry{
+			Source:     entry.Source,
+			Type:       entry.Type,
+			Constraint: entry.Constraint,
+			Current:    describeLockPin(entry),
+			Latest:     describeLockPin(now),
+		})
+	}
+
+	sort.SliceStable(moved, func(i, j int) bool { return moved[i].Source < moved[j].Source })
+
+	return moved
+}
+
+// pinnedLockSource is the import a locked entry is read as, pinned to its commit or manifest digest.
+func pinnedLockSource(entry LockEntry) string {
+	switch {
+	case entry.GitCommit != "":
+		return pinGitImportToCommit(entry.Source, entry.GitCommit)
+	case entry.ManifestDigest != "":
+		return pinOCIImportToDigest(entry.Source, entry.ManifestDigest)
+	default:
+		return entry.Source
+	}
+}
+
+// isPinnedLockEntry reports whether the entry pins a git commit or OCI manifest, which a newer one can replace.
+func isPinnedLockEntry(entry LockEntry) bool {
+	return entry.GitCommit != "" || entry.ManifestDigest != ""
+}
+
+// describeLockPin spells what the entry is pinned to, the tag a constraint resolved to along with the short commit or digest.
+func describeLockPin(entry LockEntry) string {
+	pin := shortPin(entry.GitCommit)
+	if entry.ManifestDigest != "" {
+		pin = shortPin(entry.ManifestDigest)
+	}
+
+	if entry.Version != "" {
+		return entry.Version + " (" + pin + ")"
+	}
+
+	return pin
+}
+
+func (r LockOutdatedReport) String() string {
+	if len(r.Entries) == 0 {
+		return "All locked imports are up to date\n"
+	}
+
+	return formatMovedLockEntries("Outdated imports:", r.Entries) +
+		fmt.Sprintf("\n%d outdated, run 'yamll lock update <source>' to update them\n", len(r.Entries))
+}
+
+func (r LockUpdateReport) String() string {
+	if len(r.Entries) == 0 {
+		return "Locked imports are already up to date\n"
+	}
+
+	return formatMovedLockEntries("Updated imports:", r.Entries)
+}
+
+func formatMovedLockEntries(title string, entries []LockOutdatedEntry) string {
+	lines := []string{title}
+
+	for _, entry := range entries {
+		line := "  " + entry.Source
+		if entry.Constraint != "" {
+			line += " [" + entry.Constraint + "]"
+		}
+
+		lines = append(lines, line, "    "+entry.Current+" -> "+entry.Latest)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -22,7 +22,7 @@ func TestConfigYamlFailsWhenLockedFileContentChanges(t *testing.T) {
 	generateCfg.SetLogger()
 	generateCfg.LockFile = lockFile
 
-	lockData, err := generateCfg.Lock()
+	lockData, err := generateCfg.Lock(t.Context())
 	require.NoError(t, err)
 	require.NoError(t, os.WriteFile(lockFile, lockData, 0o600))
 
@@ -32,7 +32,7 @@ func TestConfigYamlFailsWhenLockedFileContentChanges(t *testing.T) {
 	runCfg.SetLogger()
 	runCfg.LockFile = lockFile
 
-	_, err = runCfg.Yaml()
+	_, err = runCfg.Yaml(t.Context())
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "changed since the lock file was generated")
 	require.Contains(t, err.Error(), "dependency "+baseFile)
@@ -55,7 +55,7 @@ func TestConfigYamlFailsWhenLockedPatternFileContentChanges(t *testing.T) {
 	generateCfg.SetLogger()
 	generateCfg.LockFile = lockFile
 
-	lockData, err := generateCfg.Lock()
+	lockData, err := generateCfg.Lock(t.Context())
 	require.NoError(t, err)
 	require.NoError(t, os.WriteFile(lockFile, lockData, 0o600))
 
@@ -65,7 +65,7 @@ func TestConfigYamlFailsWhenLockedPatternFileContentChanges(t *testing.T) {
 	runCfg.SetLogger()
 	runCfg.LockFile = lockFile
 
-	_, err = runCfg.Yaml()
+	_, err = runCfg.Yaml(t.Context())
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "changed since the lock file was generated")
 	require.Contains(t, err.Error(), "pattern dependency "+pattern)
@@ -86,7 +86,7 @@ func TestConfigLockRegeneratesWhenExistingLockIsStale(t *testing.T) {
 	generateCfg.SetLogger()
 	generateCfg.LockFile = lockFile
 
-	lockData, err := generateCfg.Lock()
+	lockData, err := generateCfg.Lock(t.Context())
 	require.NoError(t, err)
 	require.NoError(t, os.WriteFile(lockFile, lockData, 0o600))
 
@@ -96,7 +96,7 @@ func TestConfigLockRegeneratesWhenExistingLockIsStale(t *testing.T) {
 	regenerateCfg.SetLogger()
 	regenerateCfg.LockFile = lockFile
 
-	nextLockData, err := regenerateCfg.Lock()
+	nextLockData, err := regenerateCfg.Lock(t.Context())
 	require.NoError(t, err)
 	require.Contains(t, string(nextLockData), "sha256:")
 }
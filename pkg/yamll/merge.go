@@ -15,10 +15,21 @@ func (cfg *Config) mergeData(src string, routes YamlRoutes) (Yaml, error) {
 
 	builder.Grow(len(src) + len(routes)*64)
 
+	graph := routes.Graph()
+	if cycles := graph.Cycles(); len(cycles) != 0 {
+		return "", &errors.YamllError{Message: fmt.Sprintf("import cycle detected: %s", cycles[0])}
+	}
+
+	for _, edge := range graph.Edges {
+		if _, exists := routes[edge.To]; !exists {
+			return "", &errors.YamllError{Message: fmt.Sprintf("dependency route missing for '%s'", edge.To)}
+		}
+	}
+
 	for _, file := range cfg.rootFiles(routes) {
 		fileData := routes[file]
 
-		out, err := cfg.merge("", routes, file, make(map[string]bool))
+		out, err := cfg.merge("", routes, graph, file)
 		if err != nil {
 			return "", err
 		}
@@ -38,47 +49,40 @@ func (cfg *Config) mergeData(src string, routes YamlRoutes) (Yaml, error) {
 	return Yaml(builder.String()), nil
 }
 
-// merge actually merges the data when invoked with correct parameters.
-func (cfg *Config) merge(src string, routes YamlRoutes, file string, visiting map[string]bool) (string, error) {
-	route, exists := routes[file]
-	if !exists {
+// merge actually merges the data of everything file imports, dependencies first, skipping what was merged already.
+func (cfg *Config) merge(src string, routes YamlRoutes, graph *Graph, file string) (string, error) {
+	if _, exists := routes[file]; !exists {
 		return "", &errors.YamllError{Message: fmt.Sprintf("dependency route missing for '%s'", file)}
 	}
 
-	if visiting[file] {
-		return "", &errors.YamllError{Message: fmt.Sprintf("import cycle detected at '%s'", file)}
-	}
-
-	visiting[file] = true
-	defer delete(visiting, file)
+	merged := make(map[string]struct{}, len(routes))
 
-	for _, dependency := range routes[file].Dependency {
-		if _, exists := routes[dependency.Path]; !exists {
-			return "", &errors.YamllError{Message: fmt.Sprintf("dependency route missing for '%s'", dependency.Path)}
+	for key, route := range routes {
+		if route.Merged {
+			merged[key] = struct{}{}
 		}
+	}
 
-		cfg.log.Debug("importing YAML file", slog.String("path", dependency.Path))
-
-		if routes[dependency.Path].Merged {
-			cfg.log.Warn("file already imported hence skipping", slog.String("file", dependency.Path))
-
+	for _, key := range graph.orderFrom(file, merged) {
+		route := routes[key]
+		if route.Root {
 			continue
 		}
 
-		out, err := cfg.merge(src, routes, dependency.Path, visiting)
-		if err != nil {
-			return "", err
+		// A directory import holds nothing but the imports of its files, which are merged on their own.
+		if dependencyType(route.File) == TypeDirectory {
+			route.Merged = true
+
+			continue
 		}
 
-		src = out
-	}
+		cfg.log.Debug("importing YAML file", slog.String("path", key))
 
-	if !route.Merged && !route.Root {
 		src = fmt.Sprintf("%s\n%s\n# Source: %s\n%s", src, cfg.Limiter, route.File, route.DataRaw)
 
 		route.Merged = true
 
-		cfg.log.Debug("file was imported successfully", slog.String("file", file))
+		cfg.log.Debug("file was imported successfully", slog.String("file", key))
 	}
 
 	return src, nil
@@ -124,45 +128,9 @@ func (cfg *Config) rootFiles(routes YamlRoutes) []string {
 	return files
 }
 
+// OrderedFiles lists the files dependencies first, see Graph.TopologicalOrder.
 func (yamlRoutes YamlRoutes) OrderedFiles() []string {
-	var (
-		rootFiles     = make([]string, 0, len(yamlRoutes))
-		leftoverFiles = make([]string, 0, len(yamlRoutes))
-	)
-
-	for file, route := range yamlRoutes {
-		if route.Root {
-			rootFiles = append(rootFiles, file)
-		}
-	}
-
-	sort.SliceStable(rootFiles, func(i, j int) bool {
-		return routeLess(yamlRoutes, rootFiles[i], rootFiles[j])
-	})
-
-	seen := make(map[string]struct{}, len(yamlRoutes))
-	visiting := make(map[string]struct{}, len(yamlRoutes))
-	orderedFiles := make([]string, 0, len(yamlRoutes))
-
-	for _, file := range rootFiles {
-		yamlRoutes.appendOrderedFile(file, seen, visiting, &orderedFiles)
-	}
-
-	for file := range yamlRoutes {
-		if _, exists := seen[file]; exists {
-			continue
-		}
-
-		leftoverFiles = append(leftoverFiles, file)
-	}
-
-	sort.SliceStable(leftoverFiles, func(i, j int) bool {
-		return routeLess(yamlRoutes, leftoverFiles[i], leftoverFiles[j])
-	})
-
-	orderedFiles = append(orderedFiles, leftoverFiles...)
-
-	return orderedFiles
+	return yamlRoutes.Graph().TopologicalOrder()
 }
 
 func containsDependency(dependencies []*Dependency, target string) bool {
@@ -175,33 +143,6 @@ func containsDependency(dependencies []*Dependency, target string) bool {
 	return false
 }
 
-func (yamlRoutes YamlRoutes) appendOrderedFile(file string, seen map[string]struct{}, visiting map[string]struct{}, orderedFiles *[]string) {
-	if _, exists := seen[file]; exists {
-		return
-	}
-
-	if _, exists := visiting[file]; exists {
-		return
-	}
-
-	route, exists := yamlRoutes[file]
-	if !exists {
-		return
-	}
-
-	visiting[file] = struct{}{}
-
-	defer delete(visiting, file)
-
-	for _, dependency := range route.Dependency {
-		yamlRoutes.appendOrderedFile(dependency.Path, seen, visiting, orderedFiles)
-	}
-
-	seen[file] = struct{}{}
-
-	*orderedFiles = append(*orderedFiles, file)
-}
-
 func routeLess(routes YamlRoutes, left, right string) bool {
 	leftRoute := routes[left]
 	rightRoute := routes[right]
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -12,25 +13,48 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"runtime"
 	"strings"
 
 	"github.com/nikhilsbhat/yamll/pkg/errors"
 )
 
 type ociManifest struct {
-	SchemaVersion int `json:"schemaVersion"`
+	SchemaVersion int    `json:"schemaVersion"`
+	MediaType     string `json:"mediaType,omitempty"`
+	ArtifactType  string `json:"artifactType,omitempty"`
 	Config        struct {
 		MediaType string `json:"mediaType"`
 		Digest    string `json:"digest"`
 		Size      int    `json:"size"`
 	} `json:"config"`
-	Layers []struct {
-		MediaType string `json:"mediaType"`
-		Digest    string `json:"digest"`
-		Size      int    `json:"size"`
-	} `json:"layers"`
+	Layers []ociDescriptor `json:"layers"`
+	// Manifests are set on image indexes, which point at a manifest per platform.
+	Manifests   []ociDescriptor   `json:"manifests,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *ociPlatform      `json:"platform,omitempty"`
+}
+
+type ociPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
 }
 
+const (
+	// ociTitleAnnotation names the file a layer holds, it is what ?path= selects on.
+	ociTitleAnnotation = "org.opencontainers.image.title"
+	ociIndexMediaType  = "application/vnd.oci.image.index.v1+json"
+	// ociDockerListMediaType is the Docker counterpart of the OCI image index.
+	ociDockerListMediaType = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
 type ociAuthChallenge struct {
 	Realm   string
 	Service string
@@ -50,8 +74,72 @@ func SetOCIHTTPClientForTest(client *http.Client) {
 	ociHTTPClient = client
 }
 
+// ociRegistry sends the requests of an OCI import, retrying the transient failures.
+type ociRegistry struct {
+	client *http.Client
+	retry  retryPolicy
+	log    *slog.Logger
+}
+
+// ociResponse is a response read in full, so that it outlives the request timeout.
+type ociResponse struct {
+	statusCode int
+	status     string
+	header     http.Header
+	body       []byte
+}
+
+// ociRegistry returns the registry client of the import, honouring its TLS settings.
+func (dependency *Dependency) ociRegistry(auth Auth, log *slog.Logger) (ociRegistry, error) {
+	tlsSettings, err := auth.tlsSettings()
+	if err != nil {
+		return ociRegistry{}, err
+	}
+
+	client, err := tlsSettings.httpClient(ociHTTPClient)
+	if err != nil {
+		return ociRegistry{}, err
+	}
+
+	return ociRegistry{client: client, retry: dependency.retry, log: log}, nil
+}
+
+// send sends the request built by newRequest, failing on statuses other than 2xx and 401.
+func (registry ociRegistry) send(ctx context.Context, target string, newRequest func(ctx context.Context) (*http.Request, error)) (ociResponse, error) {
+	var response ociResponse
+
+	err := registry.retry.do(ctx, registry.log, target, func(ctx context.Context) error {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return err
+		}
+
+		resp, err := registry.client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		response = ociResponse{statusCode: resp.StatusCode, status: resp.Status, header: resp.Header, body: body}
+
+		if resp.StatusCode == http.StatusUnauthorized || (resp.StatusCode >= 200 && resp.StatusCode < 300) {
+			return nil
+		}
+
+		return retryableStatus(&errors.YamllError{Message: ociRequestError(target, resp.Status, body)}, resp.StatusCode, resp.Header)
+	})
+
+	return response, err
+}
+
 // OCI reads YAML content from an OCI artifact import.
-func (dependency *Dependency) OCI(_ *slog.Logger) (File, error) {
+func (dependency *Dependency) OCI(ctx context.Context, log *slog.Logger) (File, error) {
 	ref, err := parseOCIReference(dependency.Path)
 	if err != nil {
 		return File{}, err
@@ -62,34 +150,43 @@ func (dependency *Dependency) OCI(_ *slog.Logger) (File, error) {
 		auth = *dependency.Auth
 	}
 
-	manifestBody, err := ociGetWithAuth(
-		ociHTTPClient,
-		ociManifestURL(ref, ref.Reference),
-		ref,
-		auth,
-	)
+	registry, err := dependency.ociRegistry(auth, log)
 	if err != nil {
 		return File{}, err
 	}
 
-	var manifest ociManifest
-	if err := json.Unmarshal(manifestBody, &manifest); err != nil {
-		return File{}, &errors.YamllError{Message: fmt.Sprintf("reading OCI manifest errored with: %v", err)}
+	manifest, manifestDigest, err := ociResolveManifest(ctx, registry, ref, auth, log)
+	if err != nil {
+		return File{}, err
+	}
+
+	cacheKey := ociCacheKey(ref, manifestDigest)
+
+	pattern := isPattern(ref.Path)
+
+	if entry, content, ok := dependency.cache.Get(cacheKey); ok && !pattern {
+		log.Debug("OCI manifest unchanged, serving artifact from cache", slog.String("path", dependency.Path))
+
+		return File{Name: dependency.Path, Data: content, Meta: FileMeta{SHA256: entry.SHA256, ManifestDigest: manifestDigest}}, nil
+	}
+
+	layers, err := ociSelectLayers(manifest.Layers, ref)
+	if err != nil {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf("OCI artifact '%s': %v", dependency.Path, err)}
+	}
+
+	if pattern {
+		return dependency.ociPattern(ctx, registry, ref, auth, layers, manifestDigest)
 	}
 
-	blobPayloads := make([][]byte, 0, len(manifest.Layers))
+	blobPayloads := make([][]byte, 0, len(layers))
 
-	for _, layer := range manifest.Layers {
+	for _, layer := range layers {
 		if layer.Digest == "" {
 			continue
 		}
 
-		body, err := ociGetWithAuth(
-			ociHTTPClient,
-			ociBlobURL(ref, layer.Digest),
-			ref,
-			auth,
-		)
+		body, err := ociBlob(ctx, registry, ref, auth, layer)
 		if err != nil {
 			return File{}, err
 		}
@@ -114,21 +211,329 @@ func (dependency *Dependency) OCI(_ *slog.Logger) (File, error) {
 
 	sum := sha256.Sum256(out.Bytes())
 
+	if cacheErr := dependency.cache.Put(CacheEntry{Key: cacheKey, Type: TypeOCI, Name: dependency.Path}, out.String()); cacheErr != nil {
+		log.Warn("caching OCI artifact failed", slog.String("path", dependency.Path), slog.Any("err", cacheErr))
+	}
+
 	return File{
 		Name: dependency.Path,
 		Data: out.String(),
-		Meta: FileMeta{SHA256: hex.EncodeToString(sum[:])},
+		Meta: FileMeta{SHA256: hex.EncodeToString(sum[:]), ManifestDigest: manifestDigest},
 	}, nil
 }
 
+// ociPattern reads every layer whose title matches the ?path= pattern, each kept as a Source of the import
+// named after the import of that single layer.
+func (dependency *Dependency) ociPattern(
+	ctx context.Context, registry ociRegistry, ref *ociReference, auth Auth, layers []ociDescriptor, manifestDigest string,
+) (File, error) {
+	sources := make([]File, 0, len(layers))
+
+	for _, layer := range layers {
+		body, err := ociBlob(ctx, registry, ref, auth, layer)
+		if err != nil {
+			return File{}, err
+		}
+
+		sources = append(sources, File{
+			Name: ref.withPath("", layer.Annotations[ociTitleAnnotation]),
+			Data: string(body),
+			Meta: FileMeta{SHA256: checksumForContent(string(body)), ManifestDigest: manifestDigest},
+		})
+	}
+
+	file := patternFile(dependency.Path, sources)
+	file.Meta.ManifestDigest = manifestDigest
+
+	return file, nil
+}
+
+// ociManifestDigest resolves the import to the digest of its manifest, without reading any layer.
+func (dependency *Dependency) ociManifestDigest(ctx context.Context, log *slog.Logger) (string, error) {
+	ref, err := parseOCIReference(dependency.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var auth Auth
+	if dependency.Auth != nil {
+		auth = *dependency.Auth
+	}
+
+	registry, err := dependency.ociRegistry(auth, log)
+	if err != nil {
+		return "", err
+	}
+
+	_, manifestDigest, err := ociResolveManifest(ctx, registry, ref, auth, log)
+
+	return manifestDigest, err
+}
+
+// pinOCIImportToDigest rewrites oci://registry/repo:tag?query to oci://registry/repo@<digest>?query.
+func pinOCIImportToDigest(source, digest string) string {
+	ref, err := parseOCIReference(source)
+	if err != nil {
+		return source
+	}
+
+	pinned := fmt.Sprintf("%s%s/%s@%s", TypeOCI, ref.Registry, ref.Repository, digest)
+	if _, query, found := strings.Cut(source, "?"); found {
+		pinned += "?" + query
+	}
+
+	return pinned
+}
+
+func ociTagMovedError(source, lockedDigest, manifestDigest string) error {
+	return &errors.YamllError{Message: fmt.Sprintf(
+		"OCI tag of %s moved since the lock file was generated: locked manifest %s, the tag now points at %s; run 'yamll lock' to accept it",
+		source, lockedDigest, manifestDigest,
+	)}
+}
+
+// lockedManifestError explains a failed read of the manifest the lock file pins, which is usually gone from the registry
+// because its tag moved on and the old manifest was garbage collected.
+func (cfg *Config) lockedManifestError(ctx context.Context, dependency *Dependency, source string, locked *LockEntry, err error) error {
+	if cfg.Offline || cfg.Vendor || ociConstraintFromSource(source) == "" {
+		return err
+	}
+
+	tag := &Dependency{Path: source, Type: TypeOCI, Auth: dependency.Auth, cache: dependency.cache, retry: dependency.retry}
+
+	manifestDigest, tagErr := tag.ociManifestDigest(ctx, cfg.log)
+	if tagErr != nil || manifestDigest == locked.ManifestDigest {
+		return err
+	}
+
+	return &errors.YamllError{Message: fmt.Sprintf(
+		"%v, reading the locked manifest errored with: %v", ociTagMovedError(source, locked.ManifestDigest, manifestDigest), err,
+	)}
+}
+
+// ociResolveManifest fetches the manifest of the reference and returns it with its digest, verified against the
+// reference when it is one. Image indexes resolve to the manifest without a platform, else to the one of this platform.
+func ociResolveManifest(ctx context.Context, registry ociRegistry, ref *ociReference, auth Auth, log *slog.Logger) (ociManifest, string, error) {
+	manifestBody, err := ociGetWithAuth(ctx, registry, ociManifestURL(ref, ref.Reference), ref, auth)
+	if err != nil {
+		return ociManifest{}, "", err
+	}
+
+	manifestDigest, err := ociVerifiedDigest(ref.Reference, ref.isDigest(), manifestBody, "manifest "+ref.Reference)
+	if err != nil {
+		return ociManifest{}, "", err
+	}
+
+	var manifest ociManifest
+	if err = json.Unmarshal(manifestBody, &manifest); err != nil {
+		return ociManifest{}, "", &errors.YamllError{Message: fmt.Sprintf("reading OCI manifest errored with: %v", err)}
+	}
+
+	if manifest.MediaType != ociIndexMediaType && manifest.MediaType != ociDockerListMediaType && len(manifest.Manifests) == 0 {
+		return manifest, manifestDigest, nil
+	}
+
+	selected, err := ociSelectIndexManifest(manifest.Manifests)
+	if err != nil {
+		return ociManifest{}, "", &errors.YamllError{Message: fmt.Sprintf("OCI index '%s': %v", ref.Reference, err)}
+	}
+
+	log.Debug("resolved OCI image index", slog.String("index", manifestDigest), slog.String("manifest", selected.Digest))
+
+	manifestBody, err = ociGetWithAuth(ctx, registry, ociManifestURL(ref, selected.Digest), ref, auth)
+	if err != nil {
+		return ociManifest{}, "", err
+	}
+
+	if manifestDigest, err = ociVerifiedDigest(selected.Digest, true, manifestBody, "manifest "+selected.Digest); err != nil {
+		return ociManifest{}, "", err
+	}
+
+	manifest = ociManifest{}
+	if err = json.Unmarshal(manifestBody, &manifest); err != nil {
+		return ociManifest{}, "", &errors.YamllError{Message: fmt.Sprintf("reading OCI manifest errored with: %v", err)}
+	}
+
+	if len(manifest.Manifests) != 0 {
+		return ociManifest{}, "", &errors.YamllError{Message: fmt.Sprintf("OCI index '%s' points at another index '%s'", ref.Reference, selected.Digest)}
+	}
+
+	return manifest, manifestDigest, nil
+}
+
+// ociSelectIndexManifest picks the manifest of an index: the only one, else the one without a platform, which is how
+// artifacts are indexed, else the one for the platform yamll runs on.
+func ociSelectIndexManifest(manifests []ociDescriptor) (ociDescriptor, error) {
+	if len(manifests) == 1 {
+		return manifests[0], nil
+	}
+
+	for _, manifest := range manifests {
+		if manifest.Platform == nil {
+			return manifest, nil
+		}
+	}
+
+	platforms := make([]string, 0, len(manifests))
+
+	for _, manifest := range manifests {
+		if manifest.Platform.OS == runtime.GOOS && manifest.Platform.Architecture == runtime.GOARCH {
+			return manifest, nil
+		}
+
+		platforms = append(platforms, manifest.Platform.OS+"/"+manifest.Platform.Architecture)
+	}
+
+	return ociDescriptor{}, fmt.Errorf("no manifest without a platform or for %s/%s, available: %s",
+		runtime.GOOS, runtime.GOARCH, strings.Join(platforms, ", "))
+}
+
+// ociBlob fetches the blob of the layer, failing when its content does not match the digest and size of the layer.
+func ociBlob(ctx context.Context, registry ociRegistry, ref *ociReference, auth Auth, layer ociDescriptor) ([]byte, error) {
+	body, err := ociGetWithAuth(ctx, registry, ociBlobURL(ref, layer.Digest), ref, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	if layer.Size > 0 && len(body) != layer.Size {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("OCI blob %s has %d bytes, its layer declares %d", layer.Digest, len(body), layer.Size)}
+	}
+
+	if _, err = ociVerifiedDigest(layer.Digest, true, body, "blob "+layer.Digest); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// ociVerifiedDigest returns the sha256 digest of the content, checking it against expected when verify is set.
+// Expected may use any of the sha256 and sha512 algorithms.
+func ociVerifiedDigest(expected string, verify bool, content []byte, what string) (string, error) {
+	digest := ociDigestOf(content)
+
+	if !verify {
+		return digest, nil
+	}
+
+	algorithm, _, _ := strings.Cut(expected, ":")
+
+	var actual string
+
+	switch algorithm {
+	case "sha256":
+		actual = digest
+	case "sha512":
+		sha512Sum := sha512.Sum512(content)
+		actual = "sha512:" + hex.EncodeToString(sha512Sum[:])
+	default:
+		return "", &errors.YamllError{Message: fmt.Sprintf("OCI %s uses the unsupported digest algorithm '%s'", what, algorithm)}
+	}
+
+	if actual != expected {
+		return "", &errors.YamllError{Message: fmt.Sprintf("OCI %s failed verification: its content has the digest %s", what, actual)}
+	}
+
+	return digest, nil
+}
+
+// ociDigestOf is the sha256 digest of the content, as registries name blobs and manifests.
+func ociDigestOf(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ociSelectLayers returns every layer, or only those whose title annotation matches the ?path= selector,
+// which is either a title or a pattern of titles. Layers are first filtered by the ?media_type= patterns when set.
+func ociSelectLayers(layers []ociDescriptor, ref *ociReference) ([]ociDescriptor, error) {
+	if len(ref.MediaTypes) != 0 {
+		filtered := make([]ociDescriptor, 0, len(layers))
+
+		for _, layer := range layers {
+			for _, mediaType := range ref.MediaTypes {
+				if matched, _ := matchGlob(mediaType, layer.MediaType); matched {
+					filtered = append(filtered, layer)
+
+					break
+				}
+			}
+		}
+
+		if len(filtered) == 0 {
+			return nil, fmt.Errorf("no layer has a media type matching %s", strings.Join(ref.MediaTypes, ", "))
+		}
+
+		layers = filtered
+	}
+
+	if ref.Path == "" {
+		return layers, nil
+	}
+
+	if err := validateGlob(ref.Path); err != nil {
+		return nil, err
+	}
+
+	var (
+		titles   = make([]string, 0, len(layers))
+		selected []ociDescriptor
+	)
+
+	for _, layer := range layers {
+		title := layer.Annotations[ociTitleAnnotation]
+		if title == "" || layer.Digest == "" {
+			continue
+		}
+
+		if title == ref.Path {
+			return []ociDescriptor{layer}, nil
+		}
+
+		if matched, _ := matchGlob(ref.Path, title); matched {
+			selected = append(selected, layer)
+		}
+
+		titles = append(titles, title)
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no layer titled %q, available: %s", ref.Path, strings.Join(titles, ", "))
+	}
+
+	return selected, nil
+}
+
+// ociReference is a parsed oci:// import, Reference holds either a tag or a manifest digest.
 type ociReference struct {
 	Registry   string
 	Repository string
 	Reference  string
+	Path       string
+	// MediaTypes are the ?media_type= patterns layers are filtered by.
+	MediaTypes []string
+}
+
+// isDigest reports whether the reference pins a manifest digest rather than a tag.
+func (ref *ociReference) isDigest() bool {
+	return strings.Contains(ref.Reference, ":")
+}
+
+// withPath returns the import selecting the layer titled layerPath, pinned to manifestDigest when it is known.
+func (ref *ociReference) withPath(manifestDigest, layerPath string) string {
+	reference := ":" + ref.Reference
+	if ref.isDigest() {
+		reference = "@" + ref.Reference
+	}
+
+	if manifestDigest != "" {
+		reference = "@" + manifestDigest
+	}
+
+	return fmt.Sprintf("%s%s/%s%s?path=%s", TypeOCI, ref.Registry, ref.Repository, reference, layerPath)
 }
 
 func parseOCIReference(raw string) (*ociReference, error) {
-	trimmed := strings.TrimPrefix(raw, TypeOCI)
+	trimmed, query, _ := strings.Cut(strings.TrimPrefix(raw, TypeOCI), "?")
 	if trimmed == "" {
 		return nil, &errors.YamllError{Message: fmt.Sprintf("invalid OCI import: %q", raw)}
 	}
@@ -138,20 +543,51 @@ func parseOCIReference(raw string) (*ociReference, error) {
 		return nil, &errors.YamllError{Message: fmt.Sprintf("invalid OCI import reference: %q", raw)}
 	}
 
-	repo, ref, found := strings.Cut(repoRef, ":")
-	if !found || repo == "" || ref == "" {
+	// repo:tag, repo@sha256:<hex>, or repo:tag@sha256:<hex> where the digest wins over the tag.
+	repo, ref, isDigest := strings.Cut(repoRef, "@")
+	if isDigest {
+		repo, _, _ = strings.Cut(repo, ":")
+
+		algorithm, encoded, _ := strings.Cut(ref, ":")
+		if algorithm == "" || encoded == "" || strings.Trim(encoded, "0123456789abcdef") != "" {
+			return nil, &errors.YamllError{Message: fmt.Sprintf("invalid OCI import digest: %q, it has to be <algorithm>:<hex>", raw)}
+		}
+
+		if algorithm != "sha256" && algorithm != "sha512" {
+			return nil, &errors.YamllError{Message: fmt.Sprintf("OCI import %q uses the unsupported digest algorithm '%s'", raw, algorithm)}
+		}
+	} else {
+		repo, ref, _ = strings.Cut(repoRef, ":")
+	}
+
+	if repo == "" || ref == "" {
 		return nil, &errors.YamllError{Message: fmt.Sprintf("invalid OCI import reference: %q", raw)}
 	}
 
-	return &ociReference{
-		Registry:   registry,
-		Repository: repo,
-		Reference:  ref,
-	}, nil
+	reference := &ociReference{Registry: registry, Repository: repo, Reference: ref}
+
+	if query != "" {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, &errors.YamllError{Message: fmt.Sprintf("invalid OCI import query: %q", raw)}
+		}
+
+		reference.Path = values.Get("path")
+
+		for _, mediaTypes := range values["media_type"] {
+			for mediaType := range strings.SplitSeq(mediaTypes, ",") {
+				if mediaType = strings.TrimSpace(mediaType); mediaType != "" {
+					reference.MediaTypes = append(reference.MediaTypes, mediaType)
+				}
+			}
+		}
+	}
+
+	return reference, nil
 }
 
-func ociGetWithAuth(client *http.Client, requestURL string, ref *ociReference, auth Auth) ([]byte, error) {
-	body, challenge, err := ociFetch(client, requestURL, auth, "")
+func ociGetWithAuth(ctx context.Context, registry ociRegistry, requestURL string, ref *ociReference, auth Auth) ([]byte, error) {
+	body, challenge, err := ociFetch(ctx, registry, requestURL, auth, "")
 	if err == nil {
 		return body, nil
 	}
@@ -160,62 +596,60 @@ func ociGetWithAuth(client *http.Client, requestURL string, ref *ociReference, a
 		return nil, err
 	}
 
-	token, err := ociToken(client, challenge, ref, auth)
+	token, err := ociToken(ctx, registry, challenge, ref, auth, "pull")
 	if err != nil {
 		return nil, err
 	}
 
-	body, _, err = ociFetch(client, requestURL, auth, token)
+	body, _, err = ociFetch(ctx, registry, requestURL, auth, token)
 
 	return body, err
 }
 
-func ociFetch(client *http.Client, requestURL string, auth Auth, bearer string) ([]byte, *ociAuthChallenge, error) {
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, requestURL, nil)
-	if err != nil {
-		return nil, nil, err
-	}
+func ociFetch(ctx context.Context, registry ociRegistry, requestURL string, auth Auth, bearer string) ([]byte, *ociAuthChallenge, error) {
+	resp, err := registry.send(ctx, requestURL, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+		if err != nil {
+			return nil, err
+		}
 
-	switch {
-	case bearer != "":
-		req.Header.Set("Authorization", "Bearer "+bearer)
-	case auth.UserName != "" || auth.Password != "":
-		req.SetBasicAuth(auth.UserName, auth.Password)
-	case auth.BarerToken != "":
-		req.Header.Set("Authorization", "Bearer "+auth.BarerToken)
-	}
+		ociAuthorize(req, auth, bearer)
 
-	req.Header.Set("Accept", strings.Join([]string{
-		"application/vnd.oci.image.manifest.v1+json",
-		"application/vnd.docker.distribution.manifest.v2+json",
-		"application/vnd.oci.artifact.manifest.v1+json",
-	}, ", "))
+		req.Header.Set("Accept", strings.Join([]string{
+			"application/vnd.oci.image.manifest.v1+json",
+			"application/vnd.docker.distribution.manifest.v2+json",
+			"application/vnd.oci.artifact.manifest.v1+json",
+			ociIndexMediaType,
+			ociDockerListMediaType,
+		}, ", "))
 
-	resp, err := client.Do(req)
+		return req, nil
+	})
 	if err != nil {
 		return nil, nil, err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusUnauthorized {
-		if challenge, ok := parseOCIChallenge(resp.Header.Get("WWW-Authenticate")); ok {
+	if resp.statusCode == http.StatusUnauthorized {
+		if challenge, ok := parseOCIChallenge(resp.header.Get("WWW-Authenticate")); ok {
 			return nil, &challenge, &errors.YamllError{Message: "oci registry requires auth"}
 		}
-	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		payload, _ := io.ReadAll(resp.Body)
 
-		return nil, nil, &errors.YamllError{Message: ociRequestError(requestURL, resp.Status, payload)}
+		return nil, nil, &errors.YamllError{Message: ociRequestError(requestURL, resp.status, resp.body)}
 	}
 
-	payload, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, nil, err
-	}
+	return resp.body, nil, nil
+}
 
-	return payload, nil, nil
+// ociAuthorize authenticates the request with the registry token when there is one, else with the credentials of the import.
+func ociAuthorize(req *http.Request, auth Auth, bearer string) {
+	switch {
+	case bearer != "":
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	case auth.UserName != "" || auth.Password != "":
+		req.SetBasicAuth(auth.UserName, auth.Password)
+	case auth.BarerToken != "":
+		req.Header.Set("Authorization", "Bearer "+auth.BarerToken)
+	}
 }
 
 func ociScheme(registry string) string {
@@ -239,13 +673,9 @@ func ociBlobURL(ref *ociReference, digest string) string {
 	return fmt.Sprintf("%s://%s/v2/%s/blobs/%s", ociScheme(ref.Registry), ref.Registry, ref.Repository, url.PathEscape(digest))
 }
 
+// splitOCIRegistryAndRepo splits ghcr.io/company/config:v1 into the registry host and the, possibly nested, repository.
 func splitOCIRegistryAndRepo(trimmed string) (string, string, bool) {
-	slash := strings.LastIndex(trimmed, "/")
-	if slash == -1 {
-		return "", "", false
-	}
-
-	return trimmed[:slash], trimmed[slash+1:], true
+	return strings.Cut(trimmed, "/")
 }
 
 func parseOCIChallenge(header string) (ociAuthChallenge, bool) {
@@ -276,7 +706,8 @@ func parseOCIChallenge(header string) (ociAuthChallenge, bool) {
 	return challenge, challenge.Realm != ""
 }
 
-func ociToken(client *http.Client, challenge *ociAuthChallenge, ref *ociReference, auth Auth) (string, error) {
+// ociToken exchanges the credentials for a registry token, scoped to the actions on the repository unless the challenge sets the scope.
+func ociToken(ctx context.Context, registry ociRegistry, challenge *ociAuthChallenge, ref *ociReference, auth Auth, actions string) (string, error) {
 	tokenURL, err := url.Parse(challenge.Realm)
 	if err != nil {
 		return "", err
@@ -291,34 +722,27 @@ func ociToken(client *http.Client, challenge *ociAuthChallenge, ref *ociReferenc
 	if challenge.Scope != "" {
 		query.Set("scope", challenge.Scope)
 	} else {
-		query.Set("scope", fmt.Sprintf("repository:%s:pull", ref.Repository))
+		query.Set("scope", fmt.Sprintf("repository:%s:%s", ref.Repository, actions))
 	}
 
 	tokenURL.RawQuery = query.Encode()
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, tokenURL.String(), nil)
-	if err != nil {
-		return "", err
-	}
+	resp, err := registry.send(ctx, tokenURL.String(), func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+		if err != nil {
+			return nil, err
+		}
 
-	switch {
-	case auth.UserName != "" || auth.Password != "":
-		req.SetBasicAuth(auth.UserName, auth.Password)
-	case auth.BarerToken != "":
-		req.Header.Set("Authorization", "Bearer "+auth.BarerToken)
-	}
+		ociAuthorize(req, auth, "")
 
-	resp, err := client.Do(req)
+		return req, nil
+	})
 	if err != nil {
 		return "", err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		payload, _ := io.ReadAll(resp.Body)
-
-		return "", &errors.YamllError{Message: ociRequestError(tokenURL.String(), resp.Status, payload)}
+	if resp.statusCode == http.StatusUnauthorized {
+		return "", &errors.YamllError{Message: ociRequestError(tokenURL.String(), resp.status, resp.body)}
 	}
 
 	var payload struct {
@@ -326,7 +750,7 @@ func ociToken(client *http.Client, challenge *ociAuthChallenge, ref *ociReferenc
 		AccessToken string `json:"access_token"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+	if err := json.Unmarshal(resp.body, &payload); err != nil {
 		return "", err
 	}
 
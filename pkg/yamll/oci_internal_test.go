@@ -0,0 +1,26 @@
+package yamll
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOCIReference(t *testing.T) {
+	for raw, expected := range map[string]ociReference{
+		"oci://ghcr.io/config:v1":                     {Registry: "ghcr.io", Repository: "config", Reference: "v1"},
+		"oci://ghcr.io/company/config:v1":             {Registry: "ghcr.io", Repository: "company/config", Reference: "v1"},
+		"oci://ghcr.io/company/team/config:v1?path=a": {Registry: "ghcr.io", Repository: "company/team/config", Reference: "v1", Path: "a"},
+		"oci://localhost:5000/company/config:v1":      {Registry: "localhost:5000", Repository: "company/config", Reference: "v1"},
+		"oci://ghcr.io/company/config@sha256:abc":     {Registry: "ghcr.io", Repository: "company/config", Reference: "sha256:abc"},
+	} {
+		ref, err := parseOCIReference(raw)
+		require.NoError(t, err, raw)
+		require.Equal(t, expected, *ref, raw)
+	}
+
+	for _, raw := range []string{"oci://ghcr.io", "oci://ghcr.io/config", "oci:///config:v1"} {
+		_, err := parseOCIReference(raw)
+		require.Error(t, err, raw)
+	}
+}
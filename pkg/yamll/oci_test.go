@@ -2,9 +2,16 @@ package yamll_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/nikhilsbhat/yamll/pkg/yamll"
@@ -12,13 +19,15 @@ import (
 )
 
 func TestDependencyOCI(t *testing.T) {
+	blob := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: platform-config\n"
+
 	yamll.SetOCIHTTPClientForTest(&http.Client{
 		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
 			switch {
 			case strings.HasSuffix(req.URL.Path, "/manifests/v1"):
 				body := strings.Join([]string{
 					`{"schemaVersion":2,"config":{"mediaType":"application/vnd.oci.empty.v1+json","digest":"sha256:config","size":0},"layers":[`,
-					`{"mediaType":"application/vnd.oci.image.layer.v1.tar+gzip","digest":"sha256:deadbeef","size":67}]}`,
+					ociLayer("application/vnd.oci.image.layer.v1.tar+gzip", blob, ""), `]}`,
 				}, "")
 
 				return &http.Response{
@@ -26,10 +35,10 @@ func TestDependencyOCI(t *testing.T) {
 					Body:       io.NopCloser(strings.NewReader(body)),
 					Header:     make(http.Header),
 				}, nil
-			case strings.HasSuffix(req.URL.Path, "/blobs/sha256:deadbeef"):
+			case strings.HasSuffix(req.URL.Path, "/blobs/"+ociDigest(blob)):
 				return &http.Response{
 					StatusCode: http.StatusOK,
-					Body:       io.NopCloser(strings.NewReader("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: platform-config\n")),
+					Body:       io.NopCloser(strings.NewReader(blob)),
 					Header:     make(http.Header),
 				}, nil
 			default:
@@ -53,7 +62,7 @@ func TestDependencyOCI(t *testing.T) {
 	cfg := yamll.New(false, "DEBUG", "")
 	cfg.SetLogger()
 
-	out, err := dependency.ReadData(false, cfg.GetLogger())
+	out, err := dependency.ReadData(t.Context(), false, cfg.GetLogger())
 	require.NoError(t, err)
 	require.Equal(t, dependency.Path, out.Name)
 	require.Contains(t, out.Data, "apiVersion: v1")
@@ -66,3 +75,443 @@ type roundTripperFunc func(*http.Request) (*http.Response, error)
 func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 	return fn(req)
 }
+
+func ociDigest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ociLayer is the manifest descriptor of a layer holding content, titled when title is set.
+func ociLayer(mediaType, content, title string) string {
+	annotations := ""
+	if title != "" {
+		annotations = fmt.Sprintf(`,"annotations":{"org.opencontainers.image.title":%q}`, title)
+	}
+
+	return fmt.Sprintf(`{"mediaType":%q,"digest":%q,"size":%d%s}`, mediaType, ociDigest(content), len(content), annotations)
+}
+
+// ociBlobs serves the blobs by their digest.
+func ociBlobs(contents ...string) map[string]string {
+	blobs := make(map[string]string, len(contents))
+	for _, content := range contents {
+		blobs[ociDigest(content)] = content
+	}
+
+	return blobs
+}
+
+func TestConfigResolveDependenciesRelativeToOCILayers(t *testing.T) {
+	base, common := "##++common.yaml\nbase: &base\n  <<: *common\n", "common: &common\n  origin: layer\n"
+	manifest := strings.Join([]string{
+		`{"schemaVersion":2,"config":{"mediaType":"application/vnd.oci.empty.v1+json","digest":"sha256:config","size":0},"layers":[`,
+		ociLayer("application/yaml", base, "base.yaml"), ",",
+		ociLayer("application/yaml", common, "common.yaml"), "]}",
+	}, "")
+	manifestDigest := ociDigest(manifest)
+	blobs := ociBlobs(base, common)
+
+	yamll.SetOCIHTTPClientForTest(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			switch {
+			case strings.HasSuffix(req.URL.Path, "/manifests/v1"), strings.HasSuffix(req.URL.Path, "/manifests/"+manifestDigest):
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(manifest)), Header: make(http.Header)}, nil
+			case strings.Contains(req.URL.Path, "/blobs/"):
+				blob, ok := blobs[req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]]
+				if ok {
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(blob)), Header: make(http.Header)}, nil
+				}
+			}
+
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(bytes.NewBufferString("not found")), Header: make(http.Header)}, nil
+		}),
+	})
+	t.Cleanup(func() {
+		yamll.SetOCIHTTPClientForTest(nil)
+	})
+
+	dir := t.TempDir()
+	rootFile := filepath.Join(dir, "root.yaml")
+	require.NoError(t, os.WriteFile(rootFile, []byte("##++oci://ghcr.io/company/platform-config:v1?path=base.yaml\napp: *base\n"), 0o600))
+
+	cfg := yamll.New(false, "DEBUG", "---", rootFile)
+	cfg.SetLogger()
+	cfg.NoLock = true
+	cfg.NoCache = true
+
+	routes, err := cfg.ResolveDependencies(t.Context(), make(map[string]*yamll.YamlData), cfg.Files...)
+	require.NoError(t, err)
+	require.Contains(t, routes, "oci://ghcr.io/company/platform-config@"+manifestDigest+"?path=common.yaml")
+
+	out, err := cfg.Yaml(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, string(out), "origin: layer")
+
+	newConfig := func() *yamll.Config {
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.CacheDir = filepath.Join(dir, "cache")
+		cfg.VendorDir = filepath.Join(dir, "yamll_vendor")
+
+		return cfg
+	}
+
+	t.Run("vendored", func(t *testing.T) {
+		report, err := newConfig().VendorDependencies(t.Context())
+		require.NoError(t, err)
+		require.Len(t, report.Entries, 2)
+
+		manifest, err := os.ReadFile(filepath.Join(dir, "yamll_vendor", "modules.txt"))
+		require.NoError(t, err)
+		require.Contains(t, string(manifest), "manifest_digest="+manifestDigest)
+
+		cfg := newConfig()
+		cfg.Vendor = true
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "origin: layer")
+	})
+
+	t.Run("offline from the import cache", func(t *testing.T) {
+		_, err := newConfig().Yaml(t.Context())
+		require.NoError(t, err)
+
+		cfg := newConfig()
+		cfg.Offline = true
+		cfg.VendorDir = filepath.Join(dir, "no_vendor")
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "origin: layer")
+	})
+}
+
+func TestDependencyOCIPattern(t *testing.T) {
+	a, b, c := "a: &a\n  origin: a\n", "b: &b\n  origin: b\n", "{}"
+	manifest := strings.Join([]string{
+		`{"schemaVersion":2,"config":{"mediaType":"application/vnd.oci.empty.v1+json","digest":"sha256:config","size":0},"layers":[`,
+		ociLayer("application/yaml", b, "envs/b.yaml"), ",",
+		ociLayer("application/yaml", a, "envs/a.yaml"), ",",
+		ociLayer("application/json", c, "envs/c.json"), "]}",
+	}, "")
+	manifestDigest := ociDigest(manifest)
+	blobs := ociBlobs(a, b, c)
+
+	var requests atomic.Int32
+
+	yamll.SetOCIHTTPClientForTest(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests.Add(1)
+
+			switch {
+			case strings.HasSuffix(req.URL.Path, "/manifests/v1"), strings.HasSuffix(req.URL.Path, "/manifests/"+manifestDigest):
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(manifest)), Header: make(http.Header)}, nil
+			case strings.Contains(req.URL.Path, "/blobs/"):
+				blob, ok := blobs[req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]]
+				if ok {
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(blob)), Header: make(http.Header)}, nil
+				}
+			}
+
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(bytes.NewBufferString("not found")), Header: make(http.Header)}, nil
+		}),
+	})
+	t.Cleanup(func() {
+		yamll.SetOCIHTTPClientForTest(nil)
+	})
+
+	dependency := yamll.Dependency{Path: "oci://ghcr.io/company/platform-config:v1?path=envs/*.yaml", Type: yamll.TypeOCI}
+
+	cfg := yamll.New(false, "DEBUG", "")
+	cfg.SetLogger()
+
+	out, err := dependency.ReadData(t.Context(), false, cfg.GetLogger())
+	require.NoError(t, err)
+	require.Equal(t, dependency.Path, out.Name)
+	require.Equal(t, manifestDigest, out.Meta.ManifestDigest)
+	require.Len(t, out.Source, 2)
+	require.Equal(t, "oci://ghcr.io/company/platform-config:v1?path=envs/a.yaml", out.Source[0].Name)
+	require.Equal(t, "oci://ghcr.io/company/platform-config:v1?path=envs/b.yaml", out.Source[1].Name)
+	require.Contains(t, out.Data, "origin: a")
+	require.Contains(t, out.Data, "origin: b")
+	require.NotContains(t, out.Data, "{}")
+
+	dependency.Path = "oci://ghcr.io/company/platform-config:v1?path=envs/*.toml"
+
+	_, err = dependency.ReadData(t.Context(), false, cfg.GetLogger())
+	require.ErrorContains(t, err, "no layer titled")
+
+	t.Run("locked pattern from a warm cache and offline", func(t *testing.T) {
+		dir := t.TempDir()
+		rootFile := filepath.Join(dir, "root.yaml")
+		require.NoError(t, os.WriteFile(rootFile, []byte("##++oci://ghcr.io/company/platform-config:v1?path=envs/*.yaml\napp:\n  <<: [*a, *b]\n"), 0o600))
+
+		newConfig := func() *yamll.Config {
+			cfg := yamll.New(false, "DEBUG", "---", rootFile)
+			cfg.SetLogger()
+			cfg.CacheDir = filepath.Join(dir, "cache")
+			cfg.LockFile = filepath.Join(dir, "yamll.lock")
+			cfg.VendorDir = filepath.Join(dir, "yamll_vendor")
+
+			return cfg
+		}
+
+		lockData, err := newConfig().Lock(t.Context())
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "yamll.lock"), lockData, 0o600))
+
+		_, err = newConfig().Yaml(t.Context())
+		require.NoError(t, err)
+
+		requests.Store(0)
+
+		out, err := newConfig().Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "origin: a")
+		require.Zero(t, requests.Load())
+
+		cfg := newConfig()
+		cfg.Offline = true
+
+		out, err = cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "origin: b")
+		require.Zero(t, requests.Load())
+	})
+}
+
+func TestDependencyOCIDigestsAndIndexes(t *testing.T) {
+	base, data, extra := "base: &base\n  origin: base\n", `{"data": true}`, "extra: &extra\n  origin: extra\n"
+	manifest := strings.Join([]string{
+		`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json","layers":[`,
+		ociLayer("application/yaml", base, "base.yaml"), ",",
+		ociLayer("application/json", data, "data.json"), ",",
+		ociLayer("application/vnd.company.config+yaml", extra, "extra.yaml"), "]}",
+	}, "")
+	manifestDigest := ociDigest(manifest)
+	tampered := `{"schemaVersion":2,"layers":[` + ociLayer("application/yaml", "tampered: false\n", "base.yaml") + `]}`
+
+	index := func(platforms ...string) string {
+		manifests := make([]string, 0, len(platforms))
+		for _, platform := range platforms {
+			osName, architecture, _ := strings.Cut(platform, "/")
+			manifests = append(manifests, fmt.Sprintf(
+				`{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":%q,"size":%d,"platform":{"os":%q,"architecture":%q}}`,
+				manifestDigest, len(manifest), osName, architecture,
+			))
+		}
+
+		return `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[` + strings.Join(manifests, ",") + `]}`
+	}
+
+	manifests := map[string]string{
+		"v1":                  manifest,
+		"index":               index("linux/amd64"),
+		"platforms":           index("plan9/mips", runtime.GOOS+"/"+runtime.GOARCH),
+		"other-platforms":     index("plan9/mips", "plan9/arm"),
+		"tampered":            tampered,
+		manifestDigest:        manifest,
+		ociDigest("mismatch"): manifest,
+	}
+	blobs := ociBlobs(base, data, extra)
+	blobs[ociDigest("tampered: false\n")] = "tampered: true!\n"
+
+	var requests []string
+
+	yamll.SetOCIHTTPClientForTest(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req.URL.Path)
+			name := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+
+			body, ok := manifests[name]
+			if !strings.Contains(req.URL.Path, "/manifests/") {
+				body, ok = blobs[name]
+			}
+
+			if !ok {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(bytes.NewBufferString("not found")), Header: make(http.Header)}, nil
+			}
+
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+		}),
+	})
+	t.Cleanup(func() {
+		yamll.SetOCIHTTPClientForTest(nil)
+	})
+
+	cfg := yamll.New(false, "DEBUG", "")
+	cfg.SetLogger()
+
+	read := func(path string) (yamll.File, error) {
+		return (&yamll.Dependency{Path: path, Type: yamll.TypeOCI}).ReadData(t.Context(), false, cfg.GetLogger())
+	}
+
+	t.Run("digest references", func(t *testing.T) {
+		for _, path := range []string{
+			"oci://ghcr.io/company/config@" + manifestDigest + "?path=base.yaml",
+			"oci://ghcr.io/company/config:v1@" + manifestDigest + "?path=base.yaml",
+		} {
+			requests = nil
+
+			out, err := read(path)
+			require.NoError(t, err, path)
+			require.Equal(t, base, out.Data)
+			require.Equal(t, manifestDigest, out.Meta.ManifestDigest)
+			require.Equal(t, "/v2/company/config/manifests/"+manifestDigest, requests[0])
+		}
+
+		_, err := read("oci://ghcr.io/company/config@" + ociDigest("mismatch") + "?path=base.yaml")
+		require.ErrorContains(t, err, "failed verification")
+
+		_, err = read("oci://ghcr.io/company/config@md5:abc?path=base.yaml")
+		require.ErrorContains(t, err, "unsupported digest algorithm 'md5'")
+
+		_, err = read("oci://ghcr.io/company/config@sha256:not-hex?path=base.yaml")
+		require.ErrorContains(t, err, "invalid OCI import digest")
+	})
+
+	t.Run("image indexes", func(t *testing.T) {
+		for _, tag := range []string{"index", "platforms"} {
+			out, err := read("oci://ghcr.io/company/config:" + tag + "?path=base.yaml")
+			require.NoError(t, err, tag)
+			require.Equal(t, base, out.Data)
+			require.Equal(t, manifestDigest, out.Meta.ManifestDigest)
+		}
+
+		_, err := read("oci://ghcr.io/company/config:other-platforms?path=base.yaml")
+		require.ErrorContains(t, err, "available: plan9/mips, plan9/arm")
+	})
+
+	t.Run("blobs are verified", func(t *testing.T) {
+		_, err := read("oci://ghcr.io/company/config:tampered?path=base.yaml")
+		require.ErrorContains(t, err, "failed verification")
+	})
+
+	t.Run("layers filtered by media type", func(t *testing.T) {
+		out, err := read("oci://ghcr.io/company/config:v1?media_type=application/yaml")
+		require.NoError(t, err)
+		require.Equal(t, base, out.Data)
+
+		out, err = read("oci://ghcr.io/company/config:v1?media_type=application/yaml,application/*%2Byaml")
+		require.NoError(t, err)
+		require.Equal(t, base+"\n---\n"+extra, out.Data)
+
+		out, err = read("oci://ghcr.io/company/config:v1?media_type=*/*%2Byaml&path=*.yaml")
+		require.NoError(t, err)
+		require.Len(t, out.Source, 1)
+		require.Equal(t, extra, out.Source[0].Data)
+
+		_, err = read("oci://ghcr.io/company/config:v1?media_type=text/plain")
+		require.ErrorContains(t, err, "no layer has a media type matching text/plain")
+	})
+}
+
+func TestConfigLockPinsOCIManifestDigest(t *testing.T) {
+	first, second := "base: &base\n  release: first\n", "base: &base\n  release: second\n"
+	manifestFor := func(content string) string {
+		return `{"schemaVersion":2,"layers":[` + ociLayer("application/yaml", content, "base.yaml") + `]}`
+	}
+	firstManifest, secondManifest := manifestFor(first), manifestFor(second)
+
+	manifests := map[string]string{"v1": firstManifest, ociDigest(firstManifest): firstManifest, ociDigest(secondManifest): secondManifest}
+	blobs := ociBlobs(first, second)
+
+	var requests []string
+
+	yamll.SetOCIHTTPClientForTest(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req.URL.Path)
+			name := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+
+			body, ok := manifests[name]
+			if !strings.Contains(req.URL.Path, "/manifests/") {
+				body, ok = blobs[name]
+			}
+
+			if !ok {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(bytes.NewBufferString("not found")), Header: make(http.Header)}, nil
+			}
+
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+		}),
+	})
+	t.Cleanup(func() {
+		yamll.SetOCIHTTPClientForTest(nil)
+	})
+
+	newConfig := func(t *testing.T, importPath string) *yamll.Config {
+		t.Helper()
+
+		dir := t.TempDir()
+		rootFile := filepath.Join(dir, "root.yaml")
+		require.NoError(t, os.WriteFile(rootFile, []byte("##++"+importPath+"\napp: *base\n"), 0o600))
+
+		cfg := yamll.New(false, "DEBUG", "", rootFile)
+		cfg.SetLogger()
+		cfg.NoCache = true
+		cfg.LockFile = filepath.Join(dir, "yamll.lock")
+
+		manifests["v1"] = firstManifest
+
+		lockData, err := cfg.Lock(t.Context())
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(cfg.LockFile, lockData, 0o600))
+
+		return cfg
+	}
+
+	t.Run("moved tags keep reading the locked manifest", func(t *testing.T) {
+		cfg := newConfig(t, "oci://ghcr.io/company/config:v1?path=base.yaml")
+
+		lockData, err := os.ReadFile(cfg.LockFile)
+		require.NoError(t, err)
+		require.Contains(t, string(lockData), "constraint: v1")
+		require.Contains(t, string(lockData), "manifest_digest: "+ociDigest(firstManifest))
+		require.Contains(t, string(lockData), "resolved: oci://ghcr.io/company/config@"+ociDigest(firstManifest)+"?path=base.yaml")
+
+		manifests["v1"] = secondManifest
+		requests = nil
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "release: first")
+		require.Equal(t, "/v2/company/config/manifests/"+ociDigest(firstManifest), requests[0])
+
+		_, err = cfg.LockVerify(t.Context())
+		require.NoError(t, err)
+	})
+
+	t.Run("moved tags whose locked manifest is gone", func(t *testing.T) {
+		cfg := newConfig(t, "oci://ghcr.io/company/config:v1?path=base.yaml")
+
+		manifests["v1"] = secondManifest
+
+		delete(manifests, ociDigest(firstManifest))
+		t.Cleanup(func() {
+			manifests[ociDigest(firstManifest)] = firstManifest
+		})
+
+		_, err := cfg.Yaml(t.Context())
+		require.ErrorContains(t, err, "OCI tag of oci://ghcr.io/company/config:v1?path=base.yaml moved since the lock file was generated")
+		require.ErrorContains(t, err, "the tag now points at "+ociDigest(secondManifest))
+	})
+
+	t.Run("moved tags of pattern imports keep reading the locked manifest", func(t *testing.T) {
+		cfg := newConfig(t, "oci://ghcr.io/company/config:v1?path=*.yaml")
+
+		manifests["v1"] = secondManifest
+		requests = nil
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "release: first")
+		require.Equal(t, "/v2/company/config/manifests/"+ociDigest(firstManifest), requests[0])
+
+		report, err := cfg.LockVerify(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, report.String(), "Lock file is valid")
+	})
+}
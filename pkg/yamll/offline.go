@@ -0,0 +1,110 @@
+package yamll
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+// readOffline resolves a remote dependency without touching the network.
+// A vendored copy is preferred when the vendor directory exists, otherwise a dependency pinned by the lock file
+// is looked up by its locked sha256, and anything else by the import it was last resolved from.
+func (cfg *Config) readOffline(dependency *Dependency, source string, locked *LockEntry) (File, error) {
+	if entries, err := cfg.vendorEntries(); err == nil {
+		if _, ok := entries[source]; ok {
+			return cfg.readVendored(dependency, source)
+		}
+	}
+
+	if locked != nil && locked.SHA256 != "" {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf(
+			"dependency %s is not available offline: content with sha256 %s from the lock file is neither vendored nor in the cache",
+			dependency.Path, locked.SHA256,
+		)}
+	}
+
+	file, ok := cfg.readRemembered(dependency)
+	if !ok {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf(
+			"dependency %s is not available offline: it is neither vendored nor cached, run once with network access or 'yamll vendor'",
+			dependency.Path,
+		)}
+	}
+
+	cfg.log.Debug("serving dependency from cache in offline mode", slog.String("path", dependency.Path), slog.String("sha256", file.Meta.SHA256))
+
+	return file, nil
+}
+
+// readRemembered reads what the import string last resolved to back from the cache,
+// a pattern import along with every file it matched.
+func (cfg *Config) readRemembered(dependency *Dependency) (File, bool) {
+	entry, content, ok := cfg.cache().Get(importCacheKey(dependency.Path))
+	if !ok {
+		return File{}, false
+	}
+
+	file := rememberedFile(entry, content)
+	if len(entry.Sources) == 0 {
+		return file, true
+	}
+
+	sources := make([]File, 0, len(entry.Sources))
+
+	for _, name := range entry.Sources {
+		sourceEntry, sourceContent, ok := cfg.cache().Get(importCacheKey(name))
+		if !ok {
+			return File{}, false
+		}
+
+		sources = append(sources, rememberedFile(sourceEntry, sourceContent))
+	}
+
+	pattern := patternFile(entry.Name, sources)
+	pattern.Meta.GitCommit, pattern.Meta.ManifestDigest = entry.GitCommit, entry.ManifestDigest
+
+	return pattern, true
+}
+
+func rememberedFile(entry CacheEntry, content string) File {
+	return File{
+		Name: entry.Name,
+		Data: content,
+		Meta: FileMeta{SHA256: entry.SHA256, GitCommit: entry.GitCommit, ManifestDigest: entry.ManifestDigest},
+	}
+}
+
+// rememberImport records the content an import string last resolved to, so that offline runs can find it again.
+// The files a pattern import matched are each remembered under their own import, which the pattern lists.
+func (cfg *Config) rememberImport(dependency *Dependency, file File) {
+	if !isRemoteType(dependency.Type) {
+		return
+	}
+
+	sources := make([]string, 0, len(file.Source))
+
+	for _, source := range file.Source {
+		cfg.rememberFile(dependency.Type, source.Name, source, nil)
+		sources = append(sources, source.Name)
+	}
+
+	cfg.rememberFile(dependency.Type, dependency.Path, file, sources)
+}
+
+func (cfg *Config) rememberFile(dependencyType, path string, file File, sources []string) {
+	if err := cfg.cache().Put(CacheEntry{
+		Key:            importCacheKey(path),
+		Type:           dependencyType,
+		Name:           file.Name,
+		GitCommit:      file.Meta.GitCommit,
+		ManifestDigest: file.Meta.ManifestDigest,
+		Sources:        sources,
+	}, file.Data); err != nil {
+		cfg.log.Warn("caching import failed", slog.String("path", path), slog.Any("err", err))
+	}
+}
+
+func importCacheKey(path string) string {
+	return "import:" + path
+}
@@ -0,0 +1,119 @@
+package yamll
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+const (
+	// TypePlugin is the type of imports delegated to a yamll-source-<scheme> executable.
+	TypePlugin = "plugin"
+	// PluginExecutablePrefix is prepended to the scheme of an import to find the executable serving it on PATH.
+	PluginExecutablePrefix = "yamll-source-"
+)
+
+var pluginSchemePattern = regexp.MustCompile(`^([a-z][a-z0-9+.-]*)://`)
+
+// pluginRequest is written as JSON to the stdin of a source plugin.
+type pluginRequest struct {
+	Path string `json:"path"`
+	Auth *Auth  `json:"auth,omitempty"`
+}
+
+// pluginResponse is read as JSON from the stdout of a source plugin.
+// Digest is the sha256 of Content, with or without the sha256: prefix, and is verified when set.
+// Resolved and Version describe what the import resolved to, and end up in the lock file.
+type pluginResponse struct {
+	Content  string `json:"content"`
+	Digest   string `json:"digest,omitempty"`
+	Resolved string `json:"resolved,omitempty"`
+	Version  string `json:"version,omitempty"`
+}
+
+// pluginSource delegates schemes no other source handles to a yamll-source-<scheme> executable on PATH,
+// in the style of git remote helpers.
+type pluginSource struct{}
+
+func (pluginSource) Type() string {
+	return TypePlugin
+}
+
+func (pluginSource) Match(path string) bool {
+	_, err := pluginExecutable(path)
+
+	return err == nil
+}
+
+func (pluginSource) Read(ctx context.Context, dependency *Dependency, log *slog.Logger) (File, error) {
+	return dependency.Plugin(ctx, log)
+}
+
+// Plugin reads the data from a source plugin, passing it the import path and auth as JSON on stdin.
+// The plugin is killed when ctx is done or the request timeout expires.
+func (dependency *Dependency) Plugin(ctx context.Context, log *slog.Logger) (File, error) {
+	executable, err := pluginExecutable(dependency.Path)
+	if err != nil {
+		return File{}, err
+	}
+
+	request, err := json.Marshal(pluginRequest{Path: dependency.Path, Auth: dependency.Auth})
+	if err != nil {
+		return File{}, err
+	}
+
+	ctx, cancel := dependency.retry.attemptContext(ctx)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	command := exec.CommandContext(ctx, executable) //nolint:gosec
+	command.Stdin = bytes.NewReader(request)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	log.Debug("delegating import to source plugin", slog.String("path", dependency.Path), slog.String("plugin", executable))
+
+	if err = command.Run(); err != nil {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf(
+			"source plugin %s failed for '%s' with: '%v' %s", executable, dependency.Path, err, strings.TrimSpace(stderr.String()),
+		)}
+	}
+
+	var response pluginResponse
+	if err = json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf("reading response of source plugin %s errored with: '%v'", executable, err)}
+	}
+
+	digest := checksumForContent(response.Content)
+
+	if expected := strings.TrimPrefix(response.Digest, "sha256:"); expected != "" && expected != digest {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf(
+			"source plugin %s returned content for '%s' not matching its digest: expected sha256 %s, got %s", executable, dependency.Path, expected, digest,
+		)}
+	}
+
+	name := response.Resolved
+	if name == "" {
+		name = dependency.Path
+	}
+
+	return File{Name: name, Data: response.Content, Meta: FileMeta{SHA256: digest, Version: response.Version}}, nil
+}
+
+// pluginExecutable finds the yamll-source-<scheme> executable serving the import.
+func pluginExecutable(path string) (string, error) {
+	match := pluginSchemePattern.FindStringSubmatch(path)
+	if match == nil {
+		return "", &errors.YamllError{Message: fmt.Sprintf("import '%s' has no scheme to find a source plugin for", path)}
+	}
+
+	return exec.LookPath(PluginExecutablePrefix + match[1])
+}
@@ -0,0 +1,91 @@
+package yamll_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+// installSourcePlugin puts an executable yamll-source-<scheme> on PATH that records its stdin and prints response.
+func installSourcePlugin(t *testing.T, scheme, response string) string {
+	t.Helper()
+
+	binDir := t.TempDir()
+	requestFile := filepath.Join(binDir, "request.json")
+	script := "#!/bin/sh\ncat > '" + requestFile + "'\ncat <<'RESPONSE'\n" + response + "\nRESPONSE\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, yamll.PluginExecutablePrefix+scheme), []byte(script), 0o700)) //nolint:gosec
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	return requestFile
+}
+
+func TestDependencyPlugin(t *testing.T) {
+	content := "credentials: &credentials\n  user: app\n"
+	sum := sha256.Sum256([]byte(content))
+	digest := hex.EncodeToString(sum[:])
+
+	requestFile := installSourcePlugin(t, "secrets",
+		`{"content":"credentials: &credentials\n  user: app\n","digest":"sha256:`+digest+`","resolved":"secrets://app/db@7","version":"7"}`)
+
+	dependency := yamll.Dependency{Path: "secrets://app/db"}
+	dependency.IdentifyType()
+	require.Equal(t, yamll.TypePlugin, dependency.Type)
+
+	dir := t.TempDir()
+	rootFile := filepath.Join(dir, "root.yaml")
+	require.NoError(t, os.WriteFile(rootFile, []byte("##++secrets://app/db;{\"barer_token\":\"s.token\"}\napp: *credentials\n"), 0o600))
+
+	cfg := yamll.New(false, "DEBUG", "---", rootFile)
+	cfg.SetLogger()
+	cfg.NoCache = true
+	cfg.LockFile = filepath.Join(dir, "yamll.lock")
+
+	out, err := cfg.Yaml(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, string(out), "user: app")
+
+	request, err := os.ReadFile(requestFile)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"path":"secrets://app/db","auth":{"barer_token":"s.token"}}`, string(request))
+
+	lockData, err := cfg.Lock(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, string(lockData), "type: plugin")
+	require.Contains(t, string(lockData), "resolved: secrets://app/db@7")
+	require.Contains(t, string(lockData), "version: \"7\"")
+	require.Contains(t, string(lockData), "sha256: "+digest)
+	require.NoError(t, os.WriteFile(cfg.LockFile, lockData, 0o600))
+
+	t.Run("content changed behind the lock", func(t *testing.T) {
+		installSourcePlugin(t, "secrets", `{"content":"credentials: &credentials\n  user: admin\n","version":"8"}`)
+
+		_, err := cfg.Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "changed since the lock file was generated")
+	})
+
+	t.Run("digest not matching the content", func(t *testing.T) {
+		installSourcePlugin(t, "secrets", `{"content":"credentials: {}\n","digest":"`+digest+`"}`)
+
+		_, err := (&yamll.Dependency{Path: "secrets://app/db", Type: yamll.TypePlugin}).Plugin(t.Context(), cfg.GetLogger())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not matching its digest")
+	})
+
+	t.Run("failing plugin", func(t *testing.T) {
+		binDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(binDir, yamll.PluginExecutablePrefix+"broken"), //nolint:gosec
+			[]byte("#!/bin/sh\necho 'no such secret' >&2\nexit 3\n"), 0o700))
+		t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+		_, err := (&yamll.Dependency{Path: "broken://app/db"}).Plugin(t.Context(), cfg.GetLogger())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no such secret")
+	})
+}
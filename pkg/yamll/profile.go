@@ -2,16 +2,21 @@ package yamll
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
 // BuildProfile captures phase timings for build operations.
+// RemoteFetch sums the time spent on every remote fetch, while RemoteFetchWall is the elapsed time
+// those fetches took once run concurrently.
 type BuildProfile struct {
 	ImportResolution time.Duration
 	RemoteFetch      time.Duration
+	RemoteFetchWall  time.Duration
 	MergePhase       time.Duration
 	Validation       time.Duration
 	totalStart       time.Time
+	mutex            sync.Mutex
 }
 
 func (p *BuildProfile) Total() time.Duration {
@@ -27,8 +32,9 @@ func (p *BuildProfile) String() string {
 		return ""
 	}
 
-	return fmt.Sprintf("Import resolution: %s\nRemote fetch: %s\nMerge phase: %s\nValidation: %s\nTotal: %s\n",
+	return fmt.Sprintf("Import resolution: %s\nRemote fetch (wall): %s\nRemote fetch (summed): %s\nMerge phase: %s\nValidation: %s\nTotal: %s\n",
 		prettyDuration(p.ImportResolution),
+		prettyDuration(p.RemoteFetchWall),
 		prettyDuration(p.RemoteFetch),
 		prettyDuration(p.MergePhase),
 		prettyDuration(p.Validation),
@@ -46,10 +52,19 @@ func (p *BuildProfile) begin() {
 
 func (p *BuildProfile) addRemoteFetch(duration time.Duration) {
 	if p != nil {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+
 		p.RemoteFetch += duration
 	}
 }
 
+func (p *BuildProfile) addRemoteFetchWall(duration time.Duration) {
+	if p != nil {
+		p.RemoteFetchWall += duration
+	}
+}
+
 func (p *BuildProfile) addImportResolution(duration time.Duration) {
 	if p != nil {
 		p.ImportResolution += duration
@@ -0,0 +1,324 @@
+package yamll
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+const (
+	// PublishArtifactType is the artifactType of the manifests yamll publish pushes.
+	PublishArtifactType = "application/vnd.yamll.bundle.v1"
+	// PublishLayerMediaType is the media type of every file yamll publish pushes, one layer each.
+	PublishLayerMediaType = "application/vnd.yamll.file.v1+yaml"
+
+	ociManifestMediaType   = "application/vnd.oci.image.manifest.v1+json"
+	ociEmptyMediaType      = "application/vnd.oci.empty.v1+json"
+	ociSourceAnnotation    = "org.opencontainers.image.source"
+	ociVersionAnnotation   = "org.opencontainers.image.version"
+	ociEmptyConfigContents = "{}"
+)
+
+// PublishFile is a file pushed by Publish, Title being the name imports select it by with ?path=.
+type PublishFile struct {
+	Title string
+	Data  string
+}
+
+// PublishOptions are the annotations Publish sets on the manifest.
+type PublishOptions struct {
+	// Source is the org.opencontainers.image.source annotation, usually the repository the files come from.
+	Source string
+	// Version is the org.opencontainers.image.version annotation, the tag when empty.
+	Version string
+	// Annotations are set on the manifest as they are, after Source and Version.
+	Annotations map[string]string
+}
+
+type PublishReport struct {
+	Reference string
+	Digest    string
+	Files     []string
+}
+
+// ReadPublishFiles reads the files to publish, titled by their slash separated path relative to baseDir,
+// which is what imports of the artifact and the imports between its files select them by.
+func ReadPublishFiles(baseDir string, paths ...string) ([]PublishFile, error) {
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]PublishFile, 0, len(paths))
+
+	for _, filePath := range paths {
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		title, err := filepath.Rel(absBase, absPath)
+		if err != nil || title == ".." || strings.HasPrefix(title, ".."+string(filepath.Separator)) {
+			return nil, &errors.YamllError{Message: fmt.Sprintf("file '%s' to publish is not within '%s'", filePath, baseDir)}
+		}
+
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			return nil, &errors.YamllError{Message: fmt.Sprintf("reading file to publish errored with: '%v'", err)}
+		}
+
+		files = append(files, PublishFile{Title: filepath.ToSlash(title), Data: string(data)})
+	}
+
+	return files, nil
+}
+
+// Publish pushes the files to the OCI registry as an artifact tagged by target, oci://registry/repo:tag,
+// one layer per file annotated with its title, so that oci://registry/repo:tag?path=<title> imports it.
+// Registries are authenticated against like OCI imports are, with the credentials file and the docker config.
+func (cfg *Config) Publish(ctx context.Context, target string, files []PublishFile, options PublishOptions) (PublishReport, error) {
+	ref, err := parsePublishTarget(target)
+	if err != nil {
+		return PublishReport{}, err
+	}
+
+	if len(files) == 0 {
+		return PublishReport{}, &errors.YamllError{Message: "publish requires at least one file"}
+	}
+
+	titles := make(map[string]bool, len(files))
+
+	for _, file := range files {
+		if file.Title == "" || cleanOriginPath(file.Title) != file.Title {
+			return PublishReport{}, &errors.YamllError{Message: fmt.Sprintf("'%s' cannot be the title of a published file, it has to be a relative path", file.Title)}
+		}
+
+		if titles[file.Title] {
+			return PublishReport{}, &errors.YamllError{Message: fmt.Sprintf("more than one published file is titled '%s'", file.Title)}
+		}
+
+		titles[file.Title] = true
+	}
+
+	publisher, err := cfg.ociPublisher(ctx, target, ref)
+	if err != nil {
+		return PublishReport{}, err
+	}
+
+	manifest := ociManifest{SchemaVersion: 2, MediaType: ociManifestMediaType, ArtifactType: PublishArtifactType}
+	manifest.Config.MediaType = ociEmptyMediaType
+	manifest.Config.Digest = ociDigestOf([]byte(ociEmptyConfigContents))
+	manifest.Config.Size = len(ociEmptyConfigContents)
+
+	if err = publisher.pushBlob(ctx, []byte(ociEmptyConfigContents)); err != nil {
+		return PublishReport{}, err
+	}
+
+	report := PublishReport{Reference: target}
+
+	for _, file := range files {
+		if err = publisher.pushBlob(ctx, []byte(file.Data)); err != nil {
+			return PublishReport{}, err
+		}
+
+		cfg.log.Debug("pushed file", slog.String("title", file.Title), slog.String("reference", target))
+
+		manifest.Layers = append(manifest.Layers, ociDescriptor{
+			MediaType:   PublishLayerMediaType,
+			Digest:      ociDigestOf([]byte(file.Data)),
+			Size:        len(file.Data),
+			Annotations: map[string]string{ociTitleAnnotation: file.Title},
+		})
+		report.Files = append(report.Files, file.Title)
+	}
+
+	manifest.Annotations = publishAnnotations(ref, options)
+
+	manifestBody, err := json.Marshal(manifest)
+	if err != nil {
+		return PublishReport{}, err
+	}
+
+	if _, err = publisher.do(ctx, http.MethodPut, ociManifestURL(ref, ref.Reference), ociManifestMediaType, manifestBody, http.StatusCreated); err != nil {
+		return PublishReport{}, err
+	}
+
+	report.Digest = ociDigestOf(manifestBody)
+
+	return report, nil
+}
+
+// parsePublishTarget parses the oci://registry/repo:tag artifacts are pushed to, which has to be a tag without any query.
+func parsePublishTarget(target string) (*ociReference, error) {
+	if !strings.HasPrefix(target, TypeOCI) || strings.Contains(target, "?") {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("publish target '%s' has to be oci://<registry>/<repository>:<tag>", target)}
+	}
+
+	ref, err := parseOCIReference(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.isDigest() {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("publish target '%s' has to be a tag, registries name the pushed manifest by its digest", target)}
+	}
+
+	return ref, nil
+}
+
+func publishAnnotations(ref *ociReference, options PublishOptions) map[string]string {
+	annotations := map[string]string{ociVersionAnnotation: ref.Reference}
+
+	if options.Version != "" {
+		annotations[ociVersionAnnotation] = options.Version
+	}
+
+	if options.Source != "" {
+		annotations[ociSourceAnnotation] = options.Source
+	}
+
+	for key, value := range options.Annotations {
+		annotations[key] = value
+	}
+
+	return annotations
+}
+
+// ParsePublishAnnotations parses key=value annotations, as given to yamll publish.
+func ParsePublishAnnotations(pairs []string) (map[string]string, error) {
+	annotations := make(map[string]string, len(pairs))
+
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, &errors.YamllError{Message: fmt.Sprintf("annotation '%s' has to be set as <key>=<value>", pair)}
+		}
+
+		annotations[strings.TrimSpace(key)] = value
+	}
+
+	return annotations, nil
+}
+
+// ociPublisher pushes to a repository, keeping the registry token once challenged for one.
+type ociPublisher struct {
+	registry ociRegistry
+	ref      *ociReference
+	auth     Auth
+	token    string
+}
+
+func (cfg *Config) ociPublisher(ctx context.Context, target string, ref *ociReference) (*ociPublisher, error) {
+	if err := cfg.loadCredentials(); err != nil {
+		return nil, err
+	}
+
+	dependency := &Dependency{Path: target, Type: TypeOCI, retry: cfg.retryPolicy()}
+
+	var auth Auth
+	if configured := cfg.credentials.authFor(ctx, dependency); configured != nil {
+		auth = *configured
+	}
+
+	registry, err := dependency.ociRegistry(auth, cfg.log)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ociPublisher{registry: registry, ref: ref, auth: auth}, nil
+}
+
+// pushBlob uploads the content in a single request to the upload session the registry opens for it.
+func (publisher *ociPublisher) pushBlob(ctx context.Context, content []byte) error {
+	uploadsURL := fmt.Sprintf("%s://%s/v2/%s/blobs/uploads/", ociScheme(publisher.ref.Registry), publisher.ref.Registry, publisher.ref.Repository)
+
+	resp, err := publisher.do(ctx, http.MethodPost, uploadsURL, "", nil, http.StatusAccepted)
+	if err != nil {
+		return err
+	}
+
+	location, err := url.Parse(resp.header.Get("Location"))
+	if err != nil || resp.header.Get("Location") == "" {
+		return &errors.YamllError{Message: fmt.Sprintf("registry %s opened an upload without a valid Location", publisher.ref.Registry)}
+	}
+
+	base, _ := url.Parse(uploadsURL)
+	location = base.ResolveReference(location)
+
+	query := location.Query()
+	query.Set("digest", ociDigestOf(content))
+	location.RawQuery = query.Encode()
+
+	_, err = publisher.do(ctx, http.MethodPut, location.String(), "application/octet-stream", content, http.StatusCreated)
+
+	return err
+}
+
+// do sends the request, asking for a pull and push token when the registry challenges it, and fails on any other status than expected.
+func (publisher *ociPublisher) do(ctx context.Context, method, requestURL, contentType string, body []byte, expected int) (ociResponse, error) {
+	resp, err := publisher.send(ctx, method, requestURL, contentType, body)
+	if err != nil {
+		return ociResponse{}, err
+	}
+
+	if resp.statusCode == http.StatusUnauthorized {
+		challenge, ok := parseOCIChallenge(resp.header.Get("WWW-Authenticate"))
+		if !ok {
+			return ociResponse{}, &errors.YamllError{Message: ociRequestError(requestURL, resp.status, resp.body)}
+		}
+
+		if publisher.token, err = ociToken(ctx, publisher.registry, &challenge, publisher.ref, publisher.auth, "pull,push"); err != nil {
+			return ociResponse{}, err
+		}
+
+		if resp, err = publisher.send(ctx, method, requestURL, contentType, body); err != nil {
+			return ociResponse{}, err
+		}
+	}
+
+	if resp.statusCode != expected {
+		return ociResponse{}, &errors.YamllError{Message: ociRequestError(requestURL, resp.status, resp.body)}
+	}
+
+	return resp, nil
+}
+
+func (publisher *ociPublisher) send(ctx context.Context, method, requestURL, contentType string, body []byte) (ociResponse, error) {
+	return publisher.registry.send(ctx, requestURL, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		ociAuthorize(req, publisher.auth, publisher.token)
+
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		return req, nil
+	})
+}
+
+func (r PublishReport) String() string {
+	lines := []string{"Published " + r.Reference, "Digest: " + r.Digest, "Files:"}
+
+	files := append([]string(nil), r.Files...)
+	sort.Strings(files)
+
+	for _, file := range files {
+		lines = append(lines, "  "+file)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,176 @@
+package yamll_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeRegistry serves the push and pull endpoints of the distribution API, behind a token challenge for pushes.
+func newFakeRegistry(t *testing.T) (string, map[string]string, *[]string) {
+	t.Helper()
+
+	var (
+		mutex  sync.Mutex
+		scopes []string
+	)
+
+	content := make(map[string]string)
+
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if req.URL.Path == "/token" {
+			scopes = append(scopes, req.URL.Query().Get("scope"))
+			_, _ = w.Write([]byte(`{"token":"push-token"}`))
+
+			return
+		}
+
+		if req.Method != http.MethodGet && req.Header.Get("Authorization") != "Bearer push-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q,service="fake"`, server.URL+"/token"))
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		body, _ := io.ReadAll(req.Body)
+
+		switch {
+		case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/blobs/uploads/"):
+			w.Header().Set("Location", "/upload/session?_state=abc")
+			w.WriteHeader(http.StatusAccepted)
+		case req.Method == http.MethodPut && req.URL.Path == "/upload/session":
+			digest := req.URL.Query().Get("digest")
+			if req.URL.Query().Get("_state") != "abc" || digest != ociDigest(string(body)) {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			content[digest] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		case req.Method == http.MethodPut && strings.Contains(req.URL.Path, "/manifests/"):
+			if req.Header.Get("Content-Type") != "application/vnd.oci.image.manifest.v1+json" {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			content[req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]] = string(body)
+			content[ociDigest(string(body))] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		case req.Method == http.MethodGet:
+			stored, ok := content[req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]]
+			if !ok {
+				http.NotFound(w, req)
+
+				return
+			}
+
+			_, _ = w.Write([]byte(stored))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return strings.TrimPrefix(server.URL, "http://"), content, &scopes
+}
+
+func TestConfigPublish(t *testing.T) {
+	isolateCredentials(t)
+
+	registry, content, scopes := newFakeRegistry(t)
+
+	dir := t.TempDir()
+	libDir := filepath.Join(dir, "lib")
+	require.NoError(t, os.MkdirAll(filepath.Join(libDir, "envs"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(libDir, "base.yaml"), []byte("##++envs/common.yaml\nbase: &base\n  <<: *common\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(libDir, "envs", "common.yaml"), []byte("common: &common\n  origin: published\n"), 0o600))
+
+	cfg := yamll.New(false, "DEBUG", "")
+	cfg.SetLogger()
+
+	files, err := yamll.ReadPublishFiles(libDir, filepath.Join(libDir, "base.yaml"), filepath.Join(libDir, "envs", "common.yaml"))
+	require.NoError(t, err)
+
+	target := "oci://" + registry + "/company/lib:v1.2.0"
+
+	report, err := cfg.Publish(t.Context(), target, files, yamll.PublishOptions{
+		Source:      "https://github.com/company/lib",
+		Annotations: map[string]string{"com.company.team": "platform"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"base.yaml", "envs/common.yaml"}, report.Files)
+	require.Equal(t, []string{"repository:company/lib:pull,push"}, *scopes)
+	require.Contains(t, report.String(), "Digest: "+report.Digest)
+
+	var manifest struct {
+		MediaType    string            `json:"mediaType"`
+		ArtifactType string            `json:"artifactType"`
+		Annotations  map[string]string `json:"annotations"`
+		Layers       []struct {
+			MediaType   string            `json:"mediaType"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"layers"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(content["v1.2.0"]), &manifest))
+	require.Equal(t, report.Digest, ociDigest(content["v1.2.0"]))
+	require.Equal(t, yamll.PublishArtifactType, manifest.ArtifactType)
+	require.Equal(t, map[string]string{
+		"org.opencontainers.image.source":  "https://github.com/company/lib",
+		"org.opencontainers.image.version": "v1.2.0",
+		"com.company.team":                 "platform",
+	}, manifest.Annotations)
+	require.Len(t, manifest.Layers, 2)
+	require.Equal(t, yamll.PublishLayerMediaType, manifest.Layers[1].MediaType)
+	require.Equal(t, "envs/common.yaml", manifest.Layers[1].Annotations["org.opencontainers.image.title"])
+
+	t.Run("published files import each other", func(t *testing.T) {
+		rootFile := filepath.Join(dir, "root.yaml")
+		require.NoError(t, os.WriteFile(rootFile, []byte("##++"+target+"?path=base.yaml\napp: *base\n"), 0o600))
+
+		importCfg := yamll.New(false, "DEBUG", "---", rootFile)
+		importCfg.SetLogger()
+		importCfg.NoLock = true
+		importCfg.NoCache = true
+
+		out, err := importCfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "origin: published")
+	})
+
+	t.Run("invalid publishes", func(t *testing.T) {
+		_, err := cfg.Publish(t.Context(), "oci://"+registry+"/company/lib@"+report.Digest, files, yamll.PublishOptions{})
+		require.ErrorContains(t, err, "has to be a tag")
+
+		_, err = cfg.Publish(t.Context(), target+"?path=base.yaml", files, yamll.PublishOptions{})
+		require.ErrorContains(t, err, "has to be oci://<registry>/<repository>:<tag>")
+
+		_, err = cfg.Publish(t.Context(), target, []yamll.PublishFile{{Title: "../base.yaml"}}, yamll.PublishOptions{})
+		require.ErrorContains(t, err, "it has to be a relative path")
+
+		_, err = cfg.Publish(t.Context(), target, append(files, files[0]), yamll.PublishOptions{})
+		require.ErrorContains(t, err, "more than one published file is titled 'base.yaml'")
+
+		_, err = yamll.ReadPublishFiles(libDir, filepath.Join(dir, "root.yaml"))
+		require.ErrorContains(t, err, "is not within")
+
+		_, err = yamll.ParsePublishAnnotations([]string{"no-value"})
+		require.ErrorContains(t, err, "has to be set as <key>=<value>")
+	})
+}
@@ -0,0 +1,196 @@
+package yamll
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+const (
+	// DefaultRequestTimeout bounds a single request to a remote source.
+	DefaultRequestTimeout = time.Minute
+	// DefaultRetries is the number of times a failed remote request is retried.
+	DefaultRetries = 3
+
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+	// retryMaxRetryAfter is the longest Retry-After waited for, servers asking for more are given up on right away.
+	retryMaxRetryAfter = 2 * time.Minute
+)
+
+// retryPolicy bounds and retries the requests made to remote sources, a zero policy does neither.
+type retryPolicy struct {
+	requestTimeout time.Duration
+	retries        int
+}
+
+// retryableError marks a failure worth retrying, e.g. 429 or 5xx, with the delay the server asked for.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (err *retryableError) Error() string {
+	return err.err.Error()
+}
+
+func (err *retryableError) Unwrap() error {
+	return err.err
+}
+
+func (cfg *Config) retryPolicy() retryPolicy {
+	return retryPolicy{requestTimeout: cfg.RequestTimeout, retries: cfg.Retries}
+}
+
+// withTimeout bounds ctx by Config.Timeout, the time allowed to resolve every import.
+func (cfg *Config) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if cfg.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, cfg.Timeout)
+}
+
+// contextError explains why resolving was aborted when ctx is done, and returns err otherwise.
+func (cfg *Config) contextError(ctx context.Context, err error) error {
+	switch {
+	case stdErrors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &errors.YamllError{Message: fmt.Sprintf("resolving imports did not finish within %s: '%v'", cfg.Timeout, err)}
+	case stdErrors.Is(ctx.Err(), context.Canceled):
+		return &errors.YamllError{Message: fmt.Sprintf("resolving imports was canceled: '%v'", err)}
+	default:
+		return err
+	}
+}
+
+func (policy retryPolicy) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if policy.requestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, policy.requestTimeout)
+}
+
+// do runs attempt, each time bounded by the request timeout, retrying transient failures with exponential backoff.
+// The delay asked for by the server through Retry-After takes precedence over the backoff.
+func (policy retryPolicy) do(ctx context.Context, log *slog.Logger, target string, attempt func(ctx context.Context) error) error {
+	for try := 0; ; try++ {
+		attemptCtx, cancel := policy.attemptContext(ctx)
+		err := attempt(attemptCtx)
+		timedOut := stdErrors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		delay, retryable := retryDelay(err, try)
+		retryable = retryable || timedOut
+
+		var retryErr *retryableError
+		if stdErrors.As(err, &retryErr) {
+			err = retryErr.err
+		}
+
+		if !retryable {
+			return err
+		}
+
+		if try >= policy.retries {
+			return &errors.YamllError{Message: fmt.Sprintf("giving up on '%s' after %d attempts: %v", target, try+1, err)}
+		}
+
+		if deadline, ok := ctx.Deadline(); delay > retryMaxRetryAfter || (ok && time.Until(deadline) < delay) {
+			return &errors.YamllError{Message: fmt.Sprintf(
+				"giving up on '%s': the server asked to retry after %s, longer than yamll waits: %v", target, delay, err,
+			)}
+		}
+
+		log.Warn("remote request failed, retrying",
+			slog.String("target", target), slog.Int("attempt", try+1), slog.Duration("delay", delay), slog.Any("err", err))
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay reports whether err is transient, and how long to wait before the next attempt.
+func retryDelay(err error, try int) (time.Duration, bool) {
+	delay := min(retryBaseDelay<<try, retryMaxDelay)
+
+	var retryErr *retryableError
+	if stdErrors.As(err, &retryErr) {
+		if retryErr.retryAfter > 0 {
+			delay = retryErr.retryAfter
+		}
+
+		return delay, true
+	}
+
+	var netErr net.Error
+
+	switch {
+	case stdErrors.Is(err, syscall.ECONNRESET), stdErrors.Is(err, syscall.ECONNREFUSED), stdErrors.Is(err, syscall.EPIPE),
+		stdErrors.Is(err, io.ErrUnexpectedEOF), stdErrors.Is(err, io.EOF):
+		return delay, true
+	case stdErrors.As(err, &netErr) && netErr.Timeout():
+		return delay, true
+	case strings.Contains(err.Error(), "connection reset by peer"):
+		// Not every client wraps the underlying network error.
+		return delay, true
+	default:
+		return 0, false
+	}
+}
+
+// retryableStatus marks err as retryable when the status code is 429 or 5xx,
+// other than 501 and 505 which no later attempt gets past.
+func retryableStatus(err error, statusCode int, header http.Header) error {
+	switch {
+	case statusCode == http.StatusNotImplemented, statusCode == http.StatusHTTPVersionNotSupported:
+		return err
+	case statusCode != http.StatusTooManyRequests && statusCode < http.StatusInternalServerError:
+		return err
+	}
+
+	return &retryableError{err: err, retryAfter: parseRetryAfter(header.Get("Retry-After"))}
+}
+
+// parseRetryAfter reads a Retry-After header, given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
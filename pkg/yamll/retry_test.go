@@ -0,0 +1,227 @@
+package yamll_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+// newImportingConfig writes a root file importing url and returns a config resolving it without lock or cache.
+func newImportingConfig(t *testing.T, url string) *yamll.Config {
+	t.Helper()
+
+	rootFile := filepath.Join(t.TempDir(), "root.yaml")
+	require.NoError(t, os.WriteFile(rootFile, []byte("##++"+url+"\napp: *base\n"), 0o600))
+
+	cfg := yamll.New(false, "DEBUG", "---", rootFile)
+	cfg.SetLogger()
+	cfg.NoLock = true
+	cfg.NoCache = true
+
+	return cfg
+}
+
+func TestConfigRetriesRemoteImports(t *testing.T) {
+	t.Run("retries 5xx and 429 honouring Retry-After", func(t *testing.T) {
+		var requests atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			switch requests.Add(1) {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				_, _ = w.Write([]byte("base: &base\n  flaky: false\n"))
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		start := time.Now()
+
+		out, err := newImportingConfig(t, server.URL+"/base.yaml").Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "flaky: false")
+		require.Equal(t, int32(3), requests.Load())
+		require.GreaterOrEqual(t, time.Since(start), 1500*time.Millisecond)
+	})
+
+	t.Run("gives up after the configured retries", func(t *testing.T) {
+		var requests atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requests.Add(1)
+			w.Header().Set("Retry-After", time.Now().UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		t.Cleanup(server.Close)
+
+		cfg := newImportingConfig(t, server.URL+"/base.yaml")
+		cfg.Retries = 1
+
+		_, err := cfg.Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "after 2 attempts")
+		require.Contains(t, err.Error(), "502")
+		require.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		var requests atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			http.NotFound(w, r)
+		}))
+		t.Cleanup(server.Close)
+
+		_, err := newImportingConfig(t, server.URL+"/base.yaml").Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "404")
+		require.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("does not retry 501 and 505", func(t *testing.T) {
+		for _, status := range []int{http.StatusNotImplemented, http.StatusHTTPVersionNotSupported} {
+			var requests atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(status)
+			}))
+			t.Cleanup(server.Close)
+
+			_, err := newImportingConfig(t, server.URL+"/base.yaml").Yaml(t.Context())
+			require.ErrorContains(t, err, strconv.Itoa(status))
+			require.Equal(t, int32(1), requests.Load(), status)
+		}
+	})
+
+	t.Run("gives up on a Retry-After longer than it waits", func(t *testing.T) {
+		var requests atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requests.Add(1)
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		t.Cleanup(server.Close)
+
+		start := time.Now()
+
+		_, err := newImportingConfig(t, server.URL+"/base.yaml").Yaml(t.Context())
+		require.ErrorContains(t, err, "asked to retry after 24h0m0s")
+		require.Equal(t, int32(1), requests.Load())
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("retries dropped connections", func(t *testing.T) {
+		var requests atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if requests.Add(1) == 1 {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				require.NoError(t, conn.Close())
+
+				return
+			}
+
+			_, _ = w.Write([]byte("base: &base\n  reconnected: true\n"))
+		}))
+		t.Cleanup(server.Close)
+
+		out, err := newImportingConfig(t, server.URL+"/base.yaml").Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "reconnected: true")
+		require.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("retries OCI registries", func(t *testing.T) {
+		var manifestRequests atomic.Int32
+
+		yamll.SetOCIHTTPClientForTest(&http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				header := make(http.Header)
+
+				switch {
+				case strings.HasSuffix(req.URL.Path, "/manifests/v1") && manifestRequests.Add(1) == 1:
+					header.Set("Retry-After", "1")
+
+					return &http.Response{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests", Body: http.NoBody, Header: header}, nil
+				case strings.HasSuffix(req.URL.Path, "/manifests/v1"):
+					return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(
+						`{"schemaVersion":2,"layers":[` + ociLayer("application/yaml", "base: &base\n  registry: ok\n", "") + `]}`,
+					))}, nil
+				default:
+					return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader("base: &base\n  registry: ok\n"))}, nil
+				}
+			}),
+		})
+		t.Cleanup(func() {
+			yamll.SetOCIHTTPClientForTest(nil)
+		})
+
+		out, err := newImportingConfig(t, "oci://ghcr.io/company/config:v1").Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "registry: ok")
+		require.Equal(t, int32(2), manifestRequests.Load())
+	})
+}
+
+func TestConfigTimeoutsAndCancellation(t *testing.T) {
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+
+		_, _ = w.Write([]byte("base: &base\n  slow: true\n"))
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+
+	t.Run("request timeout", func(t *testing.T) {
+		cfg := newImportingConfig(t, server.URL+"/base.yaml")
+		cfg.RequestTimeout = 50 * time.Millisecond
+		cfg.Retries = 1
+
+		_, err := cfg.Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "after 2 attempts")
+	})
+
+	t.Run("total timeout", func(t *testing.T) {
+		cfg := newImportingConfig(t, server.URL+"/base.yaml")
+		cfg.Timeout = 100 * time.Millisecond
+
+		_, err := cfg.Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "resolving imports did not finish within 100ms")
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		_, err := newImportingConfig(t, server.URL+"/base.yaml").Yaml(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "resolving imports was canceled")
+	})
+}
@@ -0,0 +1,341 @@
+package yamll
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// semverParts is the number of parts of a full version, major.minor.patch.
+	semverParts = 3
+	// semverVersionsShown is how many of the latest versions are listed when no tag satisfies a constraint.
+	semverVersionsShown = 5
+)
+
+// semverVersion is a semantic version, as tags spell it with or without a leading v: v1.4.2, 2.0.0-rc.1.
+type semverVersion struct {
+	major, minor, patch int
+	prerelease          []string
+}
+
+// semverComparator is one comparison of a constraint, >=1.2.0.
+type semverComparator struct {
+	operator string
+	version  semverVersion
+}
+
+// semverConstraint is a constraint on versions, alternatives separated by || each of comparators that all have to hold.
+type semverConstraint struct {
+	alternatives [][]semverComparator
+	// prerelease is set when the constraint names a prerelease, only then are prereleases candidates.
+	prerelease bool
+}
+
+// isSemverConstraint reports whether the git ref is a version constraint rather than a literal ref,
+// ^1.2, ~1.4.0, >=2,<3 or 1.x || 2.x.
+func isSemverConstraint(ref string) bool {
+	return strings.ContainsAny(ref[:min(len(ref), 1)], "^~<>=!") || strings.Contains(ref, ",") || strings.Contains(ref, "||")
+}
+
+// parseSemver parses a full version, leading v and build metadata allowed.
+func parseSemver(raw string) (semverVersion, bool) {
+	version, ok := parsePartialSemver(raw)
+
+	core, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(raw), "v"), "+")
+	core, _, _ = strings.Cut(core, "-")
+
+	if !ok || strings.Count(core, ".") != semverParts-1 {
+		return semverVersion{}, false
+	}
+
+	return version, true
+}
+
+// parsePartialSemver parses a version whose minor and patch may be left out, 1 or 1.2, as constraints spell them.
+func parsePartialSemver(raw string) (semverVersion, bool) {
+	raw, _, _ = strings.Cut(strings.TrimPrefix(strings.TrimSpace(raw), "v"), "+")
+	core, prerelease, hasPrerelease := strings.Cut(raw, "-")
+
+	parts := strings.Split(core, ".")
+	if len(parts) > semverParts {
+		return semverVersion{}, false
+	}
+
+	var numbers [semverParts]int
+
+	for index, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 || (len(part) > 1 && part[0] == '0') {
+			return semverVersion{}, false
+		}
+
+		numbers[index] = number
+	}
+
+	version := semverVersion{major: numbers[0], minor: numbers[1], patch: numbers[2]}
+
+	if hasPrerelease {
+		if prerelease == "" {
+			return semverVersion{}, false
+		}
+
+		version.prerelease = strings.Split(prerelease, ".")
+	}
+
+	return version, true
+}
+
+// compare orders versions by precedence, prereleases coming before their release.
+func (version semverVersion) compare(other semverVersion) int {
+	for _, diff := range []int{version.major - other.major, version.minor - other.minor, version.patch - other.patch} {
+		if diff != 0 {
+			return diff
+		}
+	}
+
+	switch {
+	case len(version.prerelease) == 0 && len(other.prerelease) == 0:
+		return 0
+	case len(version.prerelease) == 0:
+		return 1
+	case len(other.prerelease) == 0:
+		return -1
+	}
+
+	for index := 0; index < len(version.prerelease) && index < len(other.prerelease); index++ {
+		left, right := version.prerelease[index], other.prerelease[index]
+		if left == right {
+			continue
+		}
+
+		leftNumber, leftErr := strconv.Atoi(left)
+		rightNumber, rightErr := strconv.Atoi(right)
+
+		switch {
+		case leftErr == nil && rightErr == nil:
+			return leftNumber - rightNumber
+		case leftErr == nil:
+			return -1
+		case rightErr == nil:
+			return 1
+		default:
+			return strings.Compare(left, right)
+		}
+	}
+
+	return len(version.prerelease) - len(other.prerelease)
+}
+
+func (version semverVersion) String() string {
+	out := fmt.Sprintf("%d.%d.%d", version.major, version.minor, version.patch)
+	if len(version.prerelease) != 0 {
+		out += "-" + strings.Join(version.prerelease, ".")
+	}
+
+	return out
+}
+
+// parseSemverConstraint parses a constraint, every comparator of it being one of:
+//
+//	^1.2     >=1.2.0, <2.0.0, or below the first non-zero part for 0.x versions
+//	~1.4.0   >=1.4.0, <1.5.0, ~1 being >=1.0.0, <2.0.0
+//	>=2 >2 <=2 <3 =1.2.3 !=1.2.3
+//	1.x 1.2.x 1.2.3, which leave out the parts they do not pin
+func parseSemverConstraint(raw string) (semverConstraint, error) {
+	var constraint semverConstraint
+
+	for alternative := range strings.SplitSeq(raw, "||") {
+		var comparators []semverComparator
+
+		for part := range strings.SplitSeq(alternative, ",") {
+			parsed, err := parseSemverComparator(strings.TrimSpace(part))
+			if err != nil {
+				return semverConstraint{}, fmt.Errorf("invalid version constraint '%s': %w", raw, err)
+			}
+
+			for _, comparator := range parsed {
+				constraint.prerelease = constraint.prerelease || len(comparator.version.prerelease) != 0
+			}
+
+			comparators = append(comparators, parsed...)
+		}
+
+		constraint.alternatives = append(constraint.alternatives, comparators)
+	}
+
+	return constraint, nil
+}
+
+func parseSemverComparator(raw string) ([]semverComparator, error) {
+	operator := ""
+
+	for _, candidate := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(raw, candidate) {
+			operator, raw = candidate, strings.TrimSpace(strings.TrimPrefix(raw, candidate))
+
+			break
+		}
+	}
+
+	// 1.x and 1.2.* leave out what they do not pin, like 1 and 1.2 do.
+	parts := strings.Split(raw, ".")
+	for index, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			parts = parts[:index]
+
+			break
+		}
+	}
+
+	pinned := len(parts)
+	if pinned == 0 {
+		return nil, fmt.Errorf("'%s' pins no version", raw)
+	}
+
+	version, ok := parsePartialSemver(strings.Join(parts, "."))
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a version", raw)
+	}
+
+	if operator == "" || operator == "=" {
+		if pinned == semverParts {
+			return []semverComparator{{operator: "=", version: version}}, nil
+		}
+
+		operator = "~"
+	}
+
+	if len(version.prerelease) != 0 {
+		pinned = semverParts
+	}
+
+	switch operator {
+	case "^":
+		return []semverComparator{{operator: ">=", version: version}, {operator: "<", version: caretUpperBound(version, pinned)}}, nil
+	case "~":
+		return []semverComparator{{operator: ">=", version: version}, {operator: "<", version: tildeUpperBound(version, pinned)}}, nil
+	case ">":
+		// >1.2 is above every 1.2.x.
+		if pinned < semverParts {
+			return []semverComparator{{operator: ">=", version: tildeUpperBound(version, pinned)}}, nil
+		}
+	case "<=":
+		if pinned < semverParts {
+			return []semverComparator{{operator: "<", version: tildeUpperBound(version, pinned)}}, nil
+		}
+	}
+
+	return []semverComparator{{operator: operator, version: version}}, nil
+}
+
+// caretUpperBound is the first version a caret constraint excludes, the next version changing its first non-zero part.
+func caretUpperBound(version semverVersion, pinned int) semverVersion {
+	switch {
+	case version.major != 0 || pinned == 1:
+		return semverVersion{major: version.major + 1}
+	case version.minor != 0 || pinned == semverParts-1:
+		return semverVersion{minor: version.minor + 1}
+	default:
+		return semverVersion{patch: version.patch + 1}
+	}
+}
+
+// tildeUpperBound is the first version above every version matching the pinned parts of version.
+func tildeUpperBound(version semverVersion, pinned int) semverVersion {
+	if pinned == 1 {
+		return semverVersion{major: version.major + 1}
+	}
+
+	return semverVersion{major: version.major, minor: version.minor + 1}
+}
+
+func (constraint semverConstraint) matches(version semverVersion) bool {
+	if len(version.prerelease) != 0 && !constraint.prerelease {
+		return false
+	}
+
+	for _, comparators := range constraint.alternatives {
+		matched := true
+
+		for _, comparator := range comparators {
+			if !comparator.matches(version) {
+				matched = false
+
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (comparator semverComparator) matches(version semverVersion) bool {
+	diff := version.compare(comparator.version)
+
+	switch comparator.operator {
+	case ">=":
+		return diff >= 0
+	case ">":
+		return diff > 0
+	case "<=":
+		return diff <= 0
+	case "<":
+		return diff < 0
+	case "!=":
+		return diff != 0
+	default:
+		return diff == 0
+	}
+}
+
+// highestMatchingTag returns the tag of the highest version satisfying the constraint, tags that are no version being skipped.
+func highestMatchingTag(rawConstraint string, tags []string) (string, error) {
+	constraint, err := parseSemverConstraint(rawConstraint)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		best      string
+		bestFound semverVersion
+		versions  []semverVersion
+	)
+
+	for _, tag := range tags {
+		version, ok := parseSemver(tag)
+		if !ok {
+			continue
+		}
+
+		versions = append(versions, version)
+
+		if constraint.matches(version) && (best == "" || version.compare(bestFound) > 0) {
+			best, bestFound = tag, version
+		}
+	}
+
+	if best != "" {
+		return best, nil
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i].compare(versions[j]) > 0 })
+
+	shown := versions[:min(len(versions), semverVersionsShown)]
+
+	available := make([]string, 0, len(shown))
+	for _, version := range shown {
+		available = append(available, version.String())
+	}
+
+	if len(available) == 0 {
+		return "", fmt.Errorf("no tag satisfies '%s', the repository has no version tags", rawConstraint)
+	}
+
+	return "", fmt.Errorf("no tag satisfies '%s', the latest versions are %s", rawConstraint, strings.Join(available, ", "))
+}
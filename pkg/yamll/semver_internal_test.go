@@ -0,0 +1,83 @@
+package yamll
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighestMatchingTag(t *testing.T) {
+	tags := []string{"v0.2.3", "v0.2.9", "v0.3.0", "v1.2.0", "v1.2.7", "v1.4.0", "v1.4.3", "v1.5.0", "v2.0.0-rc.1", "v2.1.0", "v3.0.0", "latest", "release-1"}
+
+	tests := []struct {
+		constraint string
+		tag        string
+	}{
+		{constraint: "^1.2", tag: "v1.5.0"},
+		{constraint: "^1.2.7", tag: "v1.5.0"},
+		{constraint: "^0.2.3", tag: "v0.2.9"},
+		{constraint: "~1.4.0", tag: "v1.4.3"},
+		{constraint: "~1.4", tag: "v1.4.3"},
+		{constraint: "~1", tag: "v1.5.0"},
+		{constraint: ">=2,<3", tag: "v2.1.0"},
+		{constraint: ">= 1.2, < 1.4", tag: "v1.2.7"},
+		{constraint: ">1.4", tag: "v3.0.0"},
+		{constraint: "<=1.4", tag: "v1.4.3"},
+		{constraint: "=1.2.0", tag: "v1.2.0"},
+		{constraint: ">=1.4.0,!=1.5.0,<2", tag: "v1.4.3"},
+		{constraint: "~0.2 || 1.2.x", tag: "v1.2.7"},
+		{constraint: ">=2.0.0-rc.0,<2.1", tag: "v2.0.0-rc.1"},
+	}
+
+	for _, test := range tests {
+		tag, err := highestMatchingTag(test.constraint, tags)
+		require.NoError(t, err, test.constraint)
+		require.Equal(t, test.tag, tag, test.constraint)
+	}
+
+	t.Run("no matching tag", func(t *testing.T) {
+		_, err := highestMatchingTag("^4", tags)
+		require.ErrorContains(t, err, "no tag satisfies '^4', the latest versions are 3.0.0, 2.1.0, 2.0.0-rc.1, 1.5.0, 1.4.3")
+
+		_, err = highestMatchingTag("^1", []string{"latest"})
+		require.ErrorContains(t, err, "the repository has no version tags")
+	})
+
+	t.Run("invalid constraints", func(t *testing.T) {
+		for _, constraint := range []string{"^", ">=a", "~1.2.3.4", ">=1,", "^01.2"} {
+			_, err := highestMatchingTag(constraint, tags)
+			require.ErrorContains(t, err, "invalid version constraint", constraint)
+		}
+	})
+}
+
+func TestSemverPrecedence(t *testing.T) {
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "v1.0.1+build.5", "1.1.0",
+	}
+
+	for index := 1; index < len(ordered); index++ {
+		lower, ok := parseSemver(ordered[index-1])
+		require.True(t, ok, ordered[index-1])
+
+		higher, ok := parseSemver(ordered[index])
+		require.True(t, ok, ordered[index])
+
+		require.Negative(t, lower.compare(higher), "%s < %s", ordered[index-1], ordered[index])
+	}
+
+	for _, invalid := range []string{"1.2", "v1", "1.2.3.4", "1.02.3", "1.2.3-", "main"} {
+		_, ok := parseSemver(invalid)
+		require.False(t, ok, invalid)
+	}
+}
+
+func TestIsSemverConstraint(t *testing.T) {
+	for _, ref := range []string{"^1.2", "~1.4.0", ">=2,<3", "<3", "=1.2.3", "!=1.0.0", "1.x || 2.x"} {
+		require.True(t, isSemverConstraint(ref), ref)
+	}
+
+	for _, ref := range []string{"v1.2.3", "main", "feature/x", "1.2.3", "0123456789abcdef0123456789abcdef01234567", ""} {
+		require.False(t, isSemverConstraint(ref), ref)
+	}
+}
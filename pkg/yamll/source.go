@@ -0,0 +1,137 @@
+package yamll
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Source reads the imports of one kind, e.g. local files, Git repositories or a custom scheme like vault://.
+//
+// Read receives the import with its Auth already parsed from the import statement, and returns the content.
+// The returned File also carries what ends up in the lock file: Name is recorded as the resolved source,
+// Meta.SHA256 as its digest (computed from the content when empty) and Meta.GitCommit when the source is versioned by git.
+type Source interface {
+	// Type names the source, it is what Dependency.Type is set to for the imports it matches.
+	Type() string
+	// Match reports whether the import path belongs to this source.
+	Match(path string) bool
+	// Read fetches the import, giving up once ctx is done.
+	Read(ctx context.Context, dependency *Dependency, log *slog.Logger) (File, error)
+}
+
+var sourceRegistry = struct {
+	mutex   sync.RWMutex
+	custom  []Source
+	builtin []Source
+}{
+	builtin: []Source{
+		builtinSource{sourceType: TypeArchive, match: isArchiveImport, read: (*Dependency).Archive},
+		builtinSource{sourceType: TypeDirectory, match: isDirectoryImport, read: (*Dependency).Directory},
+		builtinSource{sourceType: TypeFilePattern, match: isPattern, read: (*Dependency).FilePattern},
+		builtinSource{sourceType: TypeOCI, match: hasPrefix(TypeOCI), read: (*Dependency).OCI},
+		builtinSource{sourceType: TypeURL, match: hasPrefix(TypeURL), read: (*Dependency).URL},
+		builtinSource{sourceType: TypeGit, match: hasPrefix(TypeGit), read: (*Dependency).Git},
+		pluginSource{},
+		builtinSource{sourceType: TypeFile, match: func(string) bool { return true }, read: (*Dependency).File},
+	},
+}
+
+// RegisterSource makes a source available to every import resolved afterwards.
+// Registered sources are matched before the built-in ones, the most recently registered first,
+// and a source registered with the Type of an existing one replaces it.
+func RegisterSource(source Source) {
+	sourceRegistry.mutex.Lock()
+	defer sourceRegistry.mutex.Unlock()
+
+	custom := make([]Source, 0, len(sourceRegistry.custom)+1)
+	custom = append(custom, source)
+
+	for _, registered := range sourceRegistry.custom {
+		if registered.Type() != source.Type() {
+			custom = append(custom, registered)
+		}
+	}
+
+	sourceRegistry.custom = custom
+}
+
+// UnregisterSource removes a source added with RegisterSource, built-in sources cannot be removed.
+func UnregisterSource(sourceType string) {
+	sourceRegistry.mutex.Lock()
+	defer sourceRegistry.mutex.Unlock()
+
+	custom := make([]Source, 0, len(sourceRegistry.custom))
+
+	for _, registered := range sourceRegistry.custom {
+		if registered.Type() != sourceType {
+			custom = append(custom, registered)
+		}
+	}
+
+	sourceRegistry.custom = custom
+}
+
+// Sources lists every source in the order imports are matched against them.
+func Sources() []Source {
+	sourceRegistry.mutex.RLock()
+	defer sourceRegistry.mutex.RUnlock()
+
+	sources := make([]Source, 0, len(sourceRegistry.custom)+len(sourceRegistry.builtin))
+	sources = append(sources, sourceRegistry.custom...)
+
+	for _, builtin := range sourceRegistry.builtin {
+		if !containsSource(sourceRegistry.custom, builtin.Type()) {
+			sources = append(sources, builtin)
+		}
+	}
+
+	return sources
+}
+
+// sourceFor returns the source handling imports of the given type.
+func sourceFor(sourceType string) (Source, bool) {
+	for _, source := range Sources() {
+		if source.Type() == sourceType {
+			return source, true
+		}
+	}
+
+	return nil, false
+}
+
+// builtinSource adapts the Dependency readers shipped with yamll to Source.
+type builtinSource struct {
+	sourceType string
+	match      func(path string) bool
+	read       func(dependency *Dependency, ctx context.Context, log *slog.Logger) (File, error)
+}
+
+func (source builtinSource) Type() string {
+	return source.sourceType
+}
+
+func (source builtinSource) Match(path string) bool {
+	return source.match(path)
+}
+
+func (source builtinSource) Read(ctx context.Context, dependency *Dependency, log *slog.Logger) (File, error) {
+	return source.read(dependency, ctx, log)
+}
+
+func hasPrefix(prefix string) func(path string) bool {
+	return func(path string) bool {
+		return strings.HasPrefix(path, prefix)
+	}
+}
+
+func containsSource(sources []Source, sourceType string) bool {
+	for _, source := range sources {
+		if source.Type() == sourceType {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,70 @@
+package yamll_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+type vaultSource struct {
+	secrets map[string]string
+	tokens  []string
+}
+
+func (source *vaultSource) Type() string { return "vault" }
+
+func (source *vaultSource) Match(path string) bool { return strings.HasPrefix(path, "vault://") }
+
+func (source *vaultSource) Read(_ context.Context, dependency *yamll.Dependency, _ *slog.Logger) (yamll.File, error) {
+	if dependency.Auth != nil {
+		source.tokens = append(source.tokens, dependency.Auth.BarerToken)
+	}
+
+	return yamll.File{Name: dependency.Path + "?version=3", Data: source.secrets[strings.TrimPrefix(dependency.Path, "vault://")]}, nil
+}
+
+func TestRegisterSource(t *testing.T) {
+	source := &vaultSource{secrets: map[string]string{"secret/app": "credentials: &credentials\n  user: app\n"}}
+
+	yamll.RegisterSource(source)
+	t.Cleanup(func() {
+		yamll.UnregisterSource("vault")
+	})
+
+	dependency := yamll.Dependency{Path: "vault://secret/app"}
+	dependency.IdentifyType()
+	require.Equal(t, "vault", dependency.Type)
+
+	dir := t.TempDir()
+	rootFile := filepath.Join(dir, "root.yaml")
+	require.NoError(t, os.WriteFile(rootFile, []byte("##++vault://secret/app;{\"barer_token\":\"s.token\"}\napp: *credentials\n"), 0o600))
+
+	cfg := yamll.New(false, "DEBUG", "---", rootFile)
+	cfg.SetLogger()
+	cfg.NoCache = true
+	cfg.LockFile = filepath.Join(dir, "yamll.lock")
+
+	out, err := cfg.Yaml(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, string(out), "user: app")
+	require.Equal(t, []string{"s.token"}, source.tokens)
+
+	lockData, err := cfg.Lock(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, string(lockData), "type: vault")
+	require.Contains(t, string(lockData), "resolved: vault://secret/app?version=3")
+
+	t.Run("unregistered schemes fall back to files", func(t *testing.T) {
+		yamll.UnregisterSource("vault")
+
+		dependency := yamll.Dependency{Path: "vault://secret/app"}
+		dependency.IdentifyType()
+		require.Equal(t, yamll.TypeFile, dependency.Type)
+	})
+}
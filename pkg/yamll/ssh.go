@@ -0,0 +1,182 @@
+package yamll
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+	"github.com/skeema/knownhosts"
+	"golang.org/x/crypto/ssh"
+)
+
+// EnvSSHKeyPassphrase holds the passphrase of encrypted ssh keys, for imports not setting ssh_key_passphrase.
+const EnvSSHKeyPassphrase = "YAMLL_SSH_KEY_PASSPHRASE"
+
+// sshAuth authenticates git over ssh with ssh_key when set, else with the keys of the running ssh-agent.
+func (dependency *Dependency) sshAuth(gitMetaData *gitMeta, auth Auth, log *slog.Logger) (transport.AuthMethod, error) {
+	hostKeys, err := hostKeyVerifier(gitMetaData, auth, dependency.strictHostKeys, log)
+	if err != nil {
+		return nil, err
+	}
+
+	if auth.SSHKey != "" {
+		signer, err := sshSigner(auth)
+		if err != nil {
+			return nil, err
+		}
+
+		return &gitssh.PublicKeys{User: gitMetaData.user, Signer: signer, HostKeyCallbackHelper: hostKeys}, nil
+	}
+
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
+		return nil, &errors.YamllError{Message: fmt.Sprintf(
+			"git repository '%s' is fetched over ssh, set ssh_key or add the key to a running ssh-agent", gitMetaData.gitBaseURL,
+		)}
+	}
+
+	log.Debug("authenticating git over ssh with the ssh-agent", slog.String("user", gitMetaData.user))
+
+	agentAuth, err := gitssh.NewSSHAgentAuth(gitMetaData.user)
+	if err != nil {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("connecting to the ssh-agent errored with: '%v'", err)}
+	}
+
+	agentAuth.HostKeyCallbackHelper = hostKeys
+
+	return agentAuth, nil
+}
+
+// sshSigner reads ssh_key, a path or the key itself, decrypting it with
+// ssh_key_passphrase or $YAMLL_SSH_KEY_PASSPHRASE when it is encrypted.
+func sshSigner(auth Auth) (ssh.Signer, error) {
+	keyName := auth.SSHKey
+	if strings.Contains(keyName, "-----BEGIN ") {
+		keyName = "inline ssh_key"
+	}
+
+	key, err := pemOrFile(auth.SSHKey)
+	if err != nil {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("reading ssh key '%s' errored with %v", keyName, err)}
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+
+	var passphraseMissing *ssh.PassphraseMissingError
+	if !stdErrors.As(err, &passphraseMissing) {
+		if err != nil {
+			return nil, &errors.YamllError{Message: fmt.Sprintf("parsing ssh key '%s' errored with %v", keyName, err)}
+		}
+
+		return signer, nil
+	}
+
+	passphrase := auth.SSHKeyPassphrase
+	if passphrase == "" {
+		passphrase = os.Getenv(EnvSSHKeyPassphrase)
+	}
+
+	if passphrase == "" {
+		return nil, &errors.YamllError{Message: fmt.Sprintf(
+			"ssh key '%s' is encrypted, set ssh_key_passphrase or $%s", keyName, EnvSSHKeyPassphrase,
+		)}
+	}
+
+	signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+	if err != nil {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("decrypting ssh key '%s' errored with %v", keyName, err)}
+	}
+
+	return signer, nil
+}
+
+// hostKeyVerifier checks the key of the ssh server against known_hosts, where a changed key always fails.
+// Hosts missing from known_hosts are accepted with a warning, unless host keys are checked strictly.
+func hostKeyVerifier(gitMetaData *gitMeta, auth Auth, strict bool, log *slog.Logger) (gitssh.HostKeyCallbackHelper, error) {
+	files := knownHostsFiles(auth)
+
+	existing := make([]string, 0, len(files))
+
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			existing = append(existing, file)
+		}
+	}
+
+	if len(existing) == 0 {
+		if strict {
+			return gitssh.HostKeyCallbackHelper{}, &errors.YamllError{Message: fmt.Sprintf(
+				"host keys are checked strictly but none of the known_hosts files %s exist", strings.Join(files, ", "),
+			)}
+		}
+
+		log.Warn("no known_hosts file found, the host key of the ssh server is not verified", slog.String("host", gitMetaData.host))
+
+		return gitssh.HostKeyCallbackHelper{HostKeyCallback: ssh.InsecureIgnoreHostKey()}, nil //nolint:gosec
+	}
+
+	knownHosts, err := knownhosts.NewDB(existing...)
+	if err != nil {
+		return gitssh.HostKeyCallbackHelper{}, &errors.YamllError{Message: fmt.Sprintf("reading known_hosts errored with: '%v'", err)}
+	}
+
+	verify := knownHosts.HostKeyCallback()
+
+	callback := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := verify(hostname, remote, key)
+
+		switch {
+		case err == nil:
+			return nil
+		case knownhosts.IsHostKeyChanged(err):
+			return &errors.YamllError{Message: fmt.Sprintf(
+				"host key %s of '%s' does not match known_hosts, it changed or the connection is intercepted", ssh.FingerprintSHA256(key), hostname,
+			)}
+		case knownhosts.IsHostUnknown(err) && strict:
+			return &errors.YamllError{Message: fmt.Sprintf(
+				"host '%s' is not in known_hosts and host keys are checked strictly, its key is %s", hostname, ssh.FingerprintSHA256(key),
+			)}
+		case knownhosts.IsHostUnknown(err):
+			log.Warn("host is not in known_hosts, accepting its key", slog.String("host", hostname), slog.String("key", ssh.FingerprintSHA256(key)))
+
+			return nil
+		default:
+			return err
+		}
+	}
+
+	port := gitMetaData.port
+	if port == "" {
+		port = "22"
+	}
+
+	return gitssh.HostKeyCallbackHelper{
+		HostKeyCallback:   callback,
+		HostKeyAlgorithms: knownHosts.HostKeyAlgorithms(net.JoinHostPort(gitMetaData.host, port)),
+	}, nil
+}
+
+// knownHostsFiles lists known_hosts of the import, else $SSH_KNOWN_HOSTS, else the files ssh reads by default.
+func knownHostsFiles(auth Auth) []string {
+	if auth.KnownHosts != "" {
+		return filepath.SplitList(auth.KnownHosts)
+	}
+
+	if files := filepath.SplitList(os.Getenv("SSH_KNOWN_HOSTS")); len(files) != 0 {
+		return files
+	}
+
+	files := []string{"/etc/ssh/ssh_known_hosts"}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append([]string{filepath.Join(home, ".ssh", "known_hosts")}, files...)
+	}
+
+	return files
+}
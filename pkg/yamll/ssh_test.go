@@ -0,0 +1,202 @@
+package yamll_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestDependencyGitOverSSH(t *testing.T) {
+	if _, err := exec.LookPath("git-upload-pack"); err != nil {
+		t.Skip("git-upload-pack is not installed")
+	}
+
+	repoDir, _ := newGitRepo(t, map[string]string{"configs/base.yaml": "base: &base\n  replicas: 1\n"})
+
+	_, clientKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	encrypted, err := ssh.MarshalPrivateKeyWithPassphrase(clientKey, "", []byte("s3cret"))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "id_ed25519")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(encrypted), 0o600))
+
+	clientPublicKey, err := ssh.NewPublicKey(clientKey.Public())
+	require.NoError(t, err)
+
+	address, hostKey := startGitSSHServer(t, "deploy", clientPublicKey)
+
+	knownHostsFile := filepath.Join(dir, "known_hosts")
+	require.NoError(t, os.WriteFile(knownHostsFile, []byte(knownhosts.Line([]string{address}, hostKey)+"\n"), 0o600))
+
+	t.Setenv("SSH_AUTH_SOCK", "")
+	t.Setenv(yamll.EnvSSHKeyPassphrase, "s3cret")
+
+	resolve := func(t *testing.T, knownHosts string, strict bool) (string, error) {
+		t.Helper()
+
+		rootFile := filepath.Join(t.TempDir(), "root.yaml")
+		require.NoError(t, os.WriteFile(rootFile, []byte(
+			"##++git+ssh://deploy@"+address+filepath.ToSlash(repoDir)+"@main?path=configs/base.yaml;"+
+				`{"ssh_key": "`+keyFile+`", "known_hosts": "`+knownHosts+`"}`+"\napp: *base\n",
+		), 0o600))
+
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.NoCache = true
+		cfg.Retries = 0
+		cfg.StrictHostKeyChecking = strict
+
+		out, err := cfg.Yaml(t.Context())
+
+		return string(out), err
+	}
+
+	t.Run("encrypted key, user and port", func(t *testing.T) {
+		out, err := resolve(t, knownHostsFile, true)
+		require.NoError(t, err)
+		require.Contains(t, out, "replicas: 1")
+	})
+
+	t.Run("host missing from known_hosts", func(t *testing.T) {
+		otherHosts := filepath.Join(t.TempDir(), "known_hosts")
+		require.NoError(t, os.WriteFile(otherHosts, []byte(knownhosts.Line([]string{"other.internal"}, hostKey)+"\n"), 0o600))
+
+		_, err := resolve(t, otherHosts, true)
+		require.ErrorContains(t, err, "is not in known_hosts and host keys are checked strictly")
+
+		out, err := resolve(t, otherHosts, false)
+		require.NoError(t, err)
+		require.Contains(t, out, "replicas: 1")
+	})
+
+	t.Run("changed host key", func(t *testing.T) {
+		publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		changedKey, err := ssh.NewPublicKey(publicKey)
+		require.NoError(t, err)
+
+		changedHosts := filepath.Join(t.TempDir(), "known_hosts")
+		require.NoError(t, os.WriteFile(changedHosts, []byte(knownhosts.Line([]string{address}, changedKey)+"\n"), 0o600))
+
+		_, err = resolve(t, changedHosts, false)
+		require.ErrorContains(t, err, "does not match known_hosts")
+	})
+}
+
+// startGitSSHServer serves git-upload-pack over ssh to the user authenticating with the given key,
+// and returns its address and host key.
+func startGitSSHServer(t *testing.T, user string, authorizedKey ssh.PublicKey) (string, ssh.PublicKey) {
+	t.Helper()
+
+	_, hostPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivateKey)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() != user || string(key.Marshal()) != string(authorizedKey.Marshal()) {
+				return nil, os.ErrPermission
+			}
+
+			return &ssh.Permissions{}, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveGitSSHConn(conn, config)
+		}
+	}()
+
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	return "127.0.0.1:" + port, hostSigner.PublicKey()
+}
+
+func serveGitSSHConn(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "only sessions are served")
+
+			continue
+		}
+
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer channel.Close()
+
+			for request := range channelRequests {
+				if request.Type != "exec" || len(request.Payload) < 4 {
+					_ = request.Reply(false, nil)
+
+					continue
+				}
+
+				command := string(request.Payload[4:])
+				service, repoPath, _ := strings.Cut(command, " ")
+				_ = request.Reply(service == "git-upload-pack", nil)
+
+				status := uint32(1)
+
+				if service == "git-upload-pack" {
+					gitCommand := exec.Command(service, strings.Trim(repoPath, "'"))
+					gitCommand.Stdin = channel
+					gitCommand.Stdout = channel
+					gitCommand.Stderr = channel.Stderr()
+
+					if gitCommand.Run() == nil {
+						status = 0
+					}
+				}
+
+				_, _ = channel.SendRequest("exit-status", false, binary.BigEndian.AppendUint32(nil, status))
+
+				return
+			}
+		}()
+	}
+}
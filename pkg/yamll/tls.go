@@ -0,0 +1,135 @@
+package yamll
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+// tlsSettings are the TLS settings of an import, read from its Auth.
+// Server certificates are verified against the system roots unless told otherwise.
+type tlsSettings struct {
+	caBundle           []byte
+	clientCert         []byte
+	clientKey          []byte
+	insecureSkipVerify bool
+}
+
+func (auth Auth) tlsSettings() (tlsSettings, error) {
+	settings := tlsSettings{insecureSkipVerify: auth.InsecureSkipVerify}
+
+	if len(auth.CaFile) != 0 {
+		caBundle, err := pemOrFile(auth.CaFile)
+		if err != nil {
+			return settings, &errors.YamllError{Message: fmt.Sprintf("reading ca_file errored with: '%v'", err)}
+		}
+
+		settings.caBundle = append(settings.caBundle, caBundle...)
+	}
+
+	if len(auth.CaContent) != 0 {
+		if len(settings.caBundle) != 0 {
+			settings.caBundle = append(settings.caBundle, '\n')
+		}
+
+		settings.caBundle = append(settings.caBundle, auth.CaContent...)
+	}
+
+	if (len(auth.ClientCert) == 0) != (len(auth.ClientKey) == 0) {
+		return settings, &errors.YamllError{Message: "client_cert and client_key have to be set together"}
+	}
+
+	if len(auth.ClientCert) != 0 {
+		var err error
+
+		if settings.clientCert, err = pemOrFile(auth.ClientCert); err != nil {
+			return settings, &errors.YamllError{Message: fmt.Sprintf("reading client_cert errored with: '%v'", err)}
+		}
+
+		if settings.clientKey, err = pemOrFile(auth.ClientKey); err != nil {
+			return settings, &errors.YamllError{Message: fmt.Sprintf("reading client_key errored with: '%v'", err)}
+		}
+	}
+
+	return settings, nil
+}
+
+// custom reports whether the settings differ from the defaults of an HTTP client.
+func (settings tlsSettings) custom() bool {
+	return settings.insecureSkipVerify || len(settings.caBundle) != 0 || len(settings.clientCert) != 0
+}
+
+func (settings tlsSettings) config() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: settings.insecureSkipVerify, //nolint:gosec
+	}
+
+	if len(settings.caBundle) != 0 {
+		certPool, err := x509.SystemCertPool()
+		if err != nil {
+			certPool = x509.NewCertPool()
+		}
+
+		if !certPool.AppendCertsFromPEM(settings.caBundle) {
+			return nil, &errors.YamllError{Message: "the CA bundle does not contain any PEM encoded certificate"}
+		}
+
+		config.RootCAs = certPool
+	}
+
+	if len(settings.clientCert) != 0 {
+		certificate, err := tls.X509KeyPair(settings.clientCert, settings.clientKey)
+		if err != nil {
+			return nil, &errors.YamllError{Message: fmt.Sprintf("loading client certificate errored with: '%v'", err)}
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// httpClient returns client as is when the settings are the defaults, else a copy of it using them.
+func (settings tlsSettings) httpClient(client *http.Client) (*http.Client, error) {
+	if !settings.custom() {
+		return client, nil
+	}
+
+	config, err := settings.config()
+	if err != nil {
+		return nil, err
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if client.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+
+	if !ok {
+		// A transport that is not backed by the network, e.g. in tests, has no TLS to configure.
+		return client, nil
+	}
+
+	transport = transport.Clone()
+	transport.TLSClientConfig = config
+
+	configured := *client
+	configured.Transport = transport
+
+	return &configured, nil
+}
+
+// pemOrFile returns value when it is PEM encoded already, e.g. substituted from an environment variable, else reads the file it names.
+func pemOrFile(value string) ([]byte, error) {
+	if strings.Contains(value, "-----BEGIN ") {
+		return []byte(value), nil
+	}
+
+	return os.ReadFile(value)
+}
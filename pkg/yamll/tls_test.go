@@ -0,0 +1,192 @@
+package yamll_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+// newClientCertificate returns a self-signed client certificate and its key, PEM encoded.
+func newClientCertificate(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "yamll"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+// writeServerCA writes the certificate of the test server to a file usable as ca_file.
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	return caFile
+}
+
+func TestDependencyURLTLS(t *testing.T) {
+	clientCert, clientKey := newClientCertificate(t)
+
+	clientCAs := x509.NewCertPool()
+	require.True(t, clientCAs.AppendCertsFromPEM([]byte(clientCert)))
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("base: &base\n  secure: true\n"))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caFile := writeServerCA(t, server)
+
+	keyFile := filepath.Join(t.TempDir(), "client.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(clientKey), 0o600))
+
+	cfg := yamll.New(false, "DEBUG", "")
+	cfg.SetLogger()
+
+	read := func(auth *yamll.Auth) (yamll.File, error) {
+		return (&yamll.Dependency{Path: server.URL + "/base.yaml", Type: yamll.TypeURL, Auth: auth}).URL(t.Context(), cfg.GetLogger())
+	}
+
+	t.Run("server certificates are verified by default", func(t *testing.T) {
+		_, err := read(nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "certificate")
+	})
+
+	t.Run("insecure_skip_verify", func(t *testing.T) {
+		file, err := read(&yamll.Auth{InsecureSkipVerify: true})
+		require.NoError(t, err)
+		require.Contains(t, file.Data, "secure: true")
+	})
+
+	t.Run("ca_file", func(t *testing.T) {
+		file, err := read(&yamll.Auth{CaFile: caFile})
+		require.NoError(t, err)
+		require.Contains(t, file.Data, "secure: true")
+	})
+
+	t.Run("mutual TLS with PEM client certificate and key file", func(t *testing.T) {
+		file, err := read(&yamll.Auth{CaFile: caFile, ClientCert: clientCert, ClientKey: keyFile})
+		require.NoError(t, err)
+		require.Contains(t, file.Data, "secure: true")
+	})
+
+	t.Run("client_cert without client_key", func(t *testing.T) {
+		_, err := read(&yamll.Auth{CaFile: caFile, ClientCert: clientCert})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "client_cert and client_key have to be set together")
+	})
+
+	t.Run("import statement with ca_file", func(t *testing.T) {
+		dir := t.TempDir()
+		rootFile := filepath.Join(dir, "root.yaml")
+		require.NoError(t, os.WriteFile(rootFile, []byte(
+			"##++"+server.URL+"/base.yaml;{\"ca_file\":\""+filepath.ToSlash(caFile)+"\"}\napp: *base\n",
+		), 0o600))
+
+		importCfg := yamll.New(false, "DEBUG", "---", rootFile)
+		importCfg.SetLogger()
+		importCfg.NoLock = true
+		importCfg.NoCache = true
+
+		out, err := importCfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "secure: true")
+	})
+}
+
+func TestDependencyOCITLS(t *testing.T) {
+	clientCert, clientKey := newClientCertificate(t)
+
+	clientCAs := x509.NewCertPool()
+	require.True(t, clientCAs.AppendCertsFromPEM([]byte(clientCert)))
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/manifests/v1"):
+			_, _ = w.Write([]byte(`{"schemaVersion":2,"layers":[` + ociLayer("application/yaml", "registry: mutual-tls\n", "") + `]}`))
+		case strings.HasSuffix(req.URL.Path, "/blobs/"+ociDigest("registry: mutual-tls\n")):
+			_, _ = w.Write([]byte("registry: mutual-tls\n"))
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	// The certificate of the test server is valid for example.com, dial it whatever the registry resolves to.
+	yamll.SetOCIHTTPClientForTest(&http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}})
+	t.Cleanup(func() {
+		yamll.SetOCIHTTPClientForTest(nil)
+	})
+
+	caFile := writeServerCA(t, server)
+
+	cfg := yamll.New(false, "DEBUG", "")
+	cfg.SetLogger()
+
+	read := func(auth *yamll.Auth) (yamll.File, error) {
+		return (&yamll.Dependency{Path: "oci://example.com/company/config:v1", Type: yamll.TypeOCI, Auth: auth}).OCI(t.Context(), cfg.GetLogger())
+	}
+
+	t.Run("server certificates are verified by default", func(t *testing.T) {
+		_, err := read(nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "certificate")
+	})
+
+	t.Run("client certificate required", func(t *testing.T) {
+		_, err := read(&yamll.Auth{CaFile: caFile})
+		require.Error(t, err)
+	})
+
+	t.Run("ca_file and mutual TLS", func(t *testing.T) {
+		file, err := read(&yamll.Auth{CaFile: caFile, ClientCert: clientCert, ClientKey: clientKey})
+		require.NoError(t, err)
+		require.Contains(t, file.Data, "registry: mutual-tls")
+	})
+}
@@ -1,6 +1,7 @@
 package yamll
 
 import (
+	"context"
 	stdErrors "errors"
 	"fmt"
 	"path/filepath"
@@ -28,14 +29,14 @@ type anchorOrigin struct {
 	file string
 }
 
-func (cfg *Config) Trace(path string) (TraceResult, error) {
+func (cfg *Config) Trace(ctx context.Context, path string) (TraceResult, error) {
 	if len(cfg.Files) == 0 {
 		return TraceResult{}, &errors.YamllError{Message: "trace requires a root file"}
 	}
 
 	cfg.Root = false
 
-	routes, err := cfg.ResolveDependencies(make(map[string]*YamlData), cfg.Files...)
+	routes, err := cfg.ResolveDependencies(ctx, make(map[string]*YamlData), cfg.Files...)
 	if err != nil {
 		return TraceResult{}, &errors.YamllError{Message: fmt.Sprintf("fetching dependency tree errored with: '%v'", err)}
 	}
@@ -63,7 +63,7 @@ func (yamlRoutes YamlRoutes) buildDependencyTreeNode(name string, showPatternFil
 		return node
 	}
 
-	if isPattern(name) {
+	if isPatternImport(name) {
 		node.Kind = "pattern"
 	}
 
@@ -74,7 +74,7 @@ func (yamlRoutes YamlRoutes) buildDependencyTreeNode(name string, showPatternFil
 	visiting[name] = true
 	defer delete(visiting, name)
 
-	if showPatternFiles && isPattern(name) {
+	if showPatternFiles && isPatternImport(name) {
 		patternFiles := make([]string, 0, len(route.SourceFile))
 
 		for _, src := range route.SourceFile {
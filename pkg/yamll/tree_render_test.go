@@ -32,7 +32,7 @@ func TestConfig_TreeFormats(t *testing.T) {
 	cfg.SetLogger()
 
 	t.Run("text", func(t *testing.T) {
-		out, err := cfg.Tree(yamll.TreeOutputText, true, true)
+		out, err := cfg.Tree(t.Context(), yamll.TreeOutputText, true, true)
 		require.NoError(t, err)
 		require.Contains(t, out, root)
 		require.Contains(t, out, base)
@@ -40,7 +40,7 @@ func TestConfig_TreeFormats(t *testing.T) {
 	})
 
 	t.Run("json", func(t *testing.T) {
-		out, err := cfg.Tree(yamll.TreeOutputJSON, true, true)
+		out, err := cfg.Tree(t.Context(), yamll.TreeOutputJSON, true, true)
 		require.NoError(t, err)
 
 		var node yamll.DependencyTreeNode
@@ -51,7 +51,7 @@ func TestConfig_TreeFormats(t *testing.T) {
 	})
 
 	t.Run("dot", func(t *testing.T) {
-		out, err := cfg.Tree(yamll.TreeOutputDOT, true, true)
+		out, err := cfg.Tree(t.Context(), yamll.TreeOutputDOT, true, true)
 		require.NoError(t, err)
 		require.Contains(t, out, "digraph yamll")
 		require.Contains(t, out, "TestConfig_TreeFormats")
@@ -60,7 +60,7 @@ func TestConfig_TreeFormats(t *testing.T) {
 	})
 
 	t.Run("mermaid", func(t *testing.T) {
-		out, err := cfg.Tree(yamll.TreeOutputMermaid, true, true)
+		out, err := cfg.Tree(t.Context(), yamll.TreeOutputMermaid, true, true)
 		require.NoError(t, err)
 		require.Contains(t, out, "graph TD")
 		require.Contains(t, out, "<br/>")
@@ -68,7 +68,7 @@ func TestConfig_TreeFormats(t *testing.T) {
 	})
 
 	t.Run("invalid format", func(t *testing.T) {
-		_, err := cfg.Tree("xml", true, true)
+		_, err := cfg.Tree(t.Context(), "xml", true, true)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "unsupported tree output format")
 	})
@@ -1,26 +1,35 @@
 package yamll
 
 import (
+	"context"
 	"crypto/sha256"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"strings"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/nikhilsbhat/yamll/pkg/errors"
 )
 
-// FetchData implements the methods to fetch the yaml data from various sources.
-type FetchData interface {
-	URL(log *slog.Logger) (string, error)
-	Git(log *slog.Logger) (string, error)
-	File(_ *slog.Logger) (string, error)
+// URL reads the data from the URL import, retrying on 429, 5xx and dropped connections.
+func (dependency *Dependency) URL(ctx context.Context, log *slog.Logger) (File, error) {
+	content, err := dependency.download(ctx, httpCacheKey(dependency.Path), log)
+	if err != nil {
+		return File{}, err
+	}
+
+	// URL imports are YAML documents, read without the whitespace around them.
+	body := strings.TrimSpace(string(content))
+	sum := sha256.Sum256([]byte(body))
+
+	return File{Name: dependency.Path, Data: body, Meta: FileMeta{SHA256: hex.EncodeToString(sum[:])}}, nil
 }
 
-// URL reads the data from the URL import.
-func (dependency *Dependency) URL(log *slog.Logger) (File, error) {
+// download fetches the body of the URL byte for byte with the auth and TLS settings of the import,
+// retrying on 429, 5xx and dropped connections, and serves it from the cache under cacheKey while the server reports it unchanged.
+func (dependency *Dependency) download(ctx context.Context, cacheKey string, log *slog.Logger) ([]byte, error) {
 	httpClient := resty.New()
 
 	var auth Auth
@@ -38,31 +47,69 @@ func (dependency *Dependency) URL(log *slog.Logger) (File, error) {
 		httpClient.SetBasicAuth(auth.UserName, auth.Password)
 	}
 
-	if len(auth.CaContent) != 0 {
-		log.Debug("using CA for authentication for remote URL", slog.Any("url", dependency.Path))
+	tlsSettings, err := auth.tlsSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsSettings.custom() {
+		tlsConfig, err := tlsSettings.config()
+		if err != nil {
+			return nil, err
+		}
 
-		certPool := x509.NewCertPool()
-		certPool.AppendCertsFromPEM([]byte(auth.CaContent))
-		httpClient.SetTLSClientConfig(&tls.Config{RootCAs: certPool}) //nolint:gosec
-	} else {
-		log.Debug("skipping TLS verification")
+		if tlsSettings.insecureSkipVerify {
+			log.Warn("skipping TLS verification for remote URL", slog.Any("url", dependency.Path))
+		}
 
-		httpClient.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true}) //nolint:gosec
+		httpClient.SetTLSClientConfig(tlsConfig)
 	}
 
-	resp, err := httpClient.R().Get(dependency.Path)
+	cachedEntry, cachedContent, cached := dependency.cache.Get(cacheKey)
+
+	var resp *resty.Response
+
+	err = dependency.retry.do(ctx, log, dependency.Path, func(ctx context.Context) error {
+		request := httpClient.R().SetContext(ctx)
+		if cached && cachedEntry.ETag != "" {
+			request.SetHeader("If-None-Match", cachedEntry.ETag)
+		}
+
+		var err error
+
+		if resp, err = request.Get(dependency.Path); err != nil {
+			return err
+		}
+
+		if resp.IsError() {
+			return retryableStatus(&errors.YamlError{
+				Message: fmt.Sprintf("fetching URL '%s' failed with status %s", dependency.Path, resp.Status()),
+			}, resp.StatusCode(), resp.Header())
+		}
+
+		return nil
+	})
 	if err != nil {
-		return File{}, err
+		return nil, err
 	}
 
-	if resp.IsError() {
-		return File{}, &errors.YamlError{
-			Message: fmt.Sprintf("fetching URL '%s' failed with status %s", dependency.Path, resp.Status()),
-		}
+	if cached && resp.StatusCode() == http.StatusNotModified {
+		log.Debug("remote URL not modified, serving it from cache", slog.Any("url", dependency.Path))
+
+		return []byte(cachedContent), nil
 	}
 
-	body := resp.String()
-	sum := sha256.Sum256([]byte(body))
+	// The raw body, resp.String() trims it, which corrupts binary content such as archives.
+	body := resp.Body()
+
+	if cacheErr := dependency.cache.Put(CacheEntry{
+		Key:  cacheKey,
+		Type: dependency.Type,
+		Name: dependency.Path,
+		ETag: resp.Header().Get("ETag"),
+	}, string(body)); cacheErr != nil {
+		log.Warn("caching remote URL failed", slog.Any("url", dependency.Path), slog.Any("err", cacheErr))
+	}
 
-	return File{Name: dependency.Path, Data: body, Meta: FileMeta{SHA256: hex.EncodeToString(sum[:])}}, err
+	return body, nil
 }
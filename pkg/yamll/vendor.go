@@ -0,0 +1,423 @@
+package yamll
+
+import (
+	"bufio"
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nikhilsbhat/yamll/pkg/errors"
+)
+
+const (
+	// DefaultVendorDir is the directory remote imports are vendored into.
+	DefaultVendorDir   = "yamll_vendor"
+	vendorManifestName = "modules.txt"
+	vendorManifestHead = "# yamll vendor manifest generated by 'yamll vendor', do not edit."
+)
+
+// VendorEntry maps a remote import to its vendored copy.
+// Pattern imports have an entry per matched file, File being the import of that file.
+type VendorEntry struct {
+	Source         string
+	File           string
+	Path           string
+	SHA256         string
+	GitCommit      string
+	ManifestDigest string
+}
+
+// VendorReport holds the outcome of vendoring remote imports.
+type VendorReport struct {
+	Dir     string
+	Entries []VendorEntry
+}
+
+type vendorManifest struct {
+	once    sync.Once
+	entries map[string][]VendorEntry
+	err     error
+}
+
+// VendorDependencies resolves the whole import graph and writes every remote source file into the vendor directory,
+// along with a modules.txt manifest mapping each import to its vendored path and sha256.
+func (cfg *Config) VendorDependencies(ctx context.Context) (VendorReport, error) {
+	cfg.Root = false
+
+	previousNoLock, previousVendor := cfg.NoLock, cfg.Vendor
+	cfg.NoLock, cfg.Vendor = true, false
+
+	defer func() {
+		cfg.NoLock, cfg.Vendor = previousNoLock, previousVendor
+	}()
+
+	routes, err := cfg.ResolveDependencies(ctx, make(map[string]*YamlData), cfg.Files...)
+	if err != nil {
+		return VendorReport{}, &errors.YamllError{Message: fmt.Sprintf("fetching dependency tree errored with: '%v'", err)}
+	}
+
+	vendorDir := cfg.vendorDir()
+	if err = resetVendorDir(vendorDir); err != nil {
+		return VendorReport{}, err
+	}
+
+	report := VendorReport{Dir: vendorDir}
+
+	for _, file := range YamlRoutes(routes).OrderedFiles() {
+		route := routes[file]
+		if route == nil || !isRemoteType(dependencyType(route.File)) {
+			continue
+		}
+
+		pattern := isPatternImport(route.File)
+
+		for _, src := range route.SourceFile {
+			entry := VendorEntry{
+				Source:         route.File,
+				SHA256:         checksumForContent(src.Data),
+				GitCommit:      src.Meta.GitCommit,
+				ManifestDigest: src.Meta.ManifestDigest,
+			}
+			if pattern {
+				entry.File = src.Name
+			}
+
+			relPath, err := vendorRelPath(route.File)
+			if pattern {
+				relPath, err = vendorRelPath(src.Name)
+			}
+
+			if err != nil {
+				return VendorReport{}, err
+			}
+
+			if err = os.MkdirAll(filepath.Join(vendorDir, filepath.Dir(relPath)), defaultDirPermissions); err != nil {
+				return VendorReport{}, err
+			}
+
+			if err = os.WriteFile(filepath.Join(vendorDir, relPath), []byte(src.Data), cacheFilePermissions); err != nil {
+				return VendorReport{}, err
+			}
+
+			cfg.log.Debug("vendored remote import", slog.String("source", route.File), slog.String("path", relPath))
+
+			entry.Path = relPath
+			report.Entries = append(report.Entries, entry)
+		}
+	}
+
+	sort.SliceStable(report.Entries, func(i, j int) bool { return report.Entries[i].Source < report.Entries[j].Source })
+
+	if err = writeVendorManifest(filepath.Join(vendorDir, vendorManifestName), report.Entries); err != nil {
+		return VendorReport{}, err
+	}
+
+	return report, nil
+}
+
+// readVendored reads a remote dependency from the vendor directory instead of Git, HTTP or OCI.
+// The files a pattern import matched are read back as its sources.
+func (cfg *Config) readVendored(dependency *Dependency, source string) (File, error) {
+	entries, err := cfg.vendorEntries()
+	if err != nil {
+		return File{}, err
+	}
+
+	vendored, ok := entries[source]
+	if !ok {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf("dependency %s is not vendored, run 'yamll vendor' to refresh %s", source, cfg.vendorDir())}
+	}
+
+	if len(vendored) == 1 && vendored[0].File == "" {
+		return cfg.readVendoredFile(dependency, vendored[0])
+	}
+
+	sources := make([]File, 0, len(vendored))
+
+	for _, entry := range vendored {
+		file, err := cfg.readVendoredFile(dependency, entry)
+		if err != nil {
+			return File{}, err
+		}
+
+		file.Name = entry.File
+		sources = append(sources, file)
+	}
+
+	file := patternFile(source, sources)
+	file.Meta.GitCommit = sources[0].Meta.GitCommit
+	file.Meta.ManifestDigest = sources[0].Meta.ManifestDigest
+
+	return file, nil
+}
+
+func (cfg *Config) readVendoredFile(dependency *Dependency, entry VendorEntry) (File, error) {
+	source := entry.Source
+	vendoredPath := filepath.Join(cfg.vendorDir(), entry.Path)
+
+	content, err := os.ReadFile(vendoredPath)
+	if err != nil {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf("reading vendored dependency %s errored with: '%v'", source, err)}
+	}
+
+	if actual := checksumForContent(string(content)); actual != entry.SHA256 {
+		return File{}, &errors.YamllError{Message: fmt.Sprintf(
+			"vendored dependency %s was modified: expected sha256 %s, got %s", source, entry.SHA256, actual,
+		)}
+	}
+
+	cfg.log.Debug("serving dependency from vendor directory", slog.String("path", dependency.Path), slog.String("vendored", vendoredPath))
+
+	return File{
+		Name: vendoredPath,
+		Data: string(content),
+		Meta: FileMeta{SHA256: entry.SHA256, GitCommit: entry.GitCommit, ManifestDigest: entry.ManifestDigest},
+	}, nil
+}
+
+func (cfg *Config) vendorEntries() (map[string][]VendorEntry, error) {
+	vendored := cfg.vendoredManifest()
+
+	vendored.once.Do(func() {
+		vendored.entries, vendored.err = readVendorManifest(filepath.Join(cfg.vendorDir(), vendorManifestName))
+	})
+
+	return vendored.entries, vendored.err
+}
+
+// vendoredManifest returns the manifest of the vendor directory, read once by whichever import needs it first.
+func (cfg *Config) vendoredManifest() *vendorManifest {
+	if cfg.vendored == nil {
+		cfg.vendored = &vendorManifest{}
+	}
+
+	return cfg.vendored
+}
+
+func (cfg *Config) vendorDir() string {
+	if cfg.VendorDir == "" {
+		return DefaultVendorDir
+	}
+
+	return cfg.VendorDir
+}
+
+func (r VendorReport) String() string {
+	lines := make([]string, 0, len(r.Entries)+1)
+
+	for _, entry := range r.Entries {
+		lines = append(lines, fmt.Sprintf("%s => %s", entry.Source, filepath.Join(r.Dir, entry.Path)))
+	}
+
+	lines = append(lines, fmt.Sprintf("Vendored %d remote imports into %s", len(r.Entries), r.Dir))
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// resetVendorDir clears a previously generated vendor directory so that imports which are no longer used disappear.
+// Directories that were not generated by yamll are left untouched.
+func resetVendorDir(vendorDir string) error {
+	if _, err := os.Stat(vendorDir); stdErrors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join(vendorDir, vendorManifestName)); err != nil {
+		return &errors.YamllError{Message: fmt.Sprintf("refusing to overwrite %s: it exists but has no %s", vendorDir, vendorManifestName)}
+	}
+
+	return os.RemoveAll(vendorDir)
+}
+
+func writeVendorManifest(manifestPath string, entries []VendorEntry) error {
+	var builder strings.Builder
+
+	builder.WriteString(vendorManifestHead + "\n")
+
+	for index, entry := range entries {
+		if index == 0 || entries[index-1].Source != entry.Source {
+			builder.WriteString("# " + entry.Source + "\n")
+		}
+
+		builder.WriteString(entry.Path + " sha256=" + entry.SHA256)
+
+		if entry.GitCommit != "" {
+			builder.WriteString(" git_commit=" + entry.GitCommit)
+		}
+
+		if entry.ManifestDigest != "" {
+			builder.WriteString(" manifest_digest=" + entry.ManifestDigest)
+		}
+
+		if entry.File != "" {
+			builder.WriteString(" file=" + entry.File)
+		}
+
+		builder.WriteString("\n")
+	}
+
+	return os.WriteFile(manifestPath, []byte(builder.String()), cacheFilePermissions)
+}
+
+// readVendorManifest reads the manifest, where the files a pattern import matched each follow the import.
+func readVendorManifest(manifestPath string) (map[string][]VendorEntry, error) {
+	manifest, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, &errors.YamllError{Message: fmt.Sprintf("reading vendor manifest errored with: '%v', run 'yamll vendor' first", err)}
+	}
+
+	defer manifest.Close()
+
+	entries := make(map[string][]VendorEntry)
+	scanner := bufio.NewScanner(manifest)
+
+	var source string
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || line == vendorManifestHead:
+			continue
+		case strings.HasPrefix(line, "# "):
+			source = strings.TrimPrefix(line, "# ")
+
+			continue
+		case source == "":
+			return nil, &errors.YamllError{Message: fmt.Sprintf("malformed vendor manifest %s: entry %q has no import", manifestPath, line)}
+		}
+
+		fields := strings.Fields(line)
+		entry := VendorEntry{Source: source, Path: fields[0]}
+
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+
+			switch key {
+			case "sha256":
+				entry.SHA256 = value
+			case "git_commit":
+				entry.GitCommit = value
+			case "manifest_digest":
+				entry.ManifestDigest = value
+			case "file":
+				entry.File = value
+			}
+		}
+
+		entries[source] = append(entries[source], entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// vendorRelPath derives a readable, stable location inside the vendor directory for a remote import.
+func vendorRelPath(source string) (string, error) {
+	var segments []string
+
+	switch dependencyType(source) {
+	case TypeGit:
+		meta, err := (&Dependency{Path: source}).getGitMetaData()
+		if err != nil {
+			return "", err
+		}
+
+		segments = append([]string{"git"}, strings.Split(meta.location(), "/")...)
+		// Constraints hold characters paths cannot, >=2,<3, so they are vendored under their checksum.
+		if isSemverConstraint(meta.referenceName) {
+			segments = append(segments, "semver-"+checksumForContent(meta.referenceName)[:12])
+		} else {
+			segments = append(segments, meta.referenceName)
+		}
+
+		segments = append(segments, strings.Split(meta.path, "/")...)
+	case TypeOCI:
+		ref, err := parseOCIReference(source)
+		if err != nil {
+			return "", err
+		}
+
+		segments = append([]string{"oci", ref.Registry}, strings.Split(ref.Repository, "/")...)
+		if ref.Path != "" {
+			segments = append(segments, ref.Reference)
+			segments = append(segments, strings.Split(ref.Path, "/")...)
+		} else {
+			segments = append(segments, ref.Reference+".yaml")
+		}
+	case TypeURL:
+		parsed, err := url.Parse(source)
+		if err != nil {
+			return "", err
+		}
+
+		urlPath := parsed.Path
+		if urlPath == "" || strings.HasSuffix(urlPath, "/") {
+			urlPath += "index.yaml"
+		}
+
+		if parsed.RawQuery != "" {
+			urlPath += "-" + checksumForContent(parsed.RawQuery)[:12]
+		}
+
+		segments = append([]string{"http", parsed.Host}, strings.Split(urlPath, "/")...)
+	case TypeArchive:
+		archive, member, err := splitArchiveImport(source)
+		if err != nil {
+			return "", err
+		}
+
+		archiveLocation, query, _ := strings.Cut(archive, "?")
+
+		segments = []string{"archive"}
+		if parsed, err := url.Parse(archiveLocation); err == nil && parsed.Host != "" {
+			segments = append(segments, parsed.Host)
+			archiveLocation = parsed.Path
+		}
+
+		segments = append(segments, strings.Split(filepath.ToSlash(archiveLocation), "/")...)
+		if query != "" {
+			segments[len(segments)-1] += "-" + checksumForContent(query)[:12]
+		}
+
+		segments = append(segments, strings.Split(member, "/")...)
+	case TypeFile, TypeFilePattern, TypeDirectory:
+		return "", &errors.YamllError{Message: fmt.Sprintf("dependency %s is not a remote import and cannot be vendored", source)}
+	default:
+		parsed, err := url.Parse(source)
+		if err != nil {
+			return "", err
+		}
+
+		segments = append([]string{parsed.Scheme, parsed.Host}, strings.Split(parsed.Path, "/")...)
+		if parsed.RawQuery != "" {
+			segments[len(segments)-1] += "-" + checksumForContent(parsed.RawQuery)[:12]
+		}
+	}
+
+	cleaned := make([]string, 0, len(segments))
+
+	for _, segment := range segments {
+		segment = strings.NewReplacer(":", "_", "@", "_").Replace(segment)
+		if segment == "" || segment == "." || segment == ".." {
+			continue
+		}
+
+		cleaned = append(cleaned, segment)
+	}
+
+	return path.Join(cleaned...), nil
+}
+
+func dependencyType(path string) string {
+	dependency := &Dependency{Path: path}
+	dependency.IdentifyType()
+
+	return dependency.Type
+}
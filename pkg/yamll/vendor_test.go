@@ -0,0 +1,80 @@
+package yamll_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nikhilsbhat/yamll/pkg/yamll"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigVendorDependencies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("shared: &shared\n  vendored: true\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	rootFile := filepath.Join(dir, "root.yaml")
+	vendorDir := filepath.Join(dir, "yamll_vendor")
+	remoteImport := server.URL + "/libs/base.yaml"
+
+	require.NoError(t, os.WriteFile(rootFile, []byte("##++"+remoteImport+"\napp: *shared\n"), 0o600))
+
+	newConfig := func() *yamll.Config {
+		cfg := yamll.New(false, "DEBUG", "---", rootFile)
+		cfg.SetLogger()
+		cfg.NoLock = true
+		cfg.NoCache = true
+		cfg.VendorDir = vendorDir
+
+		return cfg
+	}
+
+	report, err := newConfig().VendorDependencies(t.Context())
+	require.NoError(t, err)
+	require.Len(t, report.Entries, 1)
+	require.Equal(t, remoteImport, report.Entries[0].Source)
+
+	vendoredFile := filepath.Join(vendorDir, report.Entries[0].Path)
+	require.FileExists(t, vendoredFile)
+
+	manifest, err := os.ReadFile(filepath.Join(vendorDir, "modules.txt"))
+	require.NoError(t, err)
+	require.Contains(t, string(manifest), "# "+remoteImport+"\n")
+	require.Contains(t, string(manifest), report.Entries[0].Path+" sha256="+report.Entries[0].SHA256)
+
+	server.Close()
+
+	t.Run("reads vendored copy", func(t *testing.T) {
+		cfg := newConfig()
+		cfg.Vendor = true
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "vendored: true")
+	})
+
+	t.Run("offline falls back to vendored copy", func(t *testing.T) {
+		cfg := newConfig()
+		cfg.Offline = true
+
+		out, err := cfg.Yaml(t.Context())
+		require.NoError(t, err)
+		require.Contains(t, string(out), "vendored: true")
+	})
+
+	t.Run("rejects modified vendored copy", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(vendoredFile, []byte("shared: &shared\n  tampered: true\n"), 0o600))
+
+		cfg := newConfig()
+		cfg.Vendor = true
+
+		_, err := cfg.Yaml(t.Context())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "vendored dependency "+remoteImport+" was modified")
+	})
+}
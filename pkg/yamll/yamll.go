@@ -1,6 +1,7 @@
 package yamll
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
@@ -15,6 +16,7 @@ type YamlData struct {
 	Merged     bool          `json:"merged,omitempty" yaml:"merged,omitempty"`
 	Index      int           `json:"index,omitempty" yaml:"index,omitempty"`
 	File       string        `json:"file,omitempty" yaml:"file,omitempty"`
+	ID         string        `json:"id,omitempty" yaml:"id,omitempty"`
 	DataRaw    string        `json:"data_raw,omitempty" yaml:"data_raw,omitempty"`
 	Dependency []*Dependency `json:"dependency,omitempty" yaml:"dependency,omitempty"`
 	SourceFile []File        `json:"-" yaml:"-"`
@@ -22,17 +24,41 @@ type YamlData struct {
 
 // Config holds the information of yaml files to be parsed.
 type Config struct {
-	Root     bool          `json:"root,omitempty" yaml:"root,omitempty"`
-	Merge    bool          `json:"effective,omitempty" yaml:"effective,omitempty"`
-	Split    bool          `json:"split,omitempty" yaml:"split,omitempty"`
-	Limiter  string        `json:"limiter,omitempty" yaml:"limiter,omitempty"`
-	LogLevel string        `json:"log_level,omitempty" yaml:"log_level,omitempty"`
-	Files    []*Dependency `json:"files,omitempty" yaml:"files,omitempty"`
-	LockFile string        `json:"lock_file,omitempty" yaml:"lock_file,omitempty"`
-	NoLock   bool          `json:"no_lock,omitempty" yaml:"no_lock,omitempty"`
-	Profile  bool          `json:"profile,omitempty" yaml:"profile,omitempty"`
-	log      *slog.Logger
-	profile  *BuildProfile
+	Root            bool          `json:"root,omitempty" yaml:"root,omitempty"`
+	Merge           bool          `json:"effective,omitempty" yaml:"effective,omitempty"`
+	Split           bool          `json:"split,omitempty" yaml:"split,omitempty"`
+	Limiter         string        `json:"limiter,omitempty" yaml:"limiter,omitempty"`
+	LogLevel        string        `json:"log_level,omitempty" yaml:"log_level,omitempty"`
+	Files           []*Dependency `json:"files,omitempty" yaml:"files,omitempty"`
+	LockFile        string        `json:"lock_file,omitempty" yaml:"lock_file,omitempty"`
+	NoLock          bool          `json:"no_lock,omitempty" yaml:"no_lock,omitempty"`
+	Profile         bool          `json:"profile,omitempty" yaml:"profile,omitempty"`
+	CacheDir        string        `json:"cache_dir,omitempty" yaml:"cache_dir,omitempty"`
+	NoCache         bool          `json:"no_cache,omitempty" yaml:"no_cache,omitempty"`
+	Offline         bool          `json:"offline,omitempty" yaml:"offline,omitempty"`
+	Vendor          bool          `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	VendorDir       string        `json:"vendor_dir,omitempty" yaml:"vendor_dir,omitempty"`
+	Jobs            int           `json:"jobs,omitempty" yaml:"jobs,omitempty"`
+	ProjectRoot     string        `json:"project_root,omitempty" yaml:"project_root,omitempty"`
+	Timeout         time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	RequestTimeout  time.Duration `json:"request_timeout,omitempty" yaml:"request_timeout,omitempty"`
+	Retries         int           `json:"retries,omitempty" yaml:"retries,omitempty"`
+	CredentialsFile string        `json:"credentials_file,omitempty" yaml:"credentials_file,omitempty"`
+	// StrictHostKeyChecking rejects ssh servers missing from known_hosts, instead of accepting them with a warning.
+	StrictHostKeyChecking bool `json:"strict_host_key_checking,omitempty" yaml:"strict_host_key_checking,omitempty"`
+	// HTTPCredentials sends the credentials of ~/.netrc and git credential helpers to imports over plain http too,
+	// which otherwise only get the credentials file entries matching them.
+	HTTPCredentials bool `json:"http_credentials,omitempty" yaml:"http_credentials,omitempty"`
+	projectRootDir  string
+	log             *slog.Logger
+	profile         *BuildProfile
+	cacheStore      *Cache
+	vendored        *vendorManifest
+	gitRepos        *gitRepositories
+	credentials     *credentialStore
+	identities      map[string]string
+	// unlock leaves the lock entries it reports out of resolution, which reads them afresh instead.
+	unlock func(entry LockEntry) bool
 }
 
 // YamlRoutes holds a map of YamlData, representing a dependency tree.
@@ -67,10 +93,10 @@ type Yaml string
 // OCI based url: ##++oci://ghcr.io/<org_name>/<artifact>:<tag> ex: ##++oci://ghcr.io/company/platform-config:v1.
 //
 //nolint:lll
-func (cfg *Config) Yaml() (Yaml, error) {
+func (cfg *Config) Yaml(ctx context.Context) (Yaml, error) {
 	cfg.Root = false
 
-	dependencyRoutes, err := cfg.ResolveDependencies(make(map[string]*YamlData), cfg.Files...)
+	dependencyRoutes, err := cfg.ResolveDependencies(ctx, make(map[string]*YamlData), cfg.Files...)
 	if err != nil {
 		return "", &errors.YamllError{Message: fmt.Sprintf("fetching dependency tree errored with: '%v'", err)}
 	}
@@ -95,8 +121,8 @@ func (cfg *Config) Yaml() (Yaml, error) {
 }
 
 // YamlTree constructs a dependency tree and displays it in a format similar to the Linux tree utility.
-func (cfg *Config) YamlTree(color bool, showPatternFiles bool) error {
-	output, err := cfg.Tree(TreeOutputText, color, showPatternFiles)
+func (cfg *Config) YamlTree(ctx context.Context, color bool, showPatternFiles bool) error {
+	output, err := cfg.Tree(ctx, TreeOutputText, color, showPatternFiles)
 	if err != nil {
 		return err
 	}
@@ -106,10 +132,10 @@ func (cfg *Config) YamlTree(color bool, showPatternFiles bool) error {
 	return err
 }
 
-func (cfg *Config) Tree(outputFormat string, noColor, showPatternFiles bool) (string, error) {
+func (cfg *Config) Tree(ctx context.Context, outputFormat string, noColor, showPatternFiles bool) (string, error) {
 	cfg.Root = false
 
-	dependencyRoutes, err := cfg.ResolveDependencies(make(map[string]*YamlData), cfg.Files...)
+	dependencyRoutes, err := cfg.ResolveDependencies(ctx, make(map[string]*YamlData), cfg.Files...)
 	if err != nil {
 		return "", &errors.YamllError{Message: fmt.Sprintf("fetching dependency tree errored with: '%v'", err)}
 	}
@@ -122,7 +148,7 @@ func (cfg *Config) Tree(outputFormat string, noColor, showPatternFiles bool) (st
 }
 
 // YamlBuild builds YAML by substituting all anchors and aliases defined in sub-YAML files defined as libraries.
-func (cfg *Config) YamlBuild() (Yaml, error) {
+func (cfg *Config) YamlBuild(ctx context.Context) (Yaml, error) {
 	cfg.Root = false
 	if cfg.Profile {
 		cfg.profile = &BuildProfile{}
@@ -131,7 +157,7 @@ func (cfg *Config) YamlBuild() (Yaml, error) {
 
 	resolveStart := time.Now()
 
-	dependencyRoutes, err := cfg.ResolveDependencies(make(map[string]*YamlData), cfg.Files...)
+	dependencyRoutes, err := cfg.ResolveDependencies(ctx, make(map[string]*YamlData), cfg.Files...)
 	if err != nil {
 		return "", &errors.YamllError{Message: fmt.Sprintf("fetching dependency tree errored with: '%v'", err)}
 	}
@@ -179,10 +205,12 @@ func New(effective bool, logLevel, limiter string, paths ...string) *Config {
 	}
 
 	return &Config{
-		Files:    dependencies,
-		Limiter:  limiter,
-		LogLevel: logLevel,
-		Merge:    effective,
-		LockFile: "yamll.lock",
+		Files:          dependencies,
+		Limiter:        limiter,
+		LogLevel:       logLevel,
+		Merge:          effective,
+		LockFile:       "yamll.lock",
+		RequestTimeout: DefaultRequestTimeout,
+		Retries:        DefaultRetries,
 	}
 }
@@ -10,7 +10,7 @@ import (
 func Test_fetchDependency(t *testing.T) {
 	t.Run("", func(t *testing.T) {
 		cfg := yamll.New(false, "yamll/internal/fixtures/import.yaml", "")
-		deps, err := cfg.Yaml()
+		deps, err := cfg.Yaml(t.Context())
 		require.NoError(t, err)
 
 		require.NotNil(t, deps)